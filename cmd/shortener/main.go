@@ -1,23 +1,46 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
+	"github.com/IvanOplesnin/url-shortener/internal/audit"
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
 	"github.com/IvanOplesnin/url-shortener/internal/config"
 	"github.com/IvanOplesnin/url-shortener/internal/filestorage"
+	"github.com/IvanOplesnin/url-shortener/internal/grpcserver"
 	handlers "github.com/IvanOplesnin/url-shortener/internal/handler"
 	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/metrics"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/IvanOplesnin/url-shortener/internal/repository/bolt"
 	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/repository/metered"
 	"github.com/IvanOplesnin/url-shortener/internal/repository/persisted"
 	"github.com/IvanOplesnin/url-shortener/internal/repository/psql"
+	redisrepo "github.com/IvanOplesnin/url-shortener/internal/repository/redis"
+	"github.com/IvanOplesnin/url-shortener/internal/repository/sqlite"
 	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+	"github.com/IvanOplesnin/url-shortener/internal/tlscert"
+	"github.com/IvanOplesnin/url-shortener/internal/tracing"
 	migrate "github.com/IvanOplesnin/url-shortener/migrations"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
@@ -34,25 +57,360 @@ func run() error {
 
 	err = logger.SetupLogger(cfg.Logger.Level, cfg.Logger.Format)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	if err := runMigrate(cfg); err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	// закрываем в обратном порядке: сервер, воркеры, сброс файла, БД, трассировка
+	sd := newShutdown(cfg.HTTP.ShutdownTimeout)
+	defer sd.run()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Exporter, cfg.Tracing.File)
+	if err != nil {
+		return err
 	}
+	sd.push("tracing", shutdownTracing)
+
+	m := metrics.New()
 
 	baseURL := cfg.BaseURL
-	persistedRepo, db, err := createRepo(cfg)
+	persister, err := createPersister(cfg, m)
+	if err != nil {
+		return err
+	}
+	persistedRepo, db, err := createRepo(cfg, persister, m)
+	if err != nil {
+		return fmt.Errorf("can`t create repository: %w", err)
+	}
+	if ls, ok := persister.(*filestorage.LogStore); ok {
+		go ls.Run()
+		sd.push("storage log", ls.Close)
+	}
+	if db != nil {
+		if db.pool != nil {
+			m.RegisterPool(db.pool)
+		}
+		sd.push("db", func(context.Context) error {
+			db.close()
+			return nil
+		})
+	}
+	sd.push("storage flush", func(context.Context) error {
+		return persistedRepo.Flush()
+	})
+	if cfg.Storage.Write == config.WriteBehind {
+		go persistedRepo.Run()
+		sd.push("storage write-behind", persistedRepo.Close)
+	}
+
+	signer, err := createSigner(cfg)
+	if err != nil {
+		return err
+	}
+	deleter := shortener.NewDeleter(persistedRepo, 100, time.Second)
+	go deleter.Run()
+	sd.push("deleter", deleter.Close)
+
+	clickRepo, err := createClickRepo(cfg, db)
+	if err != nil {
+		return fmt.Errorf("can`t create click repository: %w", err)
+	}
+	recorder := shortener.NewClickRecorder(clickRepo, 10000, 500, time.Second)
+	go recorder.Run()
+	sd.push("click recorder", recorder.Close)
+
+	var auditObs audit.Observer
+	if pub := createAudit(cfg); pub != nil {
+		go pub.Run()
+		sd.push("audit", pub.Close)
+		auditObs = pub
+	}
+
+	sweeper := shortener.NewSweeper(persistedRepo, cfg.SweepInterval)
+	go sweeper.Run()
+	sd.push("sweeper", sweeper.Close)
+
+	aliasPolicy, err := usvc.NewAliasPolicy(
+		cfg.Alias.MinLen, cfg.Alias.MaxLen, cfg.Alias.Pattern,
+		slices.Concat(usvc.DefaultReservedAliases(), handlers.ReservedAliases(), cfg.Alias.Reserved),
+	)
+	if err != nil {
+		return err
+	}
+
+	gen, err := createGenerator(cfg, persistedRepo)
+	if err != nil {
+		return err
+	}
+	gen = m.TrackGenerator(gen)
+
+	opts := []shortener.Option{
+		shortener.WithDeleter(deleter),
+		shortener.WithClicks(clickRepo, recorder),
+		shortener.WithAliasPolicy(aliasPolicy),
+		shortener.WithGenerator(gen),
+		shortener.WithBlockedDomains(cfg.Blocklist.Domains),
+		shortener.WithDomains(cfg.Domains),
+		shortener.WithAudit(auditObs),
+	}
+	if cfg.Generator.PoolSize > 0 {
+		pool := createKeyPool(persistedRepo, db)
+		filler := shortener.NewKeyPoolFiller(pool, gen, cfg.Generator.PoolSize, cfg.Generator.PoolInterval)
+		go filler.Run()
+		sd.push("key pool filler", filler.Close)
+		opts = append(opts, shortener.WithKeyPool(pool, filler))
+	}
+
+	svc := shortener.New(metered.New(persistedRepo, m), baseURL, opts...)
+	m.RegisterGeneratorStats(svc.GeneratorStats)
 
+	var pinger handlers.Pinger
+	if db != nil {
+		pinger = db.pinger
+	}
+	settings, err := runtimeSettings(cfg)
+	if err != nil {
+		return err
+	}
+	rt := handlers.NewRuntime(settings)
+	proxies, err := cfg.ProxyNets()
+	if err != nil {
+		return err
+	}
+	subnet, err := cfg.SubnetNet()
 	if err != nil {
-		logger.Log.Fatalf("Can`t create repository %s", err)
+		return err
+	}
+	reload := newReloader(cfg, rt, svc)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go reload.watch(ctx, hup)
+
+	srv := &http.Server{
+		Addr: cfg.Server.String(),
+		Handler: handlers.InitHandlers(svc, baseURL, pinger, signer,
+			handlers.WithRuntime(rt),
+			handlers.WithAdmin(cfg.AdminToken, reload.Reload),
+			handlers.WithTrustedProxies(proxies),
+			handlers.WithTrustedSubnet(subnet),
+			handlers.WithAudit(auditObs),
+			handlers.WithMetrics(m, m.Handler()),
+			handlers.WithSecureCookie(cfg.TLS.Enabled),
+		),
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+	}
+	sd.push("http server", srv.Shutdown)
+
+	if cfg.TLS.Enabled {
+		created, err := tlscert.Ensure(cfg.TLS.CertFile, cfg.TLS.KeyFile, append([]string{cfg.Server.Host, baseHost(baseURL)}, domainHosts(cfg.Domains)...))
+		if err != nil {
+			return err
+		}
+		if created {
+			logger.Log.Warnf("generated self-signed certificate %s", cfg.TLS.CertFile)
+		}
 	}
-	svc := shortener.New(persistedRepo, baseURL)
-	mux := handlers.InitHandlers(svc, baseURL, db)
-	return http.ListenAndServe(cfg.Server.String(), mux)
+
+	errCh := make(chan error, 2)
+	if cfg.GRPCAddress != "" {
+		grpcSrv, lis, err := createGRPCServer(cfg, svc, pinger, signer, auditObs)
+		if err != nil {
+			return err
+		}
+		sd.push("grpc server", stopGRPC(grpcSrv))
+		go func() {
+			logger.Log.Infof("grpc server listening on %s", lis.Addr())
+			errCh <- grpcSrv.Serve(lis)
+		}()
+	}
+
+	return serve(ctx, srv, cfg.TLS, errCh)
 }
 
-func createRepo(cfg *config.Config) (*persisted.Repo, *pgxpool.Pool, error) {
-	fileStorage := filestorage.NewJSONStore(cfg.FilePath)
+// serve блокируется до сигнала остановки или ошибки одного из серверов.
+func serve(ctx context.Context, srv *http.Server, tlsCfg config.TLS, errCh chan error) error {
+	go func() {
+		if tlsCfg.Enabled {
+			logger.Log.Infof("server listening on https://%s", srv.Addr)
+			errCh <- srv.ListenAndServeTLS(tlsCfg.CertFile, tlsCfg.KeyFile)
+			return
+		}
+		logger.Log.Infof("server listening on http://%s", srv.Addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		logger.Log.Info("shutdown signal received, draining requests")
+		return nil
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+func createGRPCServer(cfg *config.Config, svc *shortener.Service, p grpcserver.Pinger, signer *auth.Signer, obs audit.Observer) (*grpc.Server, net.Listener, error) {
+	var opts []grpc.ServerOption
+	if cfg.TLS.Enabled {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("grpc tls: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	lis, err := net.Listen("tcp", cfg.GRPCAddress)
+	if err != nil {
+		return nil, nil, fmt.Errorf("grpc listen: %w", err)
+	}
+	return grpcserver.NewGRPCServer(svc, p, signer, obs, opts...), lis, nil
+}
+
+// stopGRPC дожидается активных вызовов, а по таймауту обрывает их.
+func stopGRPC(srv *grpc.Server) func(context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			srv.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func baseHost(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func domainHosts(domains []string) []string {
+	hosts := make([]string, 0, len(domains))
+	for _, d := range domains {
+		hosts = append(hosts, baseHost("http://"+d))
+	}
+	return hosts
+}
+
+// createPersister журнал для хранилища в памяти; с БД файл не пишется, и журнал не нужен.
+// bbolt при первом запуске один раз забирает ссылки из файла, поэтому читает его тем же журналом.
+func createPersister(cfg *config.Config, m *metrics.Metrics) (filestorage.Persister, error) {
+	_, isBolt := bolt.PathFromDSN(cfg.DBDSN)
+	if (cfg.DBDSN != "" && !isBolt) || cfg.Storage.Mode == config.StorageSnapshot {
+		return filestorage.NewJSONStore(cfg.FilePath, filestorage.WithSaveObserver(m.ObserveSave)), nil
+	}
+	policy, err := filestorage.ParseSyncPolicy(cfg.Storage.Sync, cfg.Storage.SyncInterval)
+	if err != nil {
+		return nil, err
+	}
+	return filestorage.NewLogStore(cfg.FilePath,
+		filestorage.WithSync(policy, cfg.Storage.SyncInterval),
+		filestorage.WithCompaction(cfg.Storage.CompactInterval, 1000),
+		filestorage.WithCompactObserver(m.ObserveSave),
+	), nil
+}
+
+// backend внешняя БД со ссылками; nil, когда ссылки живут в памяти.
+type backend struct {
+	// pool есть только у Postgres: его статистика уходит в метрики
+	pool   *pgxpool.Pool
+	clicks repository.ClickRepo
+	// keys пул ключей в БД (Postgres, Redis); без него пул держится в памяти
+	keys   repository.KeyPool
+	pinger handlers.Pinger
+	close  func()
+}
+
+func createRepo(cfg *config.Config, fileStorage filestorage.Persister, m *metrics.Metrics) (*persisted.Repo, *backend, error) {
+	if redisrepo.IsDSN(cfg.DBDSN) {
+		rdb, err := redisrepo.Connect(cfg.DBDSN)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo := redisrepo.NewRepo(rdb)
+		// транзакций у Redis нет: пакет пишется конвейером, а конфликты пропускаются
+		persisterdRepo, err := persisted.New(repo, nil, nil, fileStorage, nil, nil, repo)
+		if err != nil {
+			_ = rdb.Close()
+			return nil, nil, err
+		}
+		return persisterdRepo, &backend{
+			clicks: repo,
+			keys:   repo,
+			pinger: repo,
+			close:  func() { _ = rdb.Close() },
+		}, nil
+	}
+	if path, ok := bolt.PathFromDSN(cfg.DBDSN); ok {
+		db, err := bolt.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo := bolt.NewRepo(db)
+		// в новую базу переносим ссылки из файлового хранилища; дальше файл не читается
+		var seed repository.Seeder
+		empty, err := repo.Empty()
+		if err != nil {
+			_ = db.Close()
+			return nil, nil, err
+		}
+		if empty {
+			seed = repo
+		}
+		persisterdRepo, err := persisted.New(repo, seed, nil, fileStorage, nil, repo, repo)
+		if err != nil {
+			_ = db.Close()
+			return nil, nil, err
+		}
+		return persisterdRepo, &backend{
+			pinger: repo,
+			close:  func() { _ = db.Close() },
+		}, nil
+	}
+	if path, ok := sqlite.PathFromDSN(cfg.DBDSN); ok {
+		db, err := sqlite.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		seq, err := sqlite.OpenSequence(db, path)
+		if err != nil {
+			_ = db.Close()
+			return nil, nil, err
+		}
+		closeDB := func() {
+			_ = seq.Close()
+			_ = db.Close()
+		}
+		repo := sqlite.NewRepo(db, seq)
+		persisterdRepo, err := persisted.New(repo, nil, nil, fileStorage, nil, repo, repo)
+		if err != nil {
+			closeDB()
+			return nil, nil, err
+		}
+		return persisterdRepo, &backend{
+			clicks: repo,
+			pinger: repo,
+			close:  closeDB,
+		}, nil
+	}
 	if cfg.DBDSN != "" {
 		db, err := psql.Connect(cfg.DBDSN)
 		if err != nil {
@@ -61,20 +419,88 @@ func createRepo(cfg *config.Config) (*persisted.Repo, *pgxpool.Pool, error) {
 		repo := psql.NewRepo(db)
 		persisterdRepo, err := persisted.New(repo, nil, nil, fileStorage, nil, repo, repo)
 		if err != nil {
+			db.Close()
 			return nil, nil, err
 		}
-		return persisterdRepo, db, nil
+		return persisterdRepo, &backend{
+			pool:   db,
+			clicks: repo,
+			keys:   repo,
+			pinger: db,
+			close:  db.Close,
+		}, nil
 	}
 	repo := inmemory.NewRepo()
-	persisterdRepo, err := persisted.New(repo, repo, repo, fileStorage, repo, nil, repo)
+	var opts []persisted.Option
+	if s := cfg.Storage; s.Write == config.WriteBehind {
+		opts = append(opts, persisted.WithWriteBehind(s.FlushInterval, s.FlushBatch, s.Queue, m))
+	}
+	persisterdRepo, err := persisted.New(repo, repo, repo, fileStorage, repo, nil, repo, opts...)
 	if err != nil {
 		return nil, nil, err
 	}
 	return persisterdRepo, nil, nil
 }
 
+func createGenerator(cfg *config.Config, seq repository.Sequence) (usvc.CodeGenerator, error) {
+	g := cfg.Generator
+	if g.MaxLength <= g.Length {
+		return usvc.NewGenerator(g.Kind, g.Length, g.Salt, seq)
+	}
+	factory := func(n int) (usvc.CodeGenerator, error) {
+		return usvc.NewGenerator(g.Kind, n, g.Salt, seq)
+	}
+	return usvc.NewAdaptiveGenerator(factory, usvc.DefaultAdaptiveConfig(g.Length, g.MaxLength))
+}
+
+func createKeyPool(r repository.Repository, db *backend) repository.KeyPool {
+	if db != nil && db.keys != nil {
+		return db.keys
+	}
+	return inmemory.NewKeyPool(r)
+}
+
+func createClickRepo(cfg *config.Config, db *backend) (repository.ClickRepo, error) {
+	if db != nil && db.clicks != nil {
+		return db.clicks, nil
+	}
+	if cfg.ClicksFilePath == "" {
+		return inmemory.NewClickRepo(), nil
+	}
+	return persisted.NewClickRepo(inmemory.NewClickRepo(), filestorage.NewClickLog(cfg.ClicksFilePath))
+}
+
+// createAudit собирает приёмники аудита из конфига; без них аудит выключен.
+func createAudit(cfg *config.Config) *audit.Publisher {
+	var sinks []audit.Sink
+	if cfg.Audit.File != "" {
+		sinks = append(sinks, audit.NewFileSink(cfg.Audit.File))
+	}
+	if cfg.Audit.URL != "" {
+		sinks = append(sinks, audit.NewHTTPSink(cfg.Audit.URL, &http.Client{Timeout: 5 * time.Second}))
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	return audit.NewPublisher(10000, 5*time.Second, sinks...)
+}
+
+func createSigner(cfg *config.Config) (*auth.Signer, error) {
+	if cfg.AuthSecret != "" {
+		return auth.NewSigner([]byte(cfg.AuthSecret)), nil
+	}
+	logger.Log.Warn("auth secret is not set, generated random one: cookies will not survive restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generate auth secret: %w", err)
+	}
+	return auth.NewSigner(secret), nil
+}
+
+// runMigrate накатывает миграции Postgres; SQLite мигрирует себя сам при открытии,
+// а у bbolt и Redis схемы нет.
 func runMigrate(cfg *config.Config) error {
-	if cfg.DBDSN == "" {
+	if !isPostgres(cfg.DBDSN) {
 		return nil
 	}
 	db, err := sql.Open("pgx", cfg.DBDSN)
@@ -94,6 +520,12 @@ func runMigrate(cfg *config.Config) error {
 	return nil
 }
 
+func isPostgres(dsn string) bool {
+	_, isSQLite := sqlite.PathFromDSN(dsn)
+	_, isBolt := bolt.PathFromDSN(dsn)
+	return dsn != "" && !isSQLite && !isBolt && !redisrepo.IsDSN(dsn)
+}
+
 func isInsufficientPrivilege(err error) bool {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
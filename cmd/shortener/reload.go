@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/IvanOplesnin/url-shortener/internal/config"
+	handlers "github.com/IvanOplesnin/url-shortener/internal/handler"
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+)
+
+// reloader перечитывает конфиг по SIGHUP или из админской ручки и применяет
+// логгер, сжатие, лимиты и блок-листы. Остальные изменения только логируются.
+type reloader struct {
+	mu   sync.Mutex
+	cur  config.Config
+	load func() (*config.Config, error)
+	rt   *handlers.Runtime
+	svc  *shortener.Service
+}
+
+func newReloader(cur *config.Config, rt *handlers.Runtime, svc *shortener.Service) *reloader {
+	return &reloader{
+		cur: *cur,
+		load: func() (*config.Config, error) {
+			return config.Load(os.Args[1:], os.LookupEnv)
+		},
+		rt:  rt,
+		svc: svc,
+	}
+}
+
+func (r *reloader) Reload(_ context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.load()
+	if err != nil {
+		return nil, fmt.Errorf("reload config: %w", err)
+	}
+	settings, err := runtimeSettings(next)
+	if err != nil {
+		return nil, fmt.Errorf("reload config: %w", err)
+	}
+	// конфиг уже проверен целиком, дальше ошибок быть не должно
+	if err := logger.SetupLogger(next.Logger.Level, next.Logger.Format); err != nil {
+		return nil, fmt.Errorf("reload config: %w", err)
+	}
+	r.rt.Apply(settings)
+	r.svc.SetBlockedDomains(next.Blocklist.Domains)
+
+	merged, frozen := r.cur.Reload(next)
+	for _, name := range frozen {
+		logger.Log.Warnf("reload: %s changed but can't be applied without restart, ignored", name)
+	}
+	r.cur = merged
+	logger.Log.Infof("config reloaded: %s", &r.cur)
+	return frozen, nil
+}
+
+// watch перезагружает конфиг на каждый сигнал из sig, пока не отменён ctx.
+func (r *reloader) watch(ctx context.Context, sig <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			if _, err := r.Reload(ctx); err != nil {
+				logger.Log.Errorf("%s, keeping previous settings", err)
+			}
+		}
+	}
+}
+
+func runtimeSettings(cfg *config.Config) (handlers.RuntimeSettings, error) {
+	nets, err := cfg.Blocklist.ClientNets()
+	if err != nil {
+		return handlers.RuntimeSettings{}, err
+	}
+	return handlers.RuntimeSettings{
+		GzipEnabled:    cfg.Compression.Enabled,
+		GzipLevel:      cfg.Compression.Level,
+		MaxBodyBytes:   cfg.Limits.MaxBodyBytes,
+		RateLimit:      cfg.Limits.RateLimit,
+		RateBurst:      cfg.Limits.RateBurst,
+		BlockedClients: nets,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+)
+
+type shutdownStep struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// shutdown как defer для компонентов сервера: шаги выполняются в обратном порядке,
+// каждый со своим дедлайном, и ошибка одного не мешает остальным.
+type shutdown struct {
+	timeout time.Duration
+	steps   []shutdownStep
+}
+
+func newShutdown(timeout time.Duration) *shutdown {
+	return &shutdown{timeout: timeout}
+}
+
+func (s *shutdown) push(name string, fn func(ctx context.Context) error) {
+	s.steps = append(s.steps, shutdownStep{name: name, fn: fn})
+}
+
+func (s *shutdown) run() {
+	for i := len(s.steps) - 1; i >= 0; i-- {
+		step := s.steps[i]
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		start := time.Now()
+		if err := step.fn(ctx); err != nil {
+			logger.Log.Errorf("shutdown %s: %s", step.name, err)
+		} else {
+			logger.Log.Infof("shutdown %s: done in %s", step.name, time.Since(start))
+		}
+		cancel()
+	}
+	s.steps = nil
+}
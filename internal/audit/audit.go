@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+)
+
+const (
+	ActionShorten = "shorten"
+	ActionFollow  = "follow"
+)
+
+// Event запись аудита: кто и когда создал ссылку на url или перешёл по ней.
+type Event struct {
+	TS     int64  `json:"ts"`
+	Action string `json:"action"`
+	UserID string `json:"user_id,omitempty"`
+	URL    string `json:"url"`
+}
+
+// NewEvent событие с текущим временем в секундах Unix.
+func NewEvent(action, userID, url string) Event {
+	return Event{TS: time.Now().Unix(), Action: action, UserID: userID, URL: url}
+}
+
+// Observer получает события от сервиса и хендлеров; Notify не должен блокировать.
+type Observer interface {
+	Notify(e Event)
+}
+
+// Sink куда доставляются события: файл, HTTP и т.п.
+type Sink interface {
+	Write(ctx context.Context, e Event) error
+}
+
+// Publisher раздаёт события по приёмникам. У каждого приёмника свой буфер и горутина,
+// так что медленный HTTP не задерживает ни файл, ни редирект; при переполнении
+// буфера событие для этого приёмника отбрасывается.
+type Publisher struct {
+	sinks   []*queue
+	timeout time.Duration
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+	done   chan struct{}
+}
+
+type queue struct {
+	sink Sink
+	in   chan Event
+}
+
+func NewPublisher(bufferSize int, timeout time.Duration, sinks ...Sink) *Publisher {
+	p := &Publisher{timeout: timeout, done: make(chan struct{})}
+	for _, s := range sinks {
+		p.sinks = append(p.sinks, &queue{sink: s, in: make(chan Event, bufferSize)})
+	}
+	return p
+}
+
+func (p *Publisher) Notify(e Event) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	for _, q := range p.sinks {
+		select {
+		case q.in <- e:
+		default:
+			p.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped число событий, не попавших в буфер какого-либо приёмника.
+func (p *Publisher) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+func (p *Publisher) Run() {
+	defer close(p.done)
+	for _, q := range p.sinks {
+		p.wg.Add(1)
+		go func(q *queue) {
+			defer p.wg.Done()
+			for e := range q.in {
+				p.deliver(q.sink, e)
+			}
+		}(q)
+	}
+	p.wg.Wait()
+}
+
+func (p *Publisher) deliver(s Sink, e Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	if err := s.Write(ctx, e); err != nil {
+		logger.Log.Errorf("audit %s event: %s", e.Action, err)
+	}
+}
+
+// Close дожидается доставки того, что уже в буферах, а по ctx бросает остаток.
+func (p *Publisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	for _, q := range p.sinks {
+		close(q.in)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type blockingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	got     []Event
+}
+
+func (s *blockingSink) Write(ctx context.Context, e Event) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.got = append(s.got, e)
+	return nil
+}
+
+func TestPublisherSlowSinkDoesNotBlock(t *testing.T) {
+	slow := &blockingSink{release: make(chan struct{})}
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	p := NewPublisher(2, time.Second, slow, NewFileSink(path))
+	go p.Run()
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		p.Notify(NewEvent(ActionFollow, "user-1", "https://ya.ru"))
+	}
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+
+	close(slow.release)
+	require.NoError(t, p.Close(context.Background()))
+
+	// файл успевает за всеми событиями, медленный приёмник теряет то, что не влезло в буфер
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	lines := 0
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &e))
+		require.Equal(t, ActionFollow, e.Action)
+		lines++
+	}
+	require.Positive(t, p.Dropped())
+	require.LessOrEqual(t, len(slow.got), 3)
+	require.Equal(t, int64(20), int64(lines+len(slow.got))+p.Dropped())
+
+	// после Close события молча отбрасываются
+	p.Notify(NewEvent(ActionShorten, "", "https://ya.ru"))
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		got = Event{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.UserID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPSink(srv.URL, nil)
+	e := Event{TS: 1700000000, Action: ActionShorten, UserID: "user-1", URL: "https://practicum.yandex.ru"}
+	require.NoError(t, sink.Write(context.Background(), e))
+	require.Equal(t, e, got)
+
+	require.Error(t, sink.Write(context.Background(), Event{Action: ActionShorten}))
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileSink дописывает события в файл по одному JSON на строку.
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSink(path string) *FileSink { return &FileSink{path: path} }
+
+func (s *FileSink) Write(_ context.Context, e Event) error {
+	const msg = "audit.FileSink.Write"
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("%s: encode: %w", msg, err)
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("%s: mkdir: %w", msg, err)
+		}
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("%s: open: %w", msg, err)
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%s: write: %w", msg, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%s: close: %w", msg, err)
+	}
+	return nil
+}
+
+// HTTPSink отправляет каждое событие POST-запросом с JSON в теле.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPSink{url: url, client: client}
+}
+
+func (s *HTTPSink) Write(ctx context.Context, e Event) error {
+	const msg = "audit.HTTPSink.Write"
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("%s: encode: %w", msg, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("%s: request: %w", msg, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: post: %w", msg, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: unexpected status %d", msg, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package auth
+
+import "context"
+
+type User struct {
+	ID string
+	// IsNew выставляется, если пользователь пришёл без валидной куки и id был выдан только что.
+	IsNew bool
+}
+
+type userKey struct{}
+
+func WithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+func UserFromContext(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(userKey{}).(User)
+	return u, ok
+}
+
+// UserID возвращает id пользователя из контекста или пустую строку.
+func UserID(ctx context.Context) string {
+	u, _ := UserFromContext(ctx)
+	return u.ID
+}
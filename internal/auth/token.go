@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidToken = errors.New("invalid auth token")
+
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret}
+}
+
+// Sign возвращает токен вида "<userID>.<hmac-sha256(userID)>".
+func (s *Signer) Sign(userID string) string {
+	return userID + "." + base64.RawURLEncoding.EncodeToString(s.mac(userID))
+}
+
+func (s *Signer) Verify(token string) (string, error) {
+	userID, sig, ok := strings.Cut(token, ".")
+	if !ok || userID == "" {
+		return "", ErrInvalidToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	if !hmac.Equal(got, s.mac(userID)) {
+		return "", ErrInvalidToken
+	}
+	return userID, nil
+}
+
+func (s *Signer) mac(userID string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(userID))
+	return h.Sum(nil)
+}
+
+func NewUserID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("auth new user id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,22 +1,87 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/IvanOplesnin/url-shortener/internal/logger"
 )
 
 const (
+	ConfigKEY    = "CONFIG"
+	LogLevelKEY  = "LOG_LEVEL"
+	LogFormatKEY = "LOG_FORMAT"
+
 	AddressKEY  = "SERVER_ADDRESS"
 	BaseURLKEY  = "BASE_URL"
 	FilePathKEY = "FILE_STORAGE_PATH"
 	DatabaseDSN = "DATABASE_DSN"
+	AuthKEY     = "AUTH_SECRET"
+	ClicksKEY   = "CLICKS_FILE_PATH"
+	SweepKEY    = "SWEEP_INTERVAL"
+
+	AliasMinLenKEY   = "ALIAS_MIN_LEN"
+	AliasMaxLenKEY   = "ALIAS_MAX_LEN"
+	AliasPatternKEY  = "ALIAS_PATTERN"
+	AliasReservedKEY = "ALIAS_RESERVED"
+
+	GeneratorKEY  = "CODE_GENERATOR"
+	CodeLengthKEY = "CODE_LENGTH"
+	CodeMaxKEY    = "CODE_MAX_LENGTH"
+	CodeSaltKEY   = "CODE_SALT"
+
+	EnableHTTPSKEY = "ENABLE_HTTPS"
+	TLSCertKEY     = "TLS_CERT_FILE"
+	TLSKeyKEY      = "TLS_KEY_FILE"
+
+	ReadTimeoutKEY     = "READ_TIMEOUT"
+	WriteTimeoutKEY    = "WRITE_TIMEOUT"
+	IdleTimeoutKEY     = "IDLE_TIMEOUT"
+	ShutdownTimeoutKEY = "SHUTDOWN_TIMEOUT"
+
+	KeyPoolSizeKEY     = "KEY_POOL_SIZE"
+	KeyPoolIntervalKEY = "KEY_POOL_INTERVAL"
+
+	GzipEnabledKEY = "GZIP_ENABLED"
+	GzipLevelKEY   = "GZIP_LEVEL"
+
+	MaxBodyBytesKEY = "MAX_BODY_BYTES"
+	RateLimitKEY    = "RATE_LIMIT"
+	RateBurstKEY    = "RATE_BURST"
+
+	BlockedDomainsKEY = "BLOCKED_DOMAINS"
+	BlockedClientsKEY = "BLOCKED_CLIENTS"
+
+	AdminTokenKEY = "ADMIN_TOKEN"
+
+	TrustedProxiesKEY = "TRUSTED_PROXIES"
+	DomainsKEY        = "DOMAINS"
+
+	GRPCAddressKEY   = "GRPC_ADDRESS"
+	TrustedSubnetKEY = "TRUSTED_SUBNET"
+
+	AuditFileKEY = "AUDIT_FILE"
+	AuditURLKEY  = "AUDIT_URL"
+
+	TraceExporterKEY = "TRACE_EXPORTER"
+	TraceFileKEY     = "TRACE_FILE"
+
+	StorageModeKEY            = "FILE_STORAGE_MODE"
+	StorageSyncKEY            = "FILE_STORAGE_SYNC"
+	StorageSyncIntervalKEY    = "FILE_STORAGE_SYNC_INTERVAL"
+	StorageCompactIntervalKEY = "FILE_STORAGE_COMPACT_INTERVAL"
+	StorageWriteKEY           = "FILE_STORAGE_WRITE"
+	StorageFlushIntervalKEY   = "FILE_STORAGE_FLUSH_INTERVAL"
+	StorageFlushBatchKEY      = "FILE_STORAGE_FLUSH_BATCH"
+	StorageQueueKEY           = "FILE_STORAGE_QUEUE"
 )
 
 type Server struct {
@@ -66,12 +131,156 @@ type Logger struct {
 	Format logger.Formatter `env:"LOG_FORMAT"`
 }
 
+// HTTP таймауты сервера и время на корректную остановку.
+type HTTP struct {
+	ReadTimeout  time.Duration `env:"READ_TIMEOUT"`
+	WriteTimeout time.Duration `env:"WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `env:"IDLE_TIMEOUT"`
+	// ShutdownTimeout сколько ждать завершения активных запросов, а затем фоновых воркеров.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT"`
+}
+
+// TLS включает HTTPS; если файлов нет, при старте выпускается самоподписанный сертификат.
+type TLS struct {
+	Enabled  bool   `env:"ENABLE_HTTPS"`
+	CertFile string `env:"TLS_CERT_FILE"`
+	KeyFile  string `env:"TLS_KEY_FILE"`
+}
+
+// Alias правила для пользовательских коротких id.
+type Alias struct {
+	MinLen  int    `env:"ALIAS_MIN_LEN"`
+	MaxLen  int    `env:"ALIAS_MAX_LEN"`
+	Pattern string `env:"ALIAS_PATTERN"`
+	// Reserved дополняет встроенный список зарезервированных слов, через запятую.
+	Reserved []string `env:"ALIAS_RESERVED"`
+}
+
+// Generator стратегия генерации коротких id: random, sequence, sqids или hash.
+type Generator struct {
+	Kind   string `env:"CODE_GENERATOR"`
+	Length int    `env:"CODE_LENGTH"`
+	// MaxLength до какой длины код может вырасти при частых коллизиях; равен Length — рост выключен.
+	MaxLength int `env:"CODE_MAX_LENGTH"`
+	// Salt перемешивает алфавит в режиме sqids.
+	Salt string `env:"CODE_SALT"`
+	// PoolSize сколько ключей держать заранее сгенерированными; 0 — пул выключен.
+	PoolSize     int           `env:"KEY_POOL_SIZE"`
+	PoolInterval time.Duration `env:"KEY_POOL_INTERVAL"`
+}
+
+// Compression настройки gzip для ответов.
+type Compression struct {
+	Enabled bool `env:"GZIP_ENABLED"`
+	// Level уровень сжатия от 1 (быстрее) до 9 (плотнее).
+	Level int `env:"GZIP_LEVEL"`
+}
+
+// Limits ограничения на входящие запросы.
+type Limits struct {
+	MaxBodyBytes int64 `env:"MAX_BODY_BYTES"`
+	// RateLimit запросов в секунду с одного адреса; 0 — без ограничения.
+	RateLimit float64 `env:"RATE_LIMIT"`
+	RateBurst int     `env:"RATE_BURST"`
+}
+
+// Audit куда писать события создания ссылок и переходов; пустые поля — приёмник выключен.
+type Audit struct {
+	File string `env:"AUDIT_FILE"`
+	URL  string `env:"AUDIT_URL"`
+}
+
+// Tracing экспорт спанов OpenTelemetry: none, otlp (адрес из OTEL_EXPORTER_OTLP_*)
+// или stdout; File перенаправляет stdout-экспорт в файл.
+type Tracing struct {
+	Exporter string `env:"TRACE_EXPORTER"`
+	File     string `env:"TRACE_FILE"`
+}
+
+const (
+	StorageLog      = "log"
+	StorageSnapshot = "snapshot"
+
+	WriteSync   = "sync"
+	WriteBehind = "behind"
+)
+
+// Storage файловое хранилище без БД: log — снимок и журнал с дописыванием,
+// snapshot — перезапись всего файла на каждое изменение.
+type Storage struct {
+	Mode string `env:"FILE_STORAGE_MODE"`
+	// Sync политика fsync журнала: always, interval или none; разбирает её filestorage.
+	Sync         string        `env:"FILE_STORAGE_SYNC"`
+	SyncInterval time.Duration `env:"FILE_STORAGE_SYNC_INTERVAL"`
+	// CompactInterval как часто журнал сжимается в снимок.
+	CompactInterval time.Duration `env:"FILE_STORAGE_COMPACT_INTERVAL"`
+	// Write sync — каждое изменение сохраняется до ответа, behind — копится в очереди
+	// и сбрасывается раз в FlushInterval или по FlushBatch изменений.
+	Write         string        `env:"FILE_STORAGE_WRITE"`
+	FlushInterval time.Duration `env:"FILE_STORAGE_FLUSH_INTERVAL"`
+	FlushBatch    int           `env:"FILE_STORAGE_FLUSH_BATCH"`
+	// Queue сколько изменений может ждать сброса, дальше запросы ждут места.
+	Queue int `env:"FILE_STORAGE_QUEUE"`
+}
+
+// Blocklist запрещённые домены для сокращения и адреса клиентов.
+type Blocklist struct {
+	// Domains запрещает домен вместе с поддоменами.
+	Domains []string `env:"BLOCKED_DOMAINS"`
+	// Clients IP или подсети в CIDR-нотации.
+	Clients []string `env:"BLOCKED_CLIENTS"`
+}
+
 type Config struct {
 	Server   Server `env:"SERVER_ADDRESS"`
 	BaseURL  string `env:"BASE_URL"`
 	Logger   Logger
 	FilePath string `env:"FILE_STORAGE_PATH"`
+	Storage  Storage
 	DBDSN    string `env:"DATABASE_DSN"`
+	// AuthSecret ключ для подписи кук с id пользователя.
+	AuthSecret string `env:"AUTH_SECRET"`
+	// ClicksFilePath файл для кликов, если нет БД; пустой — клики только в памяти.
+	ClicksFilePath string `env:"CLICKS_FILE_PATH"`
+	// SweepInterval период удаления истёкших ссылок.
+	SweepInterval time.Duration `env:"SWEEP_INTERVAL"`
+	Alias         Alias
+	Generator     Generator
+	HTTP          HTTP
+	TLS           TLS
+	Compression   Compression
+	Limits        Limits
+	Blocklist     Blocklist
+	Audit         Audit
+	Tracing       Tracing
+	// AdminToken открывает /api/admin/*; пустой — админские ручки выключены.
+	AdminToken string `env:"ADMIN_TOKEN"`
+	// TrustedProxies IP или подсети прокси, чьим заголовкам Forwarded и X-Forwarded-*
+	// можно верить при построении ссылок; пусто — всегда берётся BaseURL.
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+	// Domains дополнительные короткие домены; у каждого свой набор ссылок, схема и путь — из BaseURL.
+	Domains []string `env:"DOMAINS"`
+	// GRPCAddress адрес gRPC API в форме host:port; пустой — gRPC выключен.
+	GRPCAddress string `env:"GRPC_ADDRESS"`
+	// TrustedSubnet CIDR, из которого доступна /api/internal/stats; пусто — ручка закрыта.
+	TrustedSubnet string `env:"TRUSTED_SUBNET"`
+}
+
+// ProxyNets разбирает TrustedProxies так же, как Blocklist.Clients.
+func (c *Config) ProxyNets() ([]*net.IPNet, error) {
+	return parseNets(c.TrustedProxies, "trusted proxy")
+}
+
+// SubnetNet разбирает TrustedSubnet; для пустого значения возвращает nil.
+func (c *Config) SubnetNet() (*net.IPNet, error) {
+	if c.TrustedSubnet == "" {
+		return nil, nil
+	}
+	_, subnet, err := net.ParseCIDR(strings.TrimSpace(c.TrustedSubnet))
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted subnet %q: %w", c.TrustedSubnet, err)
+	}
+	return subnet, nil
 }
 
 func (c *Config) String() string {
@@ -79,17 +288,36 @@ func (c *Config) String() string {
 	baseURL := fmt.Sprintf("BaseURl=%s", c.BaseURL)
 	logLevel := fmt.Sprintf("LogLevel=%s", c.Logger.Level)
 	logFormat := fmt.Sprintf("LogFormat=%s", c.Logger.Format)
-	filePath := fmt.Sprintf("filePath=%s", c.FilePath)
-	return strings.Join([]string{server, baseURL, logLevel, logFormat, filePath}, "; ") + "\n"
+	filePath := fmt.Sprintf("filePath=%s (%s, %s)", c.FilePath, c.Storage.Mode, c.Storage.Write)
+	clicksPath := fmt.Sprintf("clicksFilePath=%s", c.ClicksFilePath)
+	sweep := fmt.Sprintf("sweepInterval=%s", c.SweepInterval)
+	gen := fmt.Sprintf("generator=%s/%d..%d pool=%d", c.Generator.Kind, c.Generator.Length, c.Generator.MaxLength, c.Generator.PoolSize)
+	httpTimeouts := fmt.Sprintf("timeouts=read %s, write %s, idle %s, shutdown %s",
+		c.HTTP.ReadTimeout, c.HTTP.WriteTimeout, c.HTTP.IdleTimeout, c.HTTP.ShutdownTimeout)
+	https := fmt.Sprintf("https=%t grpc=%q", c.TLS.Enabled, c.GRPCAddress)
+	gzip := fmt.Sprintf("gzip=%t/%d", c.Compression.Enabled, c.Compression.Level)
+	limits := fmt.Sprintf("limits=body %d, rate %g/%d", c.Limits.MaxBodyBytes, c.Limits.RateLimit, c.Limits.RateBurst)
+	blocked := fmt.Sprintf("blocked=%d domains, %d clients", len(c.Blocklist.Domains), len(c.Blocklist.Clients))
+	return strings.Join([]string{server, baseURL, logLevel, logFormat, filePath, clicksPath, sweep, gen, httpTimeouts, https, gzip, limits, blocked}, "; ") + "\n"
 }
 
+// GetConfig собирает конфиг из умолчаний, файла (-c / CONFIG), окружения и флагов —
+// каждый следующий источник перекрывает предыдущий.
 func GetConfig() (*Config, error) {
-	const (
-		baseURLFlagUsage = `Base URL, e.g. "http://localhost:8080/"`
-		serverFlagUsage  = `Server address in form "host:port"`
-	)
+	cfg, err := Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("GetConfig: %s", cfg)
+	return cfg, nil
+}
+
+func defaults() Config {
 	cfg := Config{}
-	server := Server{
+	cfg.Server = Server{
 		Host: "localhost",
 		Port: 8080,
 	}
@@ -97,64 +325,241 @@ func GetConfig() (*Config, error) {
 	cfg.Logger.Level = "Info"
 	cfg.Logger.Format = logger.Text
 	cfg.FilePath = "data.json"
+	cfg.Storage.Mode = StorageLog
+	cfg.Storage.Sync = "interval"
+	cfg.Storage.SyncInterval = time.Second
+	cfg.Storage.CompactInterval = 5 * time.Minute
+	cfg.Storage.Write = WriteSync
+	cfg.Storage.FlushInterval = time.Second
+	cfg.Storage.FlushBatch = 1000
+	cfg.Storage.Queue = 10000
+	cfg.ClicksFilePath = "clicks.jsonl"
+	cfg.SweepInterval = time.Minute
+	cfg.Alias.MinLen = 3
+	cfg.Alias.MaxLen = 32
+	cfg.Alias.Pattern = `^[A-Za-z0-9_-]+$`
+	cfg.Generator.Kind = "random"
+	cfg.Generator.Length = 6
+	cfg.Generator.MaxLength = 10
+	cfg.Generator.PoolInterval = time.Second
+	cfg.HTTP.ReadTimeout = 10 * time.Second
+	cfg.HTTP.WriteTimeout = 15 * time.Second
+	cfg.HTTP.IdleTimeout = time.Minute
+	cfg.HTTP.ShutdownTimeout = 10 * time.Second
+	cfg.TLS.CertFile = "tls/cert.pem"
+	cfg.TLS.KeyFile = "tls/key.pem"
+	cfg.Compression.Enabled = true
+	cfg.Compression.Level = 1
+	cfg.Limits.MaxBodyBytes = 1 << 20
+	cfg.Limits.RateBurst = 20
+	cfg.Tracing.Exporter = "none"
+	return cfg
+}
 
-	flag.Var(&server, "a", serverFlagUsage)
-	flag.StringVar(&cfg.BaseURL, "b", cfg.BaseURL, baseURLFlagUsage)
-	flag.StringVar(&cfg.FilePath, "f", cfg.FilePath, "File path storage")
-	flag.StringVar(&cfg.DBDSN, "d", cfg.DBDSN, "Databse DSN")
+// Load разбирает args и окружение через lookupEnv. Все ошибки разбора и проверки
+// возвращаются разом, чтобы не чинить конфиг по одной строчке за запуск.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	opts := options()
 
-	flag.Parse()
+	fs := flag.NewFlagSet("shortener", flag.ContinueOnError)
+	flagValues := make(map[string]string)
+	for _, o := range opts {
+		name := o.flag
+		record := func(v string) error {
+			flagValues[name] = v
+			return nil
+		}
+		if o.isBool {
+			fs.BoolFunc(name, o.usage, record)
+		} else {
+			fs.Func(name, o.usage, record)
+		}
+	}
+	var configPath string
+	fs.StringVar(&configPath, "c", "", "Config file, json or yaml")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if configPath == "" {
+		configPath, _ = lookupEnv(ConfigKEY)
+	}
+
+	cfg := defaults()
+	var errs []error
 
-	if serverAddress, ok := os.LookupEnv(AddressKEY); ok {
-		if err := server.UnmarshalText([]byte(serverAddress)); err != nil {
+	if configPath != "" {
+		values, err := loadFile(configPath)
+		if err != nil {
 			return nil, err
 		}
+		known := make(map[string]struct{}, len(opts))
+		for _, o := range opts {
+			known[o.file] = struct{}{}
+			if v, ok := values[o.file]; ok {
+				if err := o.set(&cfg, v); err != nil {
+					errs = append(errs, fmt.Errorf("config file key %q: %w", o.file, err))
+				}
+			}
+		}
+		for k := range values {
+			if _, ok := known[k]; !ok {
+				errs = append(errs, fmt.Errorf("config file %s: unknown key %q", configPath, k))
+			}
+		}
 	}
 
-	if baseURL, ok := os.LookupEnv(BaseURLKEY); ok {
-		cfg.BaseURL = baseURL
+	for _, o := range opts {
+		if v, ok := lookupEnv(o.env); ok {
+			if err := o.set(&cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s %q: %w", o.env, v, err))
+			}
+		}
 	}
 
-	if filePath, ok := os.LookupEnv(FilePathKEY); ok {
-		cfg.FilePath = filePath
+	for _, o := range opts {
+		if v, ok := flagValues[o.flag]; ok {
+			if err := o.set(&cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid flag -%s %q: %w", o.flag, v, err))
+			}
+		}
 	}
 
-	if dsn, ok := os.LookupEnv("DATABASE_DSN"); ok {
-		cfg.DBDSN = dsn
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
+	return &cfg, nil
+}
 
-	cfg.Server = server
-
-	u, err := url.Parse(cfg.BaseURL)
-	if err != nil {
-		return nil, fmt.Errorf("invalid BaseURL %q: %v", cfg.BaseURL, err)
+func (c *Config) validate() []error {
+	var errs []error
+	if c.SweepInterval <= 0 {
+		errs = append(errs, fmt.Errorf("invalid sweep interval %s: must be positive", c.SweepInterval))
 	}
-	if u.Scheme == "" || u.Host == "" {
-		return nil, fmt.Errorf("invalid BaseURL %q: must include scheme and host, e.g. http://localhost:8080/", cfg.BaseURL)
+	if c.HTTP.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("invalid shutdown timeout %s: must be positive", c.HTTP.ShutdownTimeout))
+	}
+	// hash-код зависит от URL, заранее его не сгенерировать
+	if c.Generator.PoolSize > 0 && c.Generator.Kind == "hash" {
+		errs = append(errs, fmt.Errorf("key pool can't be used with hash generator"))
+	}
+	if c.Generator.PoolSize > 0 && c.Generator.PoolInterval <= 0 {
+		errs = append(errs, fmt.Errorf("invalid key pool interval %s: must be positive", c.Generator.PoolInterval))
+	}
+	if c.TLS.Enabled && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("TLS cert and key paths must not be empty"))
+	}
+	if err := c.validateBaseURL(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.ProxyNets(); err != nil {
+		errs = append(errs, err)
 	}
+	if c.Audit.URL != "" {
+		if u, err := url.Parse(c.Audit.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid audit url %q: must be http(s)://host/...", c.Audit.URL))
+		}
+	}
+	if _, err := c.SubnetNet(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, c.Storage.validate()...)
+	if c.GRPCAddress != "" {
+		var grpcAddr Server
+		if err := grpcAddr.Set(c.GRPCAddress); err != nil {
+			errs = append(errs, fmt.Errorf("invalid gRPC address: %w", err))
+		} else if grpcAddr == c.Server {
+			errs = append(errs, fmt.Errorf("gRPC address %s must differ from server address", c.GRPCAddress))
+		}
+	}
+	for _, d := range c.Domains {
+		if u, err := url.Parse("http://" + d + "/"); err != nil || u.Host != d || u.Hostname() == "" {
+			errs = append(errs, fmt.Errorf("invalid domain %q: must be host or host:port", d))
+		}
+	}
+	return append(errs, c.validateReloadable()...)
+}
 
-	urlHost := u.Hostname()
-	urlPortStr := u.Port()
+func (s Storage) validate() []error {
+	var errs []error
+	if s.Mode != StorageLog && s.Mode != StorageSnapshot {
+		errs = append(errs, fmt.Errorf("invalid file storage mode %q: must be log or snapshot", s.Mode))
+	}
+	if s.CompactInterval <= 0 {
+		errs = append(errs, fmt.Errorf("invalid file storage compact interval %s: must be positive", s.CompactInterval))
+	}
+	switch s.Write {
+	case WriteSync:
+	case WriteBehind:
+		if s.FlushInterval <= 0 {
+			errs = append(errs, fmt.Errorf("invalid file storage flush interval %s: must be positive", s.FlushInterval))
+		}
+		if s.FlushBatch < 1 {
+			errs = append(errs, fmt.Errorf("invalid file storage flush batch %d: must be at least 1", s.FlushBatch))
+		}
+		if s.Queue < 1 {
+			errs = append(errs, fmt.Errorf("invalid file storage queue %d: must be at least 1", s.Queue))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid file storage write mode %q: must be sync or behind", s.Write))
+	}
+	return errs
+}
 
-	if urlPortStr == "" {
-		return nil, fmt.Errorf(
-			"invalid BaseURL %q: port must be specified explicitly, e.g. http://%s:%d/",
-			cfg.BaseURL, cfg.Server.Host, cfg.Server.Port,
-		)
+// validateReloadable проверяет то, что можно поменять на лету: при перезагрузке
+// конфиг с ошибкой целиком отбрасывается.
+func (c *Config) validateReloadable() []error {
+	var errs []error
+	if err := logger.Check(c.Logger.Level, c.Logger.Format); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Compression.Level < 1 || c.Compression.Level > 9 {
+		errs = append(errs, fmt.Errorf("invalid gzip level %d: must be 1–9", c.Compression.Level))
+	}
+	if c.Limits.MaxBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("invalid max body size %d: must be positive", c.Limits.MaxBodyBytes))
+	}
+	if c.Limits.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("invalid rate limit %g: must not be negative", c.Limits.RateLimit))
+	}
+	if c.Limits.RateLimit > 0 && c.Limits.RateBurst < 1 {
+		errs = append(errs, fmt.Errorf("invalid rate burst %d: must be at least 1", c.Limits.RateBurst))
 	}
+	if _, err := c.Blocklist.ClientNets(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
 
-	urlPort, err := strconv.Atoi(urlPortStr)
+// validateBaseURL BaseURL — публичный адрес сервиса и от адреса, который слушает
+// сервер, не зависит: перед ним может стоять прокси с другим хостом, портом и путём.
+func (c *Config) validateBaseURL() error {
+	u, err := url.Parse(c.BaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("invalid BaseURL %q: bad port %q: %v", cfg.BaseURL, urlPortStr, err)
+		return fmt.Errorf("invalid BaseURL %q: %v", c.BaseURL, err)
 	}
-
-	if urlHost != cfg.Server.Host || urlPort != cfg.Server.Port {
-		return nil, fmt.Errorf(
-			"BaseURL %q does not match server address %s:%d; they must point to the same host and port",
-			cfg.BaseURL, cfg.Server.Host, cfg.Server.Port,
-		)
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid BaseURL %q: must include scheme and host, e.g. https://sho.rt/", c.BaseURL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid BaseURL %q: scheme must be http or https", c.BaseURL)
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid BaseURL %q: must not contain user info, query or fragment", c.BaseURL)
 	}
+	// TLS может завершаться на прокси, поэтому https при http-сервере допустим, а наоборот — нет
+	if c.TLS.Enabled && u.Scheme != "https" {
+		return fmt.Errorf("invalid BaseURL %q: scheme must be https when HTTPS is enabled", c.BaseURL)
+	}
+	return nil
+}
 
-	log.Printf("GetConfig: %s", &cfg)
-	return &cfg, nil
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
 }
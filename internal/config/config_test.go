@@ -0,0 +1,149 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func envMap(m map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := m[k]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.json", `{
+		"server_address": "localhost:9090",
+		"base_url": "http://localhost:9090/",
+		"file_storage_path": "from-file.json",
+		"database_dsn": "postgres://file",
+		"sweep_interval": "5m",
+		"code_length": 8,
+		"alias_reserved": ["admin", "static"]
+	}`)
+
+	cfg, err := Load(
+		[]string{"-c", path, "-f", "from-flag.json"},
+		envMap(map[string]string{
+			FilePathKEY: "from-env.json",
+			DatabaseDSN: "postgres://env",
+		}),
+	)
+	require.NoError(t, err)
+
+	// файл перекрывает умолчания
+	require.Equal(t, "localhost:9090", cfg.Server.String())
+	require.Equal(t, 5*time.Minute, cfg.SweepInterval)
+	require.Equal(t, 8, cfg.Generator.Length)
+	require.Equal(t, []string{"admin", "static"}, cfg.Alias.Reserved)
+	// окружение перекрывает файл
+	require.Equal(t, "postgres://env", cfg.DBDSN)
+	// флаг перекрывает окружение
+	require.Equal(t, "from-flag.json", cfg.FilePath)
+	// не заданное нигде берётся по умолчанию
+	require.Equal(t, "clicks.jsonl", cfg.ClicksFilePath)
+}
+
+func TestLoadYAMLFromEnv(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server_address: localhost:9443
+base_url: https://localhost:9443/
+enable_https: true
+read_timeout: 3s
+`)
+
+	cfg, err := Load(nil, envMap(map[string]string{ConfigKEY: path}))
+	require.NoError(t, err)
+	require.True(t, cfg.TLS.Enabled)
+	require.Equal(t, 3*time.Second, cfg.HTTP.ReadTimeout)
+}
+
+func TestLoadAggregatesErrors(t *testing.T) {
+	path := writeFile(t, "config.json", `{"sweep_interval": "soon", "bse_url": "http://x:1/"}`)
+
+	_, err := Load(
+		[]string{"-c", path, "-code-len", "six"},
+		envMap(map[string]string{ShutdownTimeoutKEY: "-1s"}),
+	)
+	require.Error(t, err)
+
+	msg := err.Error()
+	for _, want := range []string{`"sweep_interval"`, `unknown key "bse_url"`, "-code-len", "shutdown timeout"} {
+		require.True(t, strings.Contains(msg, want), "expected %q in %q", want, msg)
+	}
+}
+
+func TestLoadBaseURLScheme(t *testing.T) {
+	_, err := Load([]string{"-s"}, envMap(nil))
+	require.ErrorContains(t, err, "scheme must be https")
+
+	cfg, err := Load([]string{"-s", "-b", "https://localhost:8080/"}, envMap(nil))
+	require.NoError(t, err)
+	require.True(t, cfg.TLS.Enabled)
+}
+
+func TestLoadBaseURLBehindProxy(t *testing.T) {
+	// адрес за прокси не обязан совпадать с тем, что слушает сервер
+	cfg, err := Load([]string{"-a", "0.0.0.0:8080", "-b", "https://sho.rt/s/", "-trusted-proxies", "10.0.0.0/8,127.0.0.1"}, envMap(nil))
+	require.NoError(t, err)
+	require.Equal(t, "https://sho.rt/s/", cfg.BaseURL)
+	nets, err := cfg.ProxyNets()
+	require.NoError(t, err)
+	require.Len(t, nets, 2)
+
+	for _, bad := range []string{"sho.rt", "ftp://sho.rt/", "https://sho.rt/?a=1", "https://user@sho.rt/"} {
+		_, err := Load([]string{"-b", bad}, envMap(nil))
+		require.ErrorContains(t, err, "invalid BaseURL", bad)
+	}
+	_, err = Load(nil, envMap(map[string]string{TrustedProxiesKEY: "10.0.0.0/33"}))
+	require.ErrorContains(t, err, "invalid trusted proxy")
+}
+
+func TestReload(t *testing.T) {
+	cur, err := Load(nil, envMap(nil))
+	require.NoError(t, err)
+
+	path := writeFile(t, "config.yaml", `
+server_address: localhost:9090
+base_url: http://localhost:9090/
+log_level: debug
+gzip_level: 6
+rate_limit: 2.5
+blocked_domains: [evil.com, spam.org]
+blocked_clients: [10.0.0.0/8, 192.168.1.7]
+`)
+	next, err := Load(nil, envMap(map[string]string{ConfigKEY: path}))
+	require.NoError(t, err)
+
+	merged, frozen := cur.Reload(next)
+	require.ElementsMatch(t, []string{"Server", "BaseURL"}, frozen)
+	// адрес остаётся прежним, остальное применяется
+	require.Equal(t, "localhost:8080", merged.Server.String())
+	require.Equal(t, "debug", merged.Logger.Level)
+	require.Equal(t, 6, merged.Compression.Level)
+	require.Equal(t, 2.5, merged.Limits.RateLimit)
+	require.Equal(t, []string{"evil.com", "spam.org"}, merged.Blocklist.Domains)
+
+	nets, err := merged.Blocklist.ClientNets()
+	require.NoError(t, err)
+	require.Len(t, nets, 2)
+	require.Equal(t, "192.168.1.7/32", nets[1].String())
+
+	_, err = Load([]string{"-gzip-level", "0", "-log-level", "loud", "-blocked-clients", "1.2.3"}, envMap(nil))
+	for _, want := range []string{"gzip level", "log level", "blocked client"} {
+		require.ErrorContains(t, err, want)
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile читает плоский JSON или YAML (по расширению .yaml/.yml) и приводит значения к строкам,
+// чтобы дальше они разбирались теми же сеттерами, что и переменные окружения.
+func loadFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	raw := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("parse yaml config %s: %w", path, err)
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("parse json config %s: %w", path, err)
+		}
+	}
+
+	out := make(map[string]string, len(raw))
+	for k, v := range raw {
+		s, err := scalarString(v)
+		if err != nil {
+			return nil, fmt.Errorf("config file key %q: %w", k, err)
+		}
+		out[k] = s
+	}
+	return out, nil
+}
+
+func scalarString(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := scalarString(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value of type %T", v)
+	}
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+)
+
+// option одна настройка и три её имени: флаг, переменная окружения и ключ в файле конфига.
+type option struct {
+	flag   string
+	env    string
+	file   string
+	usage  string
+	isBool bool
+	set    func(c *Config, v string) error
+}
+
+func options() []option {
+	return []option{
+		{flag: "a", env: AddressKEY, file: "server_address", usage: `Server address in form "host:port"`,
+			set: func(c *Config, v string) error { return c.Server.Set(v) }},
+		{flag: "b", env: BaseURLKEY, file: "base_url", usage: `Base URL, e.g. "http://localhost:8080/"`,
+			set: setString(func(c *Config) *string { return &c.BaseURL })},
+		{flag: "log-level", env: LogLevelKEY, file: "log_level", usage: "Log level",
+			set: setString(func(c *Config) *string { return &c.Logger.Level })},
+		{flag: "log-format", env: LogFormatKEY, file: "log_format", usage: "Log format: text or json",
+			set: func(c *Config, v string) error { c.Logger.Format = logger.Formatter(v); return nil }},
+		{flag: "f", env: FilePathKEY, file: "file_storage_path", usage: "File path storage",
+			set: setString(func(c *Config) *string { return &c.FilePath })},
+		{flag: "file-storage-mode", env: StorageModeKEY, file: "file_storage_mode", usage: "File storage mode: log or snapshot",
+			set: setString(func(c *Config) *string { return &c.Storage.Mode })},
+		{flag: "file-storage-sync", env: StorageSyncKEY, file: "file_storage_sync", usage: "Fsync policy of storage log: always, interval or none",
+			set: setString(func(c *Config) *string { return &c.Storage.Sync })},
+		{flag: "file-storage-sync-interval", env: StorageSyncIntervalKEY, file: "file_storage_sync_interval", usage: "Fsync interval of storage log",
+			set: setDuration(func(c *Config) *time.Duration { return &c.Storage.SyncInterval })},
+		{flag: "file-storage-compact-interval", env: StorageCompactIntervalKEY, file: "file_storage_compact_interval", usage: "Interval of storage log compaction",
+			set: setDuration(func(c *Config) *time.Duration { return &c.Storage.CompactInterval })},
+		{flag: "file-storage-write", env: StorageWriteKEY, file: "file_storage_write", usage: "File storage write mode: sync or behind",
+			set: setString(func(c *Config) *string { return &c.Storage.Write })},
+		{flag: "file-storage-flush-interval", env: StorageFlushIntervalKEY, file: "file_storage_flush_interval", usage: "Flush interval of write-behind mode",
+			set: setDuration(func(c *Config) *time.Duration { return &c.Storage.FlushInterval })},
+		{flag: "file-storage-flush-batch", env: StorageFlushBatchKEY, file: "file_storage_flush_batch", usage: "Changes that trigger a write-behind flush before the interval",
+			set: setInt(func(c *Config) *int { return &c.Storage.FlushBatch })},
+		{flag: "file-storage-queue", env: StorageQueueKEY, file: "file_storage_queue", usage: "Max changes waiting for a write-behind flush",
+			set: setInt(func(c *Config) *int { return &c.Storage.Queue })},
+		{flag: "d", env: DatabaseDSN, file: "database_dsn", usage: "Database DSN: postgresql://..., redis://host:port/db, sqlite://path/to/file.db or bolt://path/to/file.db",
+			set: setString(func(c *Config) *string { return &c.DBDSN })},
+		{flag: "k", env: AuthKEY, file: "auth_secret", usage: "Secret key for signing auth cookies",
+			set: setString(func(c *Config) *string { return &c.AuthSecret })},
+		{flag: "clicks-file", env: ClicksKEY, file: "clicks_file_path", usage: "File path for click events",
+			set: setString(func(c *Config) *string { return &c.ClicksFilePath })},
+		{flag: "sweep-interval", env: SweepKEY, file: "sweep_interval", usage: "Interval of purging expired links",
+			set: setDuration(func(c *Config) *time.Duration { return &c.SweepInterval })},
+
+		{flag: "alias-min", env: AliasMinLenKEY, file: "alias_min_len", usage: "Min length of custom alias",
+			set: setInt(func(c *Config) *int { return &c.Alias.MinLen })},
+		{flag: "alias-max", env: AliasMaxLenKEY, file: "alias_max_len", usage: "Max length of custom alias",
+			set: setInt(func(c *Config) *int { return &c.Alias.MaxLen })},
+		{flag: "alias-pattern", env: AliasPatternKEY, file: "alias_pattern", usage: "Regexp for custom alias",
+			set: setString(func(c *Config) *string { return &c.Alias.Pattern })},
+		{flag: "alias-reserved", env: AliasReservedKEY, file: "alias_reserved", usage: "Extra reserved aliases, comma separated",
+			set: func(c *Config, v string) error { c.Alias.Reserved = splitList(v); return nil }},
+
+		{flag: "gen", env: GeneratorKEY, file: "code_generator", usage: "Short code generator: random, sequence, sqids, hash",
+			set: setString(func(c *Config) *string { return &c.Generator.Kind })},
+		{flag: "code-len", env: CodeLengthKEY, file: "code_length", usage: "Length of generated short code",
+			set: setInt(func(c *Config) *int { return &c.Generator.Length })},
+		{flag: "code-max-len", env: CodeMaxKEY, file: "code_max_length", usage: "Max length of generated short code",
+			set: setInt(func(c *Config) *int { return &c.Generator.MaxLength })},
+		{flag: "code-salt", env: CodeSaltKEY, file: "code_salt", usage: "Salt for sqids generator",
+			set: setString(func(c *Config) *string { return &c.Generator.Salt })},
+		{flag: "key-pool", env: KeyPoolSizeKEY, file: "key_pool_size", usage: "Size of pre-generated key pool, 0 disables it",
+			set: setInt(func(c *Config) *int { return &c.Generator.PoolSize })},
+		{flag: "key-pool-interval", env: KeyPoolIntervalKEY, file: "key_pool_interval", usage: "Interval of key pool refill check",
+			set: setDuration(func(c *Config) *time.Duration { return &c.Generator.PoolInterval })},
+
+		{flag: "s", env: EnableHTTPSKEY, file: "enable_https", usage: "Enable HTTPS", isBool: true,
+			set: setBool(func(c *Config) *bool { return &c.TLS.Enabled })},
+		{flag: "tls-cert", env: TLSCertKEY, file: "tls_cert_file", usage: "TLS certificate file, generated if missing",
+			set: setString(func(c *Config) *string { return &c.TLS.CertFile })},
+		{flag: "tls-key", env: TLSKeyKEY, file: "tls_key_file", usage: "TLS key file, generated if missing",
+			set: setString(func(c *Config) *string { return &c.TLS.KeyFile })},
+
+		{flag: "read-timeout", env: ReadTimeoutKEY, file: "read_timeout", usage: "HTTP server read timeout",
+			set: setDuration(func(c *Config) *time.Duration { return &c.HTTP.ReadTimeout })},
+		{flag: "write-timeout", env: WriteTimeoutKEY, file: "write_timeout", usage: "HTTP server write timeout",
+			set: setDuration(func(c *Config) *time.Duration { return &c.HTTP.WriteTimeout })},
+		{flag: "idle-timeout", env: IdleTimeoutKEY, file: "idle_timeout", usage: "HTTP server idle timeout",
+			set: setDuration(func(c *Config) *time.Duration { return &c.HTTP.IdleTimeout })},
+		{flag: "shutdown-timeout", env: ShutdownTimeoutKEY, file: "shutdown_timeout", usage: "Graceful shutdown timeout",
+			set: setDuration(func(c *Config) *time.Duration { return &c.HTTP.ShutdownTimeout })},
+
+		{flag: "gzip", env: GzipEnabledKEY, file: "gzip_enabled", usage: "Compress responses with gzip", isBool: true,
+			set: setBool(func(c *Config) *bool { return &c.Compression.Enabled })},
+		{flag: "gzip-level", env: GzipLevelKEY, file: "gzip_level", usage: "Gzip level 1-9",
+			set: setInt(func(c *Config) *int { return &c.Compression.Level })},
+
+		{flag: "max-body", env: MaxBodyBytesKEY, file: "max_body_bytes", usage: "Max request body size in bytes",
+			set: setInt64(func(c *Config) *int64 { return &c.Limits.MaxBodyBytes })},
+		{flag: "rate-limit", env: RateLimitKEY, file: "rate_limit", usage: "Requests per second from one client, 0 disables limit",
+			set: setFloat(func(c *Config) *float64 { return &c.Limits.RateLimit })},
+		{flag: "rate-burst", env: RateBurstKEY, file: "rate_burst", usage: "Burst of requests allowed over rate limit",
+			set: setInt(func(c *Config) *int { return &c.Limits.RateBurst })},
+
+		{flag: "blocked-domains", env: BlockedDomainsKEY, file: "blocked_domains", usage: "Domains not allowed to shorten, comma separated",
+			set: func(c *Config, v string) error { c.Blocklist.Domains = splitList(v); return nil }},
+		{flag: "blocked-clients", env: BlockedClientsKEY, file: "blocked_clients", usage: "Client IPs or CIDRs to reject, comma separated",
+			set: func(c *Config, v string) error { c.Blocklist.Clients = splitList(v); return nil }},
+
+		{flag: "admin-token", env: AdminTokenKEY, file: "admin_token", usage: "Token for admin endpoints, empty disables them",
+			set: setString(func(c *Config) *string { return &c.AdminToken })},
+		{flag: "trusted-proxies", env: TrustedProxiesKEY, file: "trusted_proxies", usage: "Proxy IPs or CIDRs allowed to set Forwarded headers, comma separated",
+			set: func(c *Config, v string) error { c.TrustedProxies = splitList(v); return nil }},
+		{flag: "t", env: TrustedSubnetKEY, file: "trusted_subnet", usage: "CIDR allowed to read /api/internal/stats, empty closes it",
+			set: setString(func(c *Config) *string { return &c.TrustedSubnet })},
+		{flag: "audit-file", env: AuditFileKEY, file: "audit_file", usage: "File for audit events, empty disables it",
+			set: setString(func(c *Config) *string { return &c.Audit.File })},
+		{flag: "audit-url", env: AuditURLKEY, file: "audit_url", usage: "URL receiving audit events by POST, empty disables it",
+			set: setString(func(c *Config) *string { return &c.Audit.URL })},
+		{flag: "trace-exporter", env: TraceExporterKEY, file: "trace_exporter", usage: "Trace exporter: none, otlp or stdout",
+			set: setString(func(c *Config) *string { return &c.Tracing.Exporter })},
+		{flag: "trace-file", env: TraceFileKEY, file: "trace_file", usage: "File for stdout trace exporter, empty writes to stdout",
+			set: setString(func(c *Config) *string { return &c.Tracing.File })},
+		{flag: "grpc-address", env: GRPCAddressKEY, file: "grpc_address", usage: `gRPC server address in form "host:port", empty disables it`,
+			set: setString(func(c *Config) *string { return &c.GRPCAddress })},
+		{flag: "domains", env: DomainsKEY, file: "domains", usage: "Extra short domains, comma separated",
+			set: func(c *Config, v string) error { c.Domains = splitList(v); return nil }},
+	}
+}
+
+func setString(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func setInt64(field func(c *Config) *int64) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func setFloat(field func(c *Config) *float64) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+)
+
+// ClientNets разбирает Clients: одиночный IP превращается в подсеть /32 или /128.
+func (b Blocklist) ClientNets() ([]*net.IPNet, error) {
+	return parseNets(b.Clients, "blocked client")
+}
+
+func parseNets(list []string, what string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, c := range list {
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid %s %q: not an IP or CIDR", what, c)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", what, c, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// Reload переносит из next настройки, которые можно применить на лету: логгер, сжатие,
+// лимиты и блок-листы. Остальное остаётся как в c, а имена изменившихся в next
+// полей возвращаются в frozen — они вступят в силу только после перезапуска.
+func (c *Config) Reload(next *Config) (merged Config, frozen []string) {
+	merged = *c
+	merged.Logger = next.Logger
+	merged.Compression = next.Compression
+	merged.Limits = next.Limits
+	merged.Blocklist = next.Blocklist
+
+	have, want := reflect.ValueOf(merged), reflect.ValueOf(*next)
+	for i := 0; i < have.NumField(); i++ {
+		if !reflect.DeepEqual(have.Field(i).Interface(), want.Field(i).Interface()) {
+			frozen = append(frozen, have.Type().Field(i).Name)
+		}
+	}
+	return merged, frozen
+}
@@ -0,0 +1,87 @@
+package filestorage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+type ClickPersister interface {
+	LoadClicks() ([]repo.Click, error)
+	AppendClicks([]repo.Click) error
+}
+
+// ClickLog хранит клики в файле по одному JSON на строку и только дописывает его.
+type ClickLog struct {
+	path string
+}
+
+func NewClickLog(path string) *ClickLog { return &ClickLog{path: path} }
+
+func (l *ClickLog) AppendClicks(clicks []repo.Click) error {
+	const msg = "filestorage.ClickLog.AppendClicks"
+
+	if dir := filepath.Dir(l.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("%s: mkdir: %w", msg, err)
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("%s: open: %w", msg, err)
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, c := range clicks {
+		if err := enc.Encode(c); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("%s: encode: %w", msg, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%s: flush: %w", msg, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%s: close: %w", msg, err)
+	}
+	return nil
+}
+
+func (l *ClickLog) LoadClicks() ([]repo.Click, error) {
+	const msg = "filestorage.ClickLog.LoadClicks"
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: open: %w", msg, err)
+	}
+	defer f.Close()
+
+	var clicks []repo.Click
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var c repo.Click
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			// недописанная последняя строка после падения — пропускаем
+			continue
+		}
+		clicks = append(clicks, c)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: scan: %w", msg, err)
+	}
+	return clicks, nil
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"time"
 
 	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
 )
@@ -17,13 +18,28 @@ type Persister interface {
 }
 
 type JSONStore struct {
-	path string
+	path   string
+	onSave func(d time.Duration, size int64)
 }
 
-func NewJSONStore(path string) *JSONStore { return &JSONStore{path: path} }
+type JSONStoreOption func(*JSONStore)
+
+// WithSaveObserver после каждого удачного Save сообщает его длительность и размер файла в байтах.
+func WithSaveObserver(fn func(d time.Duration, size int64)) JSONStoreOption {
+	return func(s *JSONStore) { s.onSave = fn }
+}
+
+func NewJSONStore(path string, opts ...JSONStoreOption) *JSONStore {
+	s := &JSONStore{path: path}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
 
 func (s *JSONStore) Save(records []repo.Record) error {
 	const msg = "filestorage.JSONStore.Save"
+	start := time.Now()
 
 	sort.Slice(records, func(i, j int) bool {
 		return records[i].ShortURL < records[j].ShortURL
@@ -49,6 +65,20 @@ func (s *JSONStore) Save(records []repo.Record) error {
 		return fmt.Errorf("%s: encode: %w", msg, err)
 	}
 
+	// без fsync после падения на месте файла может оказаться пустой tmp
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%s: sync: %w", msg, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%s: stat: %w", msg, err)
+	}
+
 	if err := f.Close(); err != nil {
 		_ = os.Remove(tmp)
 		return fmt.Errorf("%s: close: %w", msg, err)
@@ -59,6 +89,9 @@ func (s *JSONStore) Save(records []repo.Record) error {
 		return fmt.Errorf("%s: rename: %w", msg, err)
 	}
 
+	if s.onSave != nil {
+		s.onSave(time.Since(start), info.Size())
+	}
 	return nil
 }
 
@@ -0,0 +1,597 @@
+package filestorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+// SyncPolicy когда журнал сбрасывает дописанное на диск.
+type SyncPolicy string
+
+const (
+	// SyncAlways fsync после каждого Append: ничего не теряется, но каждая запись ждёт диск.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsync в фоне раз в интервал: при падении машины теряется не больше интервала.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNone сброс на усмотрение ОС: падение процесса не страшно, падение машины — да.
+	SyncNone SyncPolicy = "none"
+)
+
+// Op вид строки журнала.
+type Op string
+
+const (
+	// OpPut новая запись.
+	OpPut Op = "put"
+	// OpDelete пометка удалённой записи с domain, user_id и short_url.
+	OpDelete Op = "delete"
+	// OpPurge чистка записей, истёкших к моменту At.
+	OpPurge Op = "purge"
+)
+
+// Entry строка журнала: одно изменение хранилища.
+type Entry struct {
+	Op     Op          `json:"op"`
+	Record repo.Record `json:"record"`
+	At     *time.Time  `json:"at,omitempty"`
+}
+
+func PutEntry(rec repo.Record) Entry {
+	return Entry{Op: OpPut, Record: rec}
+}
+
+func DeleteEntry(req repo.DeleteRequest) Entry {
+	return Entry{Op: OpDelete, Record: repo.Record{Domain: req.Domain, UserID: req.UserID, ShortURL: req.ShortURL}}
+}
+
+func PurgeEntry(now time.Time) Entry {
+	return Entry{Op: OpPurge, At: &now}
+}
+
+// Appender Persister, который дописывает изменения, а не перезаписывает весь снимок.
+type Appender interface {
+	Append(entries ...Entry) error
+}
+
+// LogStore хранит записи снимком в path (тот же формат, что у JSONStore) и журналом
+// изменений в сегментах path.000001.log, path.000002.log, ... по одному JSON на строку.
+// Run в фоне делает fsync по политике и сжимает сегменты в новый снимок.
+type LogStore struct {
+	path         string
+	snap         *JSONStore
+	snapOpts     []JSONStoreOption
+	sync         SyncPolicy
+	syncEvery    time.Duration
+	compactEvery time.Duration
+	compactAfter int
+
+	mu      sync.Mutex
+	f       *os.File
+	seg     int
+	size    int64
+	pending int  // строк в сегментах поверх снимка
+	dirty   bool // есть строки без fsync
+
+	// compactMu не даёт сжатию и Save писать снимок одновременно
+	compactMu sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type LogStoreOption func(*LogStore)
+
+// ParseSyncPolicy разбирает политику из конфига; every нужен только для SyncInterval.
+func ParseSyncPolicy(s string, every time.Duration) (SyncPolicy, error) {
+	switch p := SyncPolicy(s); p {
+	case SyncAlways, SyncNone:
+		return p, nil
+	case SyncInterval:
+		if every <= 0 {
+			return "", fmt.Errorf("invalid file storage sync interval %s: must be positive", every)
+		}
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid file storage sync %q: must be always, interval or none", s)
+	}
+}
+
+// WithSync задаёт политику fsync; every нужен только для SyncInterval.
+func WithSync(p SyncPolicy, every time.Duration) LogStoreOption {
+	return func(s *LogStore) {
+		s.sync = p
+		s.syncEvery = every
+	}
+}
+
+// WithCompaction раз в every сжимает журнал в снимок, если в нём набралось хотя бы after строк.
+func WithCompaction(every time.Duration, after int) LogStoreOption {
+	return func(s *LogStore) {
+		s.compactEvery = every
+		s.compactAfter = after
+	}
+}
+
+// WithCompactObserver после каждого записанного снимка сообщает его длительность и размер.
+func WithCompactObserver(fn func(d time.Duration, size int64)) LogStoreOption {
+	return func(s *LogStore) { s.snapOpts = append(s.snapOpts, WithSaveObserver(fn)) }
+}
+
+func NewLogStore(path string, opts ...LogStoreOption) *LogStore {
+	s := &LogStore{
+		path:         path,
+		sync:         SyncInterval,
+		syncEvery:    time.Second,
+		compactEvery: 5 * time.Minute,
+		compactAfter: 10000,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.snap = NewJSONStore(path, s.snapOpts...)
+	return s
+}
+
+// Load читает снимок и накатывает на него сегменты. Недописанный хвост последнего
+// сегмента после падения обрезается, дальше журнал дописывается в этот же сегмент.
+func (s *LogStore) Load() ([]repo.Record, error) {
+	const msg = "filestorage.LogStore.Load"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, n, err := s.replay(-1)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msg, err)
+	}
+	if err := s.open(); err != nil {
+		return nil, fmt.Errorf("%s: %w", msg, err)
+	}
+	s.pending = n
+	return st.records(), nil
+}
+
+// Append дописывает entries одним write. Если записать не вышло, уже записанная
+// часть отрезается, чтобы не оставлять в середине журнала оборванную строку.
+func (s *LogStore) Append(entries ...Entry) error {
+	const msg = "filestorage.LogStore.Append"
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("%s: encode: %w", msg, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		if err := s.open(); err != nil {
+			return fmt.Errorf("%s: %w", msg, err)
+		}
+	}
+	n, err := s.f.Write(buf.Bytes())
+	if err != nil {
+		if n > 0 {
+			_ = s.f.Truncate(s.size)
+		}
+		return fmt.Errorf("%s: write: %w", msg, err)
+	}
+	s.size += int64(n)
+	s.pending += len(entries)
+
+	if s.sync == SyncAlways {
+		if err := s.f.Sync(); err != nil {
+			return fmt.Errorf("%s: sync: %w", msg, err)
+		}
+		return nil
+	}
+	s.dirty = true
+	return nil
+}
+
+// Save заменяет всё содержимое снимком records и удаляет сегменты. Дописанное
+// после того, как records были сняты, пропадёт, поэтому вызывать, когда писателей нет.
+func (s *LogStore) Save(records []repo.Record) error {
+	const msg = "filestorage.LogStore.Save"
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upto := s.seg
+	if err := s.rotate(); err != nil {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	if err := s.snap.Save(records); err != nil {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	s.pending = 0
+	if err := s.removeSegments(upto); err != nil {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	return nil
+}
+
+// Sync сбрасывает на диск всё дописанное.
+func (s *LogStore) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.syncLocked()
+}
+
+func (s *LogStore) Run() {
+	defer close(s.done)
+
+	var syncC <-chan time.Time
+	if s.sync == SyncInterval && s.syncEvery > 0 {
+		t := time.NewTicker(s.syncEvery)
+		defer t.Stop()
+		syncC = t.C
+	}
+	var compactC <-chan time.Time
+	if s.compactEvery > 0 {
+		t := time.NewTicker(s.compactEvery)
+		defer t.Stop()
+		compactC = t.C
+	}
+
+	for {
+		select {
+		case <-syncC:
+			if err := s.Sync(); err != nil {
+				logger.Log.Errorf("storage log sync: %s", err)
+			}
+		case <-compactC:
+			if err := s.Compact(); err != nil {
+				logger.Log.Errorf("storage log compact: %s", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close останавливает Run, сбрасывает журнал на диск и закрывает сегмент.
+func (s *LogStore) Close(ctx context.Context) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.syncLocked()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f = nil
+	return err
+}
+
+// Compact переключает запись на новый сегмент, а старые сворачивает в снимок и удаляет.
+// Append при этом не ждёт: под мьютексом только переключение сегмента.
+func (s *LogStore) Compact() error {
+	const msg = "filestorage.LogStore.Compact"
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	s.mu.Lock()
+	if s.f == nil || s.pending < s.compactAfter || s.pending == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	upto := s.seg
+	compacted := s.pending
+	if err := s.rotate(); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	s.pending = 0
+	s.mu.Unlock()
+
+	// при ошибке сегменты остаются на месте и попадут в следующее сжатие
+	restore := func() {
+		s.mu.Lock()
+		s.pending += compacted
+		s.mu.Unlock()
+	}
+	st, _, err := s.replay(upto)
+	if err != nil {
+		restore()
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	if err := s.snap.Save(st.records()); err != nil {
+		restore()
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	// упав здесь, при старте накатим эти сегменты на уже учитывающий их снимок:
+	// put, delete и purge дают тот же результат при повторе
+	if err := s.removeSegments(upto); err != nil {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	return nil
+}
+
+// replay читает снимок и сегменты с номером не больше upto (-1 — все).
+// Возвращает состояние и число накатанных строк.
+func (s *LogStore) replay(upto int) (*logState, int, error) {
+	records, err := s.snap.Load()
+	if err != nil {
+		return nil, 0, fmt.Errorf("load snapshot: %w", err)
+	}
+	st := newLogState(records)
+
+	segs, err := s.segments()
+	if err != nil {
+		return nil, 0, err
+	}
+	total := 0
+	for _, seg := range segs {
+		if upto >= 0 && seg > upto {
+			break
+		}
+		n, err := replaySegment(s.segmentPath(seg), st)
+		if err != nil {
+			return nil, 0, err
+		}
+		total += n
+	}
+	return st, total, nil
+}
+
+// replaySegment накатывает строки сегмента на st. Битые строки в конце считаются
+// оборванной записью и отрезаются; битая строка, за которой есть целые, — это порча файла.
+func replaySegment(path string, st *logState) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("read segment: %w", err)
+	}
+
+	var (
+		entries []Entry
+		good    int64 // конец последней целой строки
+		badAt   = -1  // начало первой битой строки после good
+	)
+	var off int64
+	for len(data) > 0 {
+		line := data
+		next := len(data)
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i]
+			next = i + 1
+		} else {
+			// без перевода строки запись точно не дописана
+			if badAt < 0 {
+				badAt = int(off)
+			}
+			break
+		}
+
+		var e Entry
+		if len(bytes.TrimSpace(line)) > 0 {
+			if err := json.Unmarshal(line, &e); err != nil {
+				if badAt < 0 {
+					badAt = int(off)
+				}
+			} else {
+				if badAt >= 0 {
+					return 0, fmt.Errorf("segment %s: corrupted line at offset %d", path, badAt)
+				}
+				entries = append(entries, e)
+			}
+		}
+		off += int64(next)
+		if badAt < 0 {
+			good = off
+		}
+		data = data[next:]
+	}
+
+	if badAt >= 0 {
+		if err := os.Truncate(path, good); err != nil {
+			return 0, fmt.Errorf("truncate torn tail: %w", err)
+		}
+		logger.Log.Warnf("storage log %s: dropped torn tail at offset %d", path, good)
+	}
+	for _, e := range entries {
+		st.apply(e)
+	}
+	return len(entries), nil
+}
+
+// open открывает последний сегмент на дописывание или создаёт первый. Вызывается под mu.
+func (s *LogStore) open() error {
+	if s.f != nil {
+		return nil
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("mkdir: %w", err)
+		}
+	}
+	segs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	seg := 1
+	if len(segs) > 0 {
+		seg = segs[len(segs)-1]
+	}
+	return s.openSegment(seg)
+}
+
+func (s *LogStore) openSegment(seg int) error {
+	f, err := os.OpenFile(s.segmentPath(seg), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat segment: %w", err)
+	}
+	s.f = f
+	s.seg = seg
+	s.size = info.Size()
+	s.dirty = false
+	return nil
+}
+
+// rotate закрывает текущий сегмент и начинает следующий. Вызывается под mu.
+func (s *LogStore) rotate() error {
+	if err := s.open(); err != nil {
+		return err
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("sync segment: %w", err)
+	}
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("close segment: %w", err)
+	}
+	s.f = nil
+	return s.openSegment(s.seg + 1)
+}
+
+func (s *LogStore) syncLocked() error {
+	if s.f == nil || !s.dirty {
+		return nil
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("filestorage.LogStore.Sync: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+func (s *LogStore) removeSegments(upto int) error {
+	segs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	for _, seg := range segs {
+		if seg > upto {
+			break
+		}
+		if err := os.Remove(s.segmentPath(seg)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove segment: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *LogStore) segmentPath(seg int) string {
+	return fmt.Sprintf("%s.%06d.log", s.path, seg)
+}
+
+// segments номера сегментов на диске по возрастанию.
+func (s *LogStore) segments() ([]int, error) {
+	matches, err := filepath.Glob(s.path + ".*.log")
+	if err != nil {
+		return nil, fmt.Errorf("list segments: %w", err)
+	}
+	segs := make([]int, 0, len(matches))
+	for _, m := range matches {
+		num := strings.TrimSuffix(strings.TrimPrefix(m, s.path+"."), ".log")
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, n)
+	}
+	sort.Ints(segs)
+	return segs, nil
+}
+
+type logKey struct {
+	domain string
+	short  repo.ShortURL
+}
+
+// logState записи, собранные из снимка и журнала.
+type logState struct {
+	recs   map[logKey]repo.Record
+	nextID int
+}
+
+func newLogState(records []repo.Record) *logState {
+	st := &logState{
+		recs: make(map[logKey]repo.Record, len(records)),
+	}
+	for _, rec := range records {
+		st.recs[logKey{rec.Domain, rec.ShortURL}] = rec
+		if rec.ID >= st.nextID {
+			st.nextID = rec.ID + 1
+		}
+	}
+	return st
+}
+
+// apply id новых записей раздаются по порядку журнала, как их раздаёт хранилище в памяти;
+// повторный put той же записи сохраняет её id.
+func (st *logState) apply(e Entry) {
+	switch e.Op {
+	case OpPut:
+		key := logKey{e.Record.Domain, e.Record.ShortURL}
+		rec := e.Record
+		if old, ok := st.recs[key]; ok {
+			rec.ID = old.ID
+		} else {
+			rec.ID = st.nextID
+			st.nextID++
+		}
+		st.recs[key] = rec
+	case OpDelete:
+		key := logKey{e.Record.Domain, e.Record.ShortURL}
+		if rec, ok := st.recs[key]; ok && rec.UserID == e.Record.UserID {
+			rec.Deleted = true
+			st.recs[key] = rec
+		}
+	case OpPurge:
+		if e.At == nil {
+			return
+		}
+		for key, rec := range st.recs {
+			if rec.Expired(*e.At) {
+				delete(st.recs, key)
+			}
+		}
+	}
+}
+
+func (st *logState) records() []repo.Record {
+	out := make([]repo.Record, 0, len(st.recs))
+	for _, rec := range st.recs {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
@@ -0,0 +1,166 @@
+package filestorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogStoreReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	past := time.Now().Add(-time.Hour)
+
+	s := NewLogStore(path, WithSync(SyncAlways, 0))
+	records, err := s.Load()
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	require.NoError(t, s.Append(
+		PutEntry(repo.Record{URL: "https://a.ru", ShortURL: "a", UserID: "u1"}),
+		PutEntry(repo.Record{URL: "https://b.ru", ShortURL: "b", UserID: "u1"}),
+		PutEntry(repo.Record{URL: "https://b.ru", ShortURL: "b", UserID: "u2", Domain: "x.example"}),
+		PutEntry(repo.Record{URL: "https://old.ru", ShortURL: "old", ExpiresAt: &past}),
+		PutEntry(repo.Record{URL: "https://c.ru", ShortURL: "b", UserID: "u1", Domain: "y.example"}),
+	))
+	require.NoError(t, s.Append(
+		// удаление касается только своего домена
+		DeleteEntry(repo.DeleteRequest{UserID: "u1", ShortURL: "b"}),
+		// чужая ссылка не удаляется
+		DeleteEntry(repo.DeleteRequest{UserID: "u2", ShortURL: "a"}),
+		PurgeEntry(time.Now()),
+	))
+
+	records, err = NewLogStore(path).Load()
+	require.NoError(t, err)
+	require.Equal(t, []repo.Record{
+		{ID: 0, URL: "https://a.ru", ShortURL: "a", UserID: "u1"},
+		{ID: 1, URL: "https://b.ru", ShortURL: "b", UserID: "u1", Deleted: true},
+		{ID: 2, URL: "https://b.ru", ShortURL: "b", UserID: "u2", Domain: "x.example"},
+		{ID: 4, URL: "https://c.ru", ShortURL: "b", UserID: "u1", Domain: "y.example"},
+	}, records)
+}
+
+func TestLogStoreTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	s := NewLogStore(path)
+	_, err := s.Load()
+	require.NoError(t, err)
+	require.NoError(t, s.Append(PutEntry(repo.Record{URL: "https://a.ru", ShortURL: "a"})))
+
+	seg := s.segmentPath(1)
+	intact, err := os.ReadFile(seg)
+	require.NoError(t, err)
+
+	// процесс упал посреди записи второй строки
+	f, err := os.OpenFile(seg, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"put","record":{"url":"https://b.r`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s = NewLogStore(path)
+	records, err := s.Load()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	data, err := os.ReadFile(seg)
+	require.NoError(t, err)
+	require.Equal(t, intact, data)
+
+	// после обрезки журнал дописывается с целой строки
+	require.NoError(t, s.Append(PutEntry(repo.Record{URL: "https://c.ru", ShortURL: "c"})))
+	records, err = NewLogStore(path).Load()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+}
+
+func TestLogStoreCorruptedMiddle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	s := NewLogStore(path)
+	seg := s.segmentPath(1)
+	require.NoError(t, os.WriteFile(seg, []byte(
+		`{"op":"put","record":{"url":"https://a.ru","short_url":"a"}}`+"\n"+
+			"garbage\n"+
+			`{"op":"put","record":{"url":"https://b.ru","short_url":"b"}}`+"\n",
+	), 0o644))
+
+	_, err := s.Load()
+	require.ErrorContains(t, err, "corrupted line")
+}
+
+func TestLogStoreCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	s := NewLogStore(path, WithCompaction(time.Hour, 2))
+	_, err := s.Load()
+	require.NoError(t, err)
+
+	require.NoError(t, s.Append(PutEntry(repo.Record{URL: "https://a.ru", ShortURL: "a", UserID: "u1"})))
+	// меньше порога — сжимать рано
+	require.NoError(t, s.Compact())
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, s.Append(PutEntry(repo.Record{URL: "https://b.ru", ShortURL: "b", UserID: "u1"})))
+	require.NoError(t, s.Compact())
+
+	segs, err := s.segments()
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, segs)
+
+	snapshot, err := NewJSONStore(path).Load()
+	require.NoError(t, err)
+	require.Len(t, snapshot, 2)
+
+	// новые строки идут в новый сегмент поверх снимка
+	require.NoError(t, s.Append(DeleteEntry(repo.DeleteRequest{UserID: "u1", ShortURL: "a"})))
+	go s.Run()
+	require.NoError(t, s.Close(context.Background()))
+
+	records, err := NewLogStore(path).Load()
+	require.NoError(t, err)
+	require.Equal(t, []repo.Record{
+		{ID: 0, URL: "https://a.ru", ShortURL: "a", UserID: "u1", Deleted: true},
+		{ID: 1, URL: "https://b.ru", ShortURL: "b", UserID: "u1"},
+	}, records)
+}
+
+func TestLogStoreLoadsLegacySnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, NewJSONStore(path).Save([]repo.Record{
+		{ID: 7, URL: "https://a.ru", ShortURL: "a"},
+	}))
+
+	s := NewLogStore(path)
+	_, err := s.Load()
+	require.NoError(t, err)
+	require.NoError(t, s.Append(PutEntry(repo.Record{URL: "https://b.ru", ShortURL: "b"})))
+
+	records, err := NewLogStore(path).Load()
+	require.NoError(t, err)
+	require.Equal(t, []repo.Record{
+		{ID: 7, URL: "https://a.ru", ShortURL: "a"},
+		{ID: 8, URL: "https://b.ru", ShortURL: "b"},
+	}, records)
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	p, err := ParseSyncPolicy("always", 0)
+	require.NoError(t, err)
+	require.Equal(t, SyncAlways, p)
+
+	p, err = ParseSyncPolicy("interval", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, SyncInterval, p)
+
+	_, err = ParseSyncPolicy("interval", 0)
+	require.Error(t, err)
+	_, err = ParseSyncPolicy("sometimes", time.Second)
+	require.Error(t, err)
+}
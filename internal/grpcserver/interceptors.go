@@ -0,0 +1,86 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	l "github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthMetadataKey метаданные с подписанным id пользователя, аналог куки auth.
+const AuthMetadataKey = "auth"
+
+// RequestIDMetadataKey аналог заголовка X-Request-ID.
+const RequestIDMetadataKey = "x-request-id"
+
+// WithRequestID берёт id вызова из метаданных или выдаёт новый, возвращает его
+// в заголовке ответа и кладёт в логгер контекста, как WithRequestID у HTTP.
+func WithRequestID(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id := firstMD(ctx, RequestIDMetadataKey)
+	if id == "" || len(id) > 128 {
+		id = l.NewRequestID()
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, id)); err != nil {
+		l.FromContext(ctx).Errorf("request id header: %s", err)
+	}
+	return handler(l.WithRequestID(ctx, id), req)
+}
+
+// WithRecovery превращает панику в обработчике в codes.Internal вместо падения процесса.
+func WithRecovery(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			l.FromContext(ctx).WithFields(logrus.Fields{
+				"method": info.FullMethod,
+				"panic":  fmt.Sprint(rec),
+				"stack":  string(debug.Stack()),
+			}).Error("rpc panic recovered")
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// WithLogging пишет в лог метод, код ответа и длительность, как WithLogging у HTTP.
+func WithLogging(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	l.FromContext(ctx).WithFields(logrus.Fields{
+		"method":   info.FullMethod,
+		"code":     status.Code(err).String(),
+		"duration": time.Since(start),
+	}).Info("rpc handled")
+	return resp, err
+}
+
+// WithAuth проверяет токен из метаданных auth. Если его нет или подпись не сходится,
+// выдаётся новый id, а токен уходит клиенту в заголовке ответа.
+func WithAuth(signer *auth.Signer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get(AuthMetadataKey); len(v) > 0 {
+			if userID, err := signer.Verify(v[0]); err == nil {
+				return handler(auth.WithUser(ctx, auth.User{ID: userID}), req)
+			}
+		}
+
+		userID, err := auth.NewUserID()
+		if err != nil {
+			l.FromContext(ctx).Errorf("auth error %s", err)
+			return nil, status.Error(codes.Internal, "can't issue user id")
+		}
+		if err := grpc.SetHeader(ctx, metadata.Pairs(AuthMetadataKey, signer.Sign(userID))); err != nil {
+			l.FromContext(ctx).Errorf("auth error %s", err)
+		}
+		return handler(auth.WithUser(ctx, auth.User{ID: userID, IsNew: true}), req)
+	}
+}
@@ -0,0 +1,239 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/audit"
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/model"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+	pb "github.com/IvanOplesnin/url-shortener/pkg/api/shortener/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Server реализует pb.ShortenerServer поверх того же shortener.Service, что и HTTP-хендлеры.
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	svc   *shortener.Service
+	p     Pinger
+	audit audit.Observer
+}
+
+// New сервер поверх svc; obs (может быть nil) получает события перехода из Resolve.
+func New(svc *shortener.Service, p Pinger, obs audit.Observer) *Server {
+	return &Server{svc: svc, p: p, audit: obs}
+}
+
+// NewGRPCServer собирает grpc.Server с логированием и авторизацией, как у chi-роутера.
+func NewGRPCServer(svc *shortener.Service, p Pinger, signer *auth.Signer, obs audit.Observer, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(WithRequestID, WithLogging, WithRecovery, WithAuth(signer)))
+	srv := grpc.NewServer(opts...)
+	pb.RegisterShortenerServer(srv, New(svc, p, obs))
+	return srv
+}
+
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	ttl, err := shortener.TTLFromSeconds(req.GetTtlSeconds())
+	if err != nil {
+		return nil, shortenStatus(err)
+	}
+	params := shortener.ShortenParams{
+		TTL:         ttl,
+		CustomAlias: repository.ShortURL(req.GetCustomAlias()),
+		Domain:      req.GetDomain(),
+	}
+	if req.GetExpiresAt() != nil {
+		t := req.GetExpiresAt().AsTime()
+		params.ExpiresAt = &t
+	}
+	res, err := s.svc.Shorten(ctx, repository.URL(req.GetUrl()), params)
+	if err != nil {
+		return nil, shortenStatus(err)
+	}
+	return &pb.ShortenResponse{Result: res.Link, Existed: res.Exists}, nil
+}
+
+func (s *Server) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	batch := make([]model.RequestBatchBody, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		b := model.RequestBatchBody{
+			CorrelationID: item.GetCorrelationId(),
+			OriginalURL:   repository.URL(item.GetOriginalUrl()),
+			CustomAlias:   repository.ShortURL(item.GetCustomAlias()),
+			TTLSeconds:    item.GetTtlSeconds(),
+		}
+		if item.GetExpiresAt() != nil {
+			t := item.GetExpiresAt().AsTime()
+			b.ExpiresAt = &t
+		}
+		batch = append(batch, b)
+	}
+	res, existed, err := s.svc.Batch(ctx, req.GetDomain(), batch)
+	if err != nil {
+		return nil, shortenStatus(err)
+	}
+	out := &pb.ShortenBatchResponse{Items: make([]*pb.BatchResult, 0, len(res)), Existed: existed}
+	for _, r := range res {
+		out.Items = append(out.Items, &pb.BatchResult{CorrelationId: r.CorrelationID, ShortUrl: r.ShortURL})
+	}
+	return out, nil
+}
+
+// Resolve как переход по ссылке: тоже учитывается в статистике.
+func (s *Server) Resolve(ctx context.Context, req *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	short := repository.ShortURL(req.GetShortId())
+	url, err := s.svc.ResolveHost(ctx, req.GetDomain(), short)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	s.svc.RecordClick(req.GetDomain(), repository.Click{
+		ShortURL:  short,
+		At:        time.Now().UTC(),
+		UserAgent: firstMD(ctx, "user-agent"),
+		IP:        peerIP(ctx),
+	})
+	if s.audit != nil {
+		s.audit.Notify(audit.NewEvent(audit.ActionFollow, auth.UserID(ctx), string(url)))
+	}
+	return &pb.ResolveResponse{OriginalUrl: string(url)}, nil
+}
+
+func (s *Server) ListUserURLs(ctx context.Context, _ *pb.ListUserURLsRequest) (*pb.ListUserURLsResponse, error) {
+	user, err := knownUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := s.svc.UserURLs(ctx, user.ID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	out := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(urls))}
+	for _, u := range urls {
+		out.Urls = append(out.Urls, &pb.UserURL{ShortUrl: u.ShortURL, OriginalUrl: string(u.OriginalURL)})
+	}
+	return out, nil
+}
+
+func (s *Server) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+	user, err := knownUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shorts := make([]repository.ShortURL, 0, len(req.GetShortIds()))
+	for _, id := range req.GetShortIds() {
+		shorts = append(shorts, repository.ShortURL(id))
+	}
+	if err := s.svc.DeleteUserURLs(ctx, user.ID, req.GetDomain(), shorts); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DeleteUserURLsResponse{}, nil
+}
+
+func (s *Server) Stats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
+	stats, err := s.svc.Stats(ctx, req.GetDomain(), repository.ShortURL(req.GetShortId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	out := &pb.StatsResponse{
+		ShortUrl:       stats.ShortURL,
+		TotalClicks:    stats.TotalClicks,
+		UniqueVisitors: stats.UniqueVisitors,
+		Daily:          make([]*pb.DailyClicks, 0, len(stats.Daily)),
+	}
+	for _, d := range stats.Daily {
+		out.Daily = append(out.Daily, &pb.DailyClicks{Date: d.Date, Clicks: d.Clicks})
+	}
+	return out, nil
+}
+
+func (s *Server) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if s.p == nil {
+		return nil, status.Error(codes.Unavailable, "database is not configured")
+	}
+	if err := s.p.Ping(ctx); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &pb.PingResponse{}, nil
+}
+
+// knownUser как в HTTP: новый пользователь ещё ничего не создавал.
+func knownUser(ctx context.Context) (auth.User, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok || user.IsNew {
+		return auth.User{}, status.Error(codes.Unauthenticated, "unknown user")
+	}
+	return user, nil
+}
+
+// shortenStatus как writeShortenError: всё, что не конфликт, — ошибка запроса.
+func shortenStatus(err error) error {
+	if st := knownStatus(err); st != nil {
+		return st
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+func toStatus(err error) error {
+	if st := knownStatus(err); st != nil {
+		return st
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func knownStatus(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFoundShortURL),
+		errors.Is(err, repository.ErrDeleted),
+		errors.Is(err, repository.ErrExpired):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrAlreadyExists),
+		errors.Is(err, repository.ErrShortURLAlreadyExists),
+		errors.Is(err, shortener.ErrAliasMismatch):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, usvc.ErrInvalidAlias),
+		errors.Is(err, usvc.ErrReservedAlias),
+		errors.Is(err, shortener.ErrBlockedURL),
+		errors.Is(err, shortener.ErrUnknownDomain),
+		errors.Is(err, shortener.ErrInvalidExpiry):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, shortener.ErrDeleterClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return nil
+}
+
+func firstMD(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
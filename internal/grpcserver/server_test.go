@@ -0,0 +1,113 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	pb "github.com/IvanOplesnin/url-shortener/pkg/api/shortener/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T) pb.ShortenerClient {
+	t.Helper()
+	repo := inmemory.NewRepo()
+	svc := shortener.New(repo, "http://localhost:8080/")
+	srv := NewGRPCServer(svc, nil, auth.NewSigner([]byte("test")), nil)
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewShortenerClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	// первый вызов выдаёт токен в заголовке ответа
+	var header metadata.MD
+	first, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://practicum.yandex.ru"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.False(t, first.GetExisted())
+	token := header.Get(AuthMetadataKey)
+	require.Len(t, token, 1)
+	require.Len(t, header.Get(RequestIDMetadataKey), 1)
+
+	header = nil
+	withID := metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, "rpc-1")
+	_, err = client.Ping(withID, &pb.PingRequest{}, grpc.Header(&header))
+	require.Error(t, err)
+	require.Equal(t, []string{"rpc-1"}, header.Get(RequestIDMetadataKey))
+
+	_, err = client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	authed := metadata.AppendToOutgoingContext(ctx, AuthMetadataKey, token[0])
+
+	second, err := client.Shorten(authed, &pb.ShortenRequest{Url: "https://practicum.yandex.ru"})
+	require.NoError(t, err)
+	require.True(t, second.GetExisted())
+	require.Equal(t, first.GetResult(), second.GetResult())
+
+	_, err = client.Shorten(authed, &pb.ShortenRequest{Url: "https://ya.ru", CustomAlias: "promo"})
+	require.NoError(t, err)
+	_, err = client.Shorten(authed, &pb.ShortenRequest{Url: "https://go.dev", CustomAlias: "promo"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.Shorten(authed, &pb.ShortenRequest{Url: "https://go.dev", Domain: "unknown.example"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.Shorten(authed, &pb.ShortenRequest{Url: "https://go.dev", TtlSeconds: 18446744074})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resolved, err := client.Resolve(ctx, &pb.ResolveRequest{ShortId: "promo"})
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", resolved.GetOriginalUrl())
+
+	_, err = client.Resolve(ctx, &pb.ResolveRequest{ShortId: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	batch, err := client.ShortenBatch(authed, &pb.ShortenBatchRequest{Items: []*pb.BatchItem{
+		{CorrelationId: "1", OriginalUrl: "https://batch.ru/1"},
+		{CorrelationId: "2", OriginalUrl: "https://batch.ru/2"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, batch.GetItems(), 2)
+	require.Equal(t, "1", batch.GetItems()[0].GetCorrelationId())
+
+	// URL уже сокращён под promo: другой alias в батче — конфликт, тот же — старая ссылка
+	_, err = client.ShortenBatch(authed, &pb.ShortenBatchRequest{Items: []*pb.BatchItem{
+		{CorrelationId: "1", OriginalUrl: "https://ya.ru", CustomAlias: "summer-sale"},
+	}})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	same, err := client.ShortenBatch(authed, &pb.ShortenBatchRequest{Items: []*pb.BatchItem{
+		{CorrelationId: "1", OriginalUrl: "https://ya.ru", CustomAlias: "promo"},
+	}})
+	require.NoError(t, err)
+	require.True(t, same.GetExisted())
+
+	list, err := client.ListUserURLs(authed, &pb.ListUserURLsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetUrls(), 4)
+
+	_, err = client.Ping(ctx, &pb.PingRequest{})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
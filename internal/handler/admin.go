@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/model"
+)
+
+const adminTokenKey = "X-Admin-Token"
+
+// Reloader перечитывает конфиг и применяет то, что можно поменять на лету;
+// ignored — изменённые настройки, которые вступят в силу только после перезапуска.
+type Reloader func(ctx context.Context) (ignored []string, err error)
+
+// WithAdminToken пускает дальше только запросы с верным X-Admin-Token.
+func WithAdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := r.Header.Get(adminTokenKey)
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ReloadHandler(reload Reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ignored, err := reload(r.Context())
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("reload error %s", err)
+			writeJSONError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if ignored == nil {
+			ignored = []string{}
+		}
+		b, err := json.Marshal(model.ResponseReload{Ignored: ignored})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(contentTypeKey, applicationJSONValue)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(b)
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReservedAliasesCoverRoutes(t *testing.T) {
+	mux := InitHandlers(shortener.New(inmemory.NewRepo(), "http://localhost:8080"), "http://localhost:8080", nil, auth.NewSigner([]byte("test")))
+
+	reserved := make(map[string]struct{})
+	for _, r := range ReservedAliases() {
+		reserved[r] = struct{}{}
+	}
+
+	err := chi.Walk(mux, func(_ string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		first := strings.Split(strings.TrimPrefix(route, "/"), "/")[0]
+		if first == "" || strings.HasPrefix(first, "{") {
+			return nil
+		}
+		_, ok := reserved[first]
+		require.Truef(t, ok, "route %s is not covered by ReservedAliases", route)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestCustomAlias(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	repo := inmemory.NewRepo()
+	repo.Seed([]repository.Record{
+		{ID: 0, URL: "https://github.com", ShortURL: "taken"},
+	})
+	policy, err := usvc.NewAliasPolicy(3, 16, usvc.DefaultAliasPattern, ReservedAliases())
+	require.NoError(t, err)
+
+	svc := shortener.New(repo, baseURL, shortener.WithAliasPolicy(policy))
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(contentTypeKey, applicationJSONValue)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"reserved word", "/api/shorten", `{"url":"https://ya.ru","custom_alias":"API"}`, http.StatusBadRequest},
+		{"too short", "/api/shorten", `{"url":"https://ya.ru","custom_alias":"ab"}`, http.StatusBadRequest},
+		{"too long", "/api/shorten", `{"url":"https://ya.ru","custom_alias":"abcdefghijklmnopq"}`, http.StatusBadRequest},
+		{"taken", "/api/shorten", `{"url":"https://ya.ru","custom_alias":"taken"}`, http.StatusConflict},
+		{"url shortened under another alias", "/api/shorten", `{"url":"https://github.com","custom_alias":"other"}`, http.StatusConflict},
+		{"url shortened under same alias", "/api/shorten", `{"url":"https://github.com","custom_alias":"taken"}`, http.StatusConflict},
+		{"ok", "/api/shorten", `{"url":"https://ya.ru","custom_alias":"my_link"}`, http.StatusCreated},
+		{"batch taken", "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://google.com","custom_alias":"taken"}]`, http.StatusConflict},
+		{"batch url shortened under another alias", "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://github.com","custom_alias":"summer-sale"}]`, http.StatusConflict},
+		{"batch url shortened under same alias", "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://github.com","custom_alias":"taken"}]`, http.StatusConflict},
+		{"batch duplicate alias", "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://a.ru","custom_alias":"same"},{"correlation_id":"2","original_url":"https://b.ru","custom_alias":"same"}]`, http.StatusBadRequest},
+		{"batch ok", "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://c.ru","custom_alias":"c-link"},{"correlation_id":"2","original_url":"https://d.ru"}]`, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := do(http.MethodPost, tt.path, tt.body)
+			require.Equal(t, tt.status, rr.Code, rr.Body.String())
+		})
+	}
+
+	rr := do(http.MethodGet, "/my_link", "")
+	require.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+	require.Equal(t, "https://ya.ru", rr.Header().Get("Location"))
+
+	rr = do(http.MethodGet, "/c-link", "")
+	require.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+}
+
+func TestDefaultAliasPolicyReserved(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	mux := InitHandlers(shortener.New(inmemory.NewRepo(), baseURL), baseURL, nil, auth.NewSigner([]byte("test")))
+
+	// служебные пути заняты и без настройки политики
+	for _, alias := range []string{"api", "Debug", "metrics", "ping"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://ya.ru","custom_alias":"`+alias+`"}`))
+		req.Header.Set(contentTypeKey, applicationJSONValue)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code, alias)
+		require.Contains(t, rr.Body.String(), "reserved")
+	}
+}
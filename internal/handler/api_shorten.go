@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 
 	"github.com/IvanOplesnin/url-shortener/internal/logger"
 	"github.com/IvanOplesnin/url-shortener/internal/model"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
 	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
 )
 
 func ShortenAPIHandler(svc *shortener.Service) http.HandlerFunc {
@@ -22,22 +25,34 @@ func ShortenAPIHandler(svc *shortener.Service) http.HandlerFunc {
 
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			logger.Log.Errorf("shorten error %s", err)
-			w.WriteHeader(http.StatusBadRequest)
+			logger.FromContext(r.Context()).Errorf("shorten error %s", err)
+			w.WriteHeader(readErrorStatus(err))
 			return
 		}
 
 		var req model.RequestBody
 		if err := json.Unmarshal(body, &req); err != nil {
-			logger.Log.Errorf("shorten error %s", err)
+			logger.FromContext(r.Context()).Errorf("shorten error %s", err)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 		ctx := r.Context()
-		res, err := svc.Shorten(ctx, req.URL)
+		ttl, err := shortener.TTLFromSeconds(req.TTLSeconds)
 		if err != nil {
-			logger.Log.Errorf("shorten error %s", err)
-			w.WriteHeader(http.StatusBadRequest)
+			logger.FromContext(ctx).Errorf("shorten error %s", err)
+			writeShortenError(w, err)
+			return
+		}
+		params := shortener.ShortenParams{
+			ExpiresAt:   req.ExpiresAt,
+			TTL:         ttl,
+			CustomAlias: req.CustomAlias,
+			Domain:      req.Domain,
+		}
+		res, err := svc.Shorten(ctx, req.URL, params)
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("shorten error %s", err)
+			writeShortenError(w, err)
 			return
 		}
 
@@ -55,3 +70,19 @@ func ShortenAPIHandler(svc *shortener.Service) http.HandlerFunc {
 		w.Write(b)
 	}
 }
+
+// writeShortenError отдаёт ошибки пользовательского alias, запрещённого или неизвестного домена телом JSON,
+// остальные — пустым 400.
+func writeShortenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrShortURLAlreadyExists):
+		writeJSONError(w, http.StatusConflict, errors.New("custom alias is already taken"))
+	case errors.Is(err, shortener.ErrAliasMismatch):
+		writeJSONError(w, http.StatusConflict, err)
+	case errors.Is(err, usvc.ErrInvalidAlias), errors.Is(err, usvc.ErrReservedAlias),
+		errors.Is(err, shortener.ErrBlockedURL), errors.Is(err, shortener.ErrUnknownDomain):
+		writeJSONError(w, http.StatusBadRequest, err)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
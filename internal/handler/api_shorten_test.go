@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/IvanOplesnin/url-shortener/internal/model"
 	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
@@ -45,7 +46,7 @@ func TestShortenApiHandler(t *testing.T) {
 					Times(1)
 
 				m.EXPECT().
-					Add(gomock.Any(), gomock.Any(), repo.URL("https://google.com")).
+					Add(gomock.Any(), gomock.Cond(func(rec repo.Record) bool { return rec.URL == "https://google.com" })).
 					Return(nil).
 					Times(1)
 			},
@@ -81,7 +82,7 @@ func TestShortenApiHandler(t *testing.T) {
 					Times(1)
 
 				m.EXPECT().
-					Add(gomock.Any(), gomock.Any(), gomock.Any()).
+					Add(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			want: want{
@@ -109,7 +110,7 @@ func TestShortenApiHandler(t *testing.T) {
 			contentType: "text/plain", // не application/json
 			setupMock: func(m *mock_repo.MockRepository) {
 				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
-				m.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
 			},
 			want: want{
 				statusCode:  http.StatusBadRequest,
@@ -131,7 +132,7 @@ func TestShortenApiHandler(t *testing.T) {
 			contentType: applicationJSONValue,
 			setupMock: func(m *mock_repo.MockRepository) {
 				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
-				m.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
 			},
 			want: want{
 				statusCode:  http.StatusBadRequest,
@@ -153,7 +154,7 @@ func TestShortenApiHandler(t *testing.T) {
 			contentType: applicationJSONValue,
 			setupMock: func(m *mock_repo.MockRepository) {
 				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
-				m.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
 			},
 			want: want{
 				statusCode:  http.StatusBadRequest,
@@ -170,7 +171,7 @@ func TestShortenApiHandler(t *testing.T) {
 			contentType: applicationJSONValue,
 			setupMock: func(m *mock_repo.MockRepository) {
 				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
-				m.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
 			},
 			want: want{
 				statusCode:  http.StatusBadRequest,
@@ -178,6 +179,155 @@ func TestShortenApiHandler(t *testing.T) {
 				bodyCheck:   nil,
 			},
 		},
+
+		// 7. Ссылка с ttl сохраняется со сроком жизни
+		{
+			name:        "link with ttl",
+			method:      http.MethodPost,
+			body:        []byte(`{"url":"https://google.com","ttl_seconds":60}`),
+			contentType: applicationJSONValue,
+			setupMock: func(m *mock_repo.MockRepository) {
+				m.EXPECT().
+					Search(gomock.Any(), repo.URL("https://google.com")).
+					Return(repo.ShortURL(""), repo.ErrNotFoundURL).
+					Times(1)
+				m.EXPECT().
+					Add(gomock.Any(), gomock.Cond(func(rec repo.Record) bool {
+						return rec.ExpiresAt != nil && rec.ExpiresAt.After(time.Now().Add(50*time.Second))
+					})).
+					Return(nil).
+					Times(1)
+			},
+			want: want{
+				statusCode:  http.StatusCreated,
+				contentType: applicationJSONValue,
+			},
+		},
+
+		// 8. expires_at и ttl_seconds одновременно
+		{
+			name:        "both expires_at and ttl",
+			method:      http.MethodPost,
+			body:        []byte(`{"url":"https://google.com","ttl_seconds":60,"expires_at":"2100-01-01T00:00:00Z"}`),
+			contentType: applicationJSONValue,
+			setupMock: func(m *mock_repo.MockRepository) {
+				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
+			},
+			want: want{
+				statusCode:  http.StatusBadRequest,
+				contentType: applicationJSONValue,
+			},
+		},
+
+		// 8a. ttl_seconds, который при умножении переполнился бы в срок меньше секунды
+		{
+			name:        "ttl overflow",
+			method:      http.MethodPost,
+			body:        []byte(`{"url":"https://google.com","ttl_seconds":18446744074}`),
+			contentType: applicationJSONValue,
+			setupMock: func(m *mock_repo.MockRepository) {
+				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
+			},
+			want: want{
+				statusCode:  http.StatusBadRequest,
+				contentType: applicationJSONValue,
+			},
+		},
+
+		// 9. expires_at в прошлом
+		{
+			name:        "expires_at in the past",
+			method:      http.MethodPost,
+			body:        []byte(`{"url":"https://google.com","expires_at":"2000-01-01T00:00:00Z"}`),
+			contentType: applicationJSONValue,
+			setupMock: func(m *mock_repo.MockRepository) {
+				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
+			},
+			want: want{
+				statusCode:  http.StatusBadRequest,
+				contentType: applicationJSONValue,
+			},
+		},
+
+		// 10. Пользовательский alias
+		{
+			name:        "custom alias",
+			method:      http.MethodPost,
+			body:        []byte(`{"url":"https://google.com","custom_alias":"my-link"}`),
+			contentType: applicationJSONValue,
+			setupMock: func(m *mock_repo.MockRepository) {
+				m.EXPECT().
+					Search(gomock.Any(), repo.URL("https://google.com")).
+					Return(repo.ShortURL(""), repo.ErrNotFoundURL).
+					Times(1)
+				m.EXPECT().
+					Add(gomock.Any(), gomock.Cond(func(rec repo.Record) bool { return rec.ShortURL == "my-link" })).
+					Return(nil).
+					Times(1)
+			},
+			want: want{
+				statusCode:  http.StatusCreated,
+				contentType: applicationJSONValue,
+				bodyCheck: func(t *testing.T, body []byte) {
+					t.Helper()
+					var res model.ResponseBody
+					if err := json.Unmarshal(body, &res); err != nil {
+						t.Fatalf("invalid json body %q: %v", string(body), err)
+					}
+					if res.Result != baseURL+"/my-link" {
+						t.Fatalf("expected %q, got %q", baseURL+"/my-link", res.Result)
+					}
+				},
+			},
+		},
+
+		// 11. alias уже занят — без повтора со случайным id
+		{
+			name:        "custom alias taken",
+			method:      http.MethodPost,
+			body:        []byte(`{"url":"https://google.com","custom_alias":"my-link"}`),
+			contentType: applicationJSONValue,
+			setupMock: func(m *mock_repo.MockRepository) {
+				m.EXPECT().
+					Search(gomock.Any(), repo.URL("https://google.com")).
+					Return(repo.ShortURL(""), repo.ErrNotFoundURL).
+					Times(1)
+				m.EXPECT().
+					Add(gomock.Any(), gomock.Any()).
+					Return(repo.ErrShortURLAlreadyExists).
+					Times(1)
+			},
+			want: want{
+				statusCode:  http.StatusConflict,
+				contentType: applicationJSONValue,
+				bodyCheck: func(t *testing.T, body []byte) {
+					t.Helper()
+					var res model.ResponseError
+					if err := json.Unmarshal(body, &res); err != nil || res.Error == "" {
+						t.Fatalf("expected json error, got %q", string(body))
+					}
+				},
+			},
+		},
+
+		// 12. alias с недопустимыми символами
+		{
+			name:        "invalid custom alias",
+			method:      http.MethodPost,
+			body:        []byte(`{"url":"https://google.com","custom_alias":"a/b c"}`),
+			contentType: applicationJSONValue,
+			setupMock: func(m *mock_repo.MockRepository) {
+				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
+			},
+			want: want{
+				statusCode:  http.StatusBadRequest,
+				contentType: applicationJSONValue,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/audit"
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (o *recordingObserver) Notify(e audit.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, e)
+}
+
+func TestAuditEvents(t *testing.T) {
+	baseURL := "http://localhost:8080/"
+	signer := auth.NewSigner([]byte("test"))
+	obs := &recordingObserver{}
+
+	svc := shortener.New(inmemory.NewRepo(), baseURL, shortener.WithAudit(obs))
+	mux := InitHandlers(svc, baseURL, nil, signer, WithAudit(obs))
+	cookie := &http.Cookie{Name: authCookieName, Value: signer.Sign("user-1")}
+
+	do := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set(contentTypeKey, contentType)
+		}
+		req.AddCookie(cookie)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodPost, "/api/shorten", applicationJSONValue, `{"url":"https://practicum.yandex.ru","custom_alias":"promo"}`)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	// повтор — не создание
+	rr = do(http.MethodPost, "/", textPlainValue, "https://practicum.yandex.ru")
+	require.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = do(http.MethodPost, "/api/shorten/batch", applicationJSONValue,
+		`[{"correlation_id":"1","original_url":"https://practicum.yandex.ru"},{"correlation_id":"2","original_url":"https://go.dev"}]`)
+	require.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = do(http.MethodGet, "/promo", "", "")
+	require.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+
+	rr = do(http.MethodGet, "/missing", "", "")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	type short struct{ action, user, url string }
+	got := make([]short, 0, len(obs.events))
+	for _, e := range obs.events {
+		require.NotZero(t, e.TS)
+		got = append(got, short{e.Action, e.UserID, e.URL})
+	}
+	require.Equal(t, []short{
+		{audit.ActionShorten, "user-1", "https://practicum.yandex.ru"},
+		{audit.ActionShorten, "user-1", "https://go.dev"},
+		{audit.ActionFollow, "user-1", "https://practicum.yandex.ru"},
+	}, got)
+}
@@ -20,26 +20,26 @@ func ShortenBatchAPIHandler(svc *shortener.Service) http.HandlerFunc {
 		w.Header().Set(contentTypeKey, applicationJSONValue)
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			logger.Log.Errorf("shorten batch error %s", err)
-			w.WriteHeader(http.StatusBadRequest)
+			logger.FromContext(r.Context()).Errorf("shorten batch error %s", err)
+			w.WriteHeader(readErrorStatus(err))
 			return
 		}
 		var reqBody []model.RequestBatchBody
 		if err := json.Unmarshal(body, &reqBody); err != nil {
-			logger.Log.Errorf("shorten batch error %s", err)
+			logger.FromContext(r.Context()).Errorf("shorten batch error %s", err)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 		ctx := r.Context()
-		respBatchBody, hadExisting, err := svc.Batch(ctx, reqBody)
+		respBatchBody, hadExisting, err := svc.Batch(ctx, r.URL.Query().Get(domainParam), reqBody)
 		if err != nil {
-			logger.Log.Errorf("shorten batch error %s", err)
-			w.WriteHeader(http.StatusBadRequest)
+			logger.FromContext(r.Context()).Errorf("shorten batch error %s", err)
+			writeShortenError(w, err)
 			return
 		}
 		resp, err := json.Marshal(respBatchBody)
 		if err != nil {
-			logger.Log.Errorf("shorten batch error %s", err)
+			logger.FromContext(r.Context()).Errorf("shorten batch error %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
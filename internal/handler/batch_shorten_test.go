@@ -159,6 +159,16 @@ func TestBatchApiShortenHandler(t *testing.T) {
 				contentType: applicationJSONValue,
 			},
 		},
+		{
+			name:        "ttl overflow",
+			method:      http.MethodPost,
+			body:        []byte(`[{"correlation_id":"req-1","original_url":"https://github.com","ttl_seconds":18446744074}]`),
+			contentType: applicationJSONValue,
+			want: want{
+				statusCode:  http.StatusBadRequest,
+				contentType: applicationJSONValue,
+			},
+		},
 	}
 
 	for _, tt := range tests {
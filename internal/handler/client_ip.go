@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const realIPKey = "X-Real-IP"
+
+type clientIPKey struct{}
+
+// WithClientIP определяет адрес клиента один раз на запрос. X-Real-IP учитывается, только
+// если запрос пришёл напрямую от прокси из trusted: остальные могут вписать туда что угодно.
+func WithClientIP(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			if containsIP(trusted, ip) {
+				if real := strings.TrimSpace(r.Header.Get(realIPKey)); net.ParseIP(real) != nil {
+					ip = real
+				}
+			}
+			ctx := context.WithValue(r.Context(), clientIPKey{}, ip)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clientIP адрес клиента из WithClientIP, а без него — адрес собеседника.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/filestorage"
+	"github.com/IvanOplesnin/url-shortener/internal/model"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/repository/persisted"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomains(t *testing.T) {
+	baseURL := "http://localhost:8080/"
+	signer := auth.NewSigner([]byte("test"))
+	store := filestorage.NewJSONStore(filepath.Join(t.TempDir(), "data.json"))
+
+	newMux := func() http.Handler {
+		repo := inmemory.NewRepo()
+		pr, err := persisted.New(repo, repo, repo, store, repo, nil, repo)
+		require.NoError(t, err)
+		svc := shortener.New(pr, baseURL, shortener.WithDomains([]string{"a.example", "B.example"}))
+		return InitHandlers(svc, baseURL, nil, signer)
+	}
+	mux := newMux()
+	cookie := &http.Cookie{Name: authCookieName, Value: signer.Sign("user-1")}
+
+	post := func(path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(contentTypeKey, contentType)
+		req.AddCookie(cookie)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	redirect := func(host, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// один и тот же alias в двух доменах ведёт в разные места
+	rr := post("/api/shorten", applicationJSONValue, `{"url":"https://marketing.ru","custom_alias":"promo","domain":"a.example"}`)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	require.JSONEq(t, `{"result":"http://a.example/promo"}`, rr.Body.String())
+
+	rr = post("/api/shorten", applicationJSONValue, `{"url":"https://support.ru","custom_alias":"promo","domain":"b.example"}`)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	require.JSONEq(t, `{"result":"http://b.example/promo"}`, rr.Body.String())
+
+	rr = post("/api/shorten", applicationJSONValue, `{"url":"https://other.ru","custom_alias":"promo","domain":"a.example"}`)
+	require.Equal(t, http.StatusConflict, rr.Code)
+
+	// тот же URL в другом домене — новая ссылка, а не конфликт
+	rr = post("/?domain=b.example", textPlainValue, "https://marketing.ru")
+	require.Equal(t, http.StatusCreated, rr.Code)
+	require.True(t, strings.HasPrefix(rr.Body.String(), "http://b.example/"))
+
+	rr = post("/api/shorten", applicationJSONValue, `{"url":"https://x.ru","domain":"c.example"}`)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "unknown domain")
+
+	rr = post("/api/shorten/batch?domain=a.example", applicationJSONValue, `[{"correlation_id":"1","original_url":"https://batch.ru"}]`)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var batch []model.ResponseBatchBody
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &batch))
+	require.True(t, strings.HasPrefix(batch[0].ShortURL, "http://a.example/"))
+
+	check := func(t *testing.T) {
+		for host, want := range map[string]string{
+			"a.example":      "https://marketing.ru",
+			"B.EXAMPLE":      "https://support.ru",
+			"localhost:8080": "",
+		} {
+			rr := redirect(host, "/promo")
+			if want == "" {
+				require.Equal(t, http.StatusNotFound, rr.Code, host)
+				continue
+			}
+			require.Equal(t, http.StatusTemporaryRedirect, rr.Code, host)
+			require.Equal(t, want, rr.Header().Get("Location"))
+		}
+	}
+	check(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.AddCookie(cookie)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var urls []model.ResponseUserURL
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &urls))
+	require.Len(t, urls, 4)
+	require.Equal(t, "http://a.example/promo", urls[0].ShortURL)
+	require.Equal(t, "http://b.example/promo", urls[1].ShortURL)
+
+	// домены переживают перезапуск через файл
+	t.Run("reload from file", func(t *testing.T) {
+		mux = newMux()
+		check(t)
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiredLinkIsGoneAndPurged(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	past := time.Now().Add(-time.Minute)
+
+	repo := inmemory.NewRepo()
+	repo.Seed([]repository.Record{
+		{ID: 0, URL: "https://github.com", ShortURL: "AbCdE1", ExpiresAt: &past},
+		{ID: 1, URL: "https://google.com", ShortURL: "ZxYwV2"},
+	})
+	svc := shortener.New(repo, baseURL)
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")))
+
+	get := func(path string) int {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusGone, get("/AbCdE1"))
+	require.Equal(t, http.StatusTemporaryRedirect, get("/ZxYwV2"))
+
+	sweeper := shortener.NewSweeper(repo, 10*time.Millisecond)
+	go sweeper.Run()
+	require.Eventually(t, func() bool {
+		return get("/AbCdE1") == http.StatusNotFound
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, sweeper.Close(context.Background()))
+
+	// после очистки URL снова можно сократить
+	res, err := svc.Shorten(context.Background(), "https://github.com", shortener.ShortenParams{TTL: time.Hour})
+	require.NoError(t, err)
+	require.False(t, res.Exists)
+	require.Equal(t, http.StatusTemporaryRedirect, get("/"+string(res.Short)))
+	require.Equal(t, http.StatusNotFound, get("/AbCdE1"))
+}
+
+func TestExpiredLinkReshortened(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	past := time.Now().Add(-time.Minute)
+
+	repo := inmemory.NewRepo()
+	repo.Seed([]repository.Record{
+		{ID: 0, URL: "https://github.com", ShortURL: "AbCdE1", ExpiresAt: &past},
+	})
+	svc := shortener.New(repo, baseURL)
+
+	// просроченная ссылка не отдаётся как существующая и до очистки
+	res, err := svc.Shorten(context.Background(), "https://github.com", shortener.ShortenParams{})
+	require.NoError(t, err)
+	require.False(t, res.Exists)
+	require.NotEqual(t, repository.ShortURL("AbCdE1"), res.Short)
+
+	again, err := svc.Shorten(context.Background(), "https://github.com", shortener.ShortenParams{})
+	require.NoError(t, err)
+	require.True(t, again.Exists)
+	require.Equal(t, res.Short, again.Short)
+
+	// очистка старой записи не отвязывает URL от новой
+	_, err = repo.PurgeExpired(context.Background(), time.Now())
+	require.NoError(t, err)
+	short, err := repo.Search(context.Background(), "https://github.com")
+	require.NoError(t, err)
+	require.Equal(t, res.Short, short)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+)
+
+const (
+	forwardedKey      = "Forwarded"
+	forwardedProtoKey = "X-Forwarded-Proto"
+	forwardedHostKey  = "X-Forwarded-Host"
+)
+
+// WithForwardedBase подставляет схему и хост из Forwarded или X-Forwarded-Proto/Host
+// в базовый URL ссылок. Заголовкам верим, только если запрос пришёл напрямую от
+// прокси из trusted; путь базового URL остаётся настроенным.
+func WithForwardedBase(baseURL string, trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(trusted) == 0 {
+			return next
+		}
+		base, err := url.Parse(baseURL)
+		if err != nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !containsIP(trusted, remoteIP(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			proto, host := forwarded(r.Header)
+			if proto == "" && host == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			resolved := *base
+			if proto != "" {
+				resolved.Scheme = proto
+			}
+			if host != "" {
+				resolved.Host = host
+			}
+			ctx := usvc.WithBase(r.Context(), resolved.String())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// forwarded достаёт схему и хост из последнего элемента: его дописал доверенный прокси,
+// а всё, что левее, мог прислать сам клиент. Forwarded (RFC 7239) важнее X-Forwarded-*;
+// значения, похожие на мусор, отбрасываются.
+func forwarded(h http.Header) (proto, host string) {
+	if v := lastValue(h.Values(forwardedKey)); v != "" {
+		for _, pair := range strings.Split(v, ";") {
+			k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			val = strings.Trim(val, `"`)
+			switch strings.ToLower(k) {
+			case "proto":
+				proto = val
+			case "host":
+				host = val
+			}
+		}
+	} else {
+		proto = lastValue(h.Values(forwardedProtoKey))
+		host = lastValue(h.Values(forwardedHostKey))
+	}
+
+	proto = strings.ToLower(proto)
+	if proto != "http" && proto != "https" {
+		proto = ""
+	}
+	if !validHost(host) {
+		host = ""
+	}
+	return proto, host
+}
+
+// lastValue последний элемент списка через запятую; заголовок может повторяться.
+func lastValue(values []string) string {
+	v := strings.Join(values, ",")
+	if i := strings.LastIndex(v, ","); i >= 0 {
+		v = v[i+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+func validHost(host string) bool {
+	if host == "" || strings.ContainsAny(host, "/\\?#@ \t") {
+		return false
+	}
+	u, err := url.Parse("http://" + host)
+	return err == nil && u.Host == host && u.Hostname() != ""
+}
+
+// requestHost хост, на который пришёл клиент: из заголовков доверенного прокси, если
+// WithForwardedBase их принял, иначе Host запроса.
+func requestHost(r *http.Request) string {
+	if base := usvc.Base(r.Context(), ""); base != "" {
+		if u, err := url.Parse(base); err == nil {
+			return u.Host
+		}
+	}
+	return r.Host
+}
+
+// remoteIP адрес непосредственного собеседника, без учёта X-Real-IP.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
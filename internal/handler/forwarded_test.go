@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardedBase(t *testing.T) {
+	baseURL := "https://sho.rt/s/"
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	svc := shortener.New(inmemory.NewRepo(), baseURL)
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")),
+		WithTrustedProxies([]*net.IPNet{proxies}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		wantPrefix string
+	}{
+		{
+			name:       "no headers",
+			remoteAddr: "10.0.0.1:5000",
+			wantPrefix: "https://sho.rt/s/",
+		},
+		{
+			name:       "x-forwarded from trusted proxy",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{forwardedProtoKey: "http", forwardedHostKey: "inner, mirror.example:8443"},
+			wantPrefix: "http://mirror.example:8443/s/",
+		},
+		{
+			// левые значения мог вписать клиент, верим только дописанному прокси
+			name:       "client supplied x-forwarded ignored",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{forwardedProtoKey: "http, https", forwardedHostKey: "evil.example, sho.rt"},
+			wantPrefix: "https://sho.rt/s/",
+		},
+		{
+			name:       "forwarded wins over x-forwarded",
+			remoteAddr: "10.0.0.1:5000",
+			headers: map[string]string{
+				forwardedKey:     `for=1.2.3.4;proto=http;host="evil.example", for=10.0.0.2;proto=https;host="alt.example"`,
+				forwardedHostKey: "ignored.example",
+			},
+			wantPrefix: "https://alt.example/s/",
+		},
+		{
+			name:       "untrusted peer",
+			remoteAddr: "192.0.2.1:5000",
+			headers:    map[string]string{forwardedProtoKey: "http", forwardedHostKey: "evil.example"},
+			wantPrefix: "https://sho.rt/s/",
+		},
+		{
+			name:       "garbage host ignored",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{forwardedProtoKey: "ftp", forwardedHostKey: "evil.example/path"},
+			wantPrefix: "https://sho.rt/s/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com/"+tt.name))
+			req.Header.Set(contentTypeKey, textPlainValue)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusCreated, rr.Code)
+			require.True(t, strings.HasPrefix(rr.Body.String(), tt.wantPrefix), "got %s", rr.Body.String())
+
+			// ссылка открывается по настроенному пути, каким бы ни был хост
+			path := "/s/" + strings.TrimPrefix(rr.Body.String(), tt.wantPrefix)
+			rr = httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			require.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	var got string
+	h := WithClientIP([]*net.IPNet{proxies})(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = clientIP(r)
+	}))
+	do := func(remoteAddr, realIP string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(realIPKey, realIP)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	require.Equal(t, "1.2.3.4", do("10.0.0.1:5000", "1.2.3.4"))
+	// клиент без прокси не выбирает себе адрес
+	require.Equal(t, "192.0.2.1", do("192.0.2.1:5000", "1.2.3.4"))
+	require.Equal(t, "10.0.0.1", do("10.0.0.1:5000", "not-an-ip"))
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/model"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeGenerators(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	shorten := func(t *testing.T, mux http.Handler, raw string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"`+raw+`"}`))
+		req.Header.Set(contentTypeKey, applicationJSONValue)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var res model.ResponseBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		return strings.TrimPrefix(res.Result, baseURL+"/")
+	}
+
+	newMux := func(t *testing.T, kind string) http.Handler {
+		t.Helper()
+		repo := inmemory.NewRepo()
+		gen, err := usvc.NewGenerator(kind, 7, "salt", repo)
+		require.NoError(t, err)
+		svc := shortener.New(repo, baseURL, shortener.WithGenerator(gen))
+		return InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")))
+	}
+
+	for _, kind := range []string{usvc.GeneratorRandom, usvc.GeneratorSequence, usvc.GeneratorSqids, usvc.GeneratorHash} {
+		t.Run(kind, func(t *testing.T) {
+			mux := newMux(t, kind)
+			seen := make(map[string]struct{})
+			for i := 0; i < 200; i++ {
+				code := shorten(t, mux, fmt.Sprintf("https://example.com/%d", i))
+				require.Len(t, code, 7)
+				require.Regexp(t, `^[A-Za-z0-9]+$`, code)
+				_, dup := seen[code]
+				require.False(t, dup, "duplicate code %s", code)
+				seen[code] = struct{}{}
+
+				req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+				rr := httptest.NewRecorder()
+				mux.ServeHTTP(rr, req)
+				require.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+			}
+		})
+	}
+
+	t.Run("hash is deterministic", func(t *testing.T) {
+		a := shorten(t, newMux(t, usvc.GeneratorHash), "https://github.com")
+		b := shorten(t, newMux(t, usvc.GeneratorHash), "https://github.com")
+		require.Equal(t, a, b)
+	})
+
+	t.Run("sqids is not sequential", func(t *testing.T) {
+		mux := newMux(t, usvc.GeneratorSqids)
+		a := shorten(t, mux, "https://a.ru")
+		b := shorten(t, mux, "https://b.ru")
+		require.NotEqual(t, a[:6], b[:6])
+	})
+
+	t.Run("unknown generator", func(t *testing.T) {
+		_, err := usvc.NewGenerator("uuid", 6, "", nil)
+		require.ErrorIs(t, err, usvc.ErrUnknownGenerator)
+	})
+}
+
+func TestAdaptiveCodeLength(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	repo := inmemory.NewRepo()
+	factory := func(n int) (usvc.CodeGenerator, error) { return usvc.NewRandomGenerator(n), nil }
+	gen, err := usvc.NewAdaptiveGenerator(factory, usvc.DefaultAdaptiveConfig(1, 4))
+	require.NoError(t, err)
+
+	svc := shortener.New(repo, baseURL, shortener.WithGenerator(gen))
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")))
+
+	// односимвольных кодов всего 62 — без роста длины большая часть запросов упала бы
+	for i := 0; i < 150; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(fmt.Sprintf(`{"url":"https://example.com/%d"}`, i)))
+		req.Header.Set(contentTypeKey, applicationJSONValue)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusCreated, rr.Code)
+	}
+
+	var batch strings.Builder
+	batch.WriteString("[")
+	for i := 0; i < 100; i++ {
+		if i > 0 {
+			batch.WriteString(",")
+		}
+		fmt.Fprintf(&batch, `{"correlation_id":"%d","original_url":"https://batch.example.com/%d"}`, i, i)
+	}
+	batch.WriteString("]")
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(batch.String()))
+	req.Header.Set(contentTypeKey, applicationJSONValue)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	stats, ok := svc.GeneratorStats()
+	require.True(t, ok)
+	require.Greater(t, stats.Length, 1)
+	require.LessOrEqual(t, stats.Length, 4)
+	require.NotZero(t, stats.Collisions)
+	require.EqualValues(t, stats.Length-1, stats.Grows)
+	require.GreaterOrEqual(t, stats.Generated, uint64(250))
+}
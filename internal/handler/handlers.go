@@ -1,9 +1,16 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
+	"net"
 	"net/http"
+	"time"
 
+	"github.com/IvanOplesnin/url-shortener/internal/audit"
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/model"
 	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
 	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
 	u "github.com/IvanOplesnin/url-shortener/internal/service/url"
@@ -16,26 +23,127 @@ const (
 	contentEncodingKey   = "Content-Encoding"
 	applicationJSONValue = "application/json"
 	textPlainValue       = "text/plain"
+
+	// domainParam query-параметр с коротким доменом для сокращения, удаления и статистики
+	domainParam = "domain"
 )
 
-func InitHandlers(svc *shortener.Service, baseURL string, p Pinger) *chi.Mux {
+// ReservedAliases первые сегменты путей, которые занимает сам сервер;
+// пользовательский alias не должен с ними совпадать.
+func ReservedAliases() []string {
+	return []string{"api", "ping", "metrics"}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set(contentTypeKey, applicationJSONValue)
+	w.WriteHeader(status)
+	b, _ := json.Marshal(model.ResponseError{Error: err.Error()})
+	_, _ = w.Write(b)
+}
+
+// Option дополнительная настройка роутера.
+type Option func(*routerOptions)
+
+type routerOptions struct {
+	rt         *Runtime
+	adminToken string
+	reload     Reloader
+	trusted    []*net.IPNet
+	subnet     *net.IPNet
+	audit      audit.Observer
+	metrics    HTTPObserver
+	metricsH   http.Handler
+	secure     bool
+}
+
+// WithRuntime берёт сжатие, лимиты и блок-лист клиентов из rt, чтобы их можно было менять на лету.
+func WithRuntime(rt *Runtime) Option {
+	return func(o *routerOptions) { o.rt = rt }
+}
+
+// WithAdmin открывает POST /api/admin/reload для запросов с токеном; пустой токен — ручки нет.
+func WithAdmin(token string, reload Reloader) Option {
+	return func(o *routerOptions) {
+		o.adminToken = token
+		o.reload = reload
+	}
+}
+
+// WithTrustedProxies разрешает брать схему и хост ссылок из заголовков Forwarded
+// и X-Forwarded-*, а адрес клиента — из X-Real-IP, если запрос пришёл с одного из этих адресов.
+func WithTrustedProxies(nets []*net.IPNet) Option {
+	return func(o *routerOptions) { o.trusted = nets }
+}
+
+// WithTrustedSubnet открывает GET /api/internal/stats для клиентов из subnet;
+// без подсети ручка всегда отвечает 403.
+func WithTrustedSubnet(subnet *net.IPNet) Option {
+	return func(o *routerOptions) { o.subnet = subnet }
+}
+
+// WithSecureCookie выдаёт куку auth с флагом Secure; включается вместе с TLS.
+func WithSecureCookie(secure bool) Option {
+	return func(o *routerOptions) { o.secure = secure }
+}
+
+// WithAudit сообщает obs о каждом переходе по короткой ссылке.
+func WithAudit(obs audit.Observer) Option {
+	return func(o *routerOptions) { o.audit = obs }
+}
+
+// WithMetrics считает запросы через obs и отдаёт h на GET /metrics.
+func WithMetrics(obs HTTPObserver, h http.Handler) Option {
+	return func(o *routerOptions) {
+		o.metrics = obs
+		o.metricsH = h
+	}
+}
+
+func InitHandlers(svc *shortener.Service, baseURL string, p Pinger, signer *auth.Signer, opts ...Option) *chi.Mux {
+	o := routerOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.rt == nil {
+		o.rt = NewRuntime(DefaultRuntimeSettings())
+	}
+
 	router := chi.NewRouter()
 
 	baseP := u.BasePath(baseURL)
 
+	router.Use(WithTracing)
+	router.Use(WithRequestID)
 	router.Use(WithLogging)
-	router.Use(CompressGzip)
+	if o.metrics != nil {
+		router.Use(CollectMetrics(o.metrics))
+	}
+	router.Use(Recoverer)
+	router.Use(WithClientIP(o.trusted))
+	router.Use(o.rt.Guard)
+	router.Use(WithForwardedBase(baseURL, o.trusted))
+	router.Use(o.rt.CompressGzip)
 	router.Use(UncompressGzip)
+	router.Use(WithAuth(signer, o.secure))
 
 	router.Post("/", ShortenLinkHandler(svc))
 	router.Post("/api/shorten", ShortenAPIHandler(svc))
 	router.Post("/api/shorten/batch", ShortenBatchAPIHandler(svc))
+	router.Get("/api/user/urls", UserURLsHandler(svc))
+	router.Delete("/api/user/urls", DeleteUserURLsHandler(svc))
+	router.Get("/api/urls/{id}/stats", StatsHandler(svc))
 	router.Get("/ping", PingHandler(p))
-
+	if o.metricsH != nil {
+		router.Method(http.MethodGet, "/metrics", o.metricsH)
+	}
+	router.With(WithTrustedSubnetOnly(o.subnet)).Get("/api/internal/stats", InternalStatsHandler(svc))
+	if o.adminToken != "" && o.reload != nil {
+		router.With(WithAdminToken(o.adminToken)).Post("/api/admin/reload", ReloadHandler(o.reload))
+	}
 
 	router.Route(
 		baseP, func(router chi.Router) {
-			router.Get("/{id}", RedirectHandler(svc))
+			router.Get("/{id}", RedirectHandler(svc, o.audit))
 		})
 
 	return router
@@ -51,12 +159,12 @@ func ShortenLinkHandler(svc *shortener.Service) http.HandlerFunc {
 		w.Header().Set(contentTypeKey, textPlainValue)
 		raw, err := io.ReadAll(r.Body)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
+			w.WriteHeader(readErrorStatus(err))
 			return
 		}
 
 		ctx := r.Context()
-		res, err := svc.Shorten(ctx, repo.URL(raw))
+		res, err := svc.Shorten(ctx, repo.URL(raw), shortener.ShortenParams{Domain: r.URL.Query().Get(domainParam)})
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
@@ -70,15 +178,29 @@ func ShortenLinkHandler(svc *shortener.Service) http.HandlerFunc {
 	}
 }
 
-func RedirectHandler(svc *shortener.Service) http.HandlerFunc {
+func RedirectHandler(svc *shortener.Service, obs audit.Observer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
 		ctx := r.Context()
-		url, err := svc.Resolve(ctx, repo.ShortURL(id))
+		url, err := svc.ResolveHost(ctx, requestHost(r), repo.ShortURL(id))
+		if errors.Is(err, repo.ErrDeleted) || errors.Is(err, repo.ErrExpired) {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
 		if err != nil {
 			http.NotFound(w, r)
 			return
 		}
+		svc.RecordClick(requestHost(r), repo.Click{
+			ShortURL:  repo.ShortURL(id),
+			At:        time.Now().UTC(),
+			Referrer:  r.Referer(),
+			UserAgent: r.UserAgent(),
+			IP:        clientIP(r),
+		})
+		if obs != nil {
+			obs.Notify(audit.NewEvent(audit.ActionFollow, auth.UserID(ctx), string(url)))
+		}
 		http.Redirect(w, r, string(url), http.StatusTemporaryRedirect)
 	}
 }
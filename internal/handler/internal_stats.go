@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+)
+
+// WithTrustedSubnetOnly пускает дальше только клиентов из subnet. Адрес берётся из X-Real-IP,
+// если запрос пришёл от доверенного прокси (WithClientIP), иначе — адрес собеседника.
+// Пустая подсеть закрывает ручку для всех.
+func WithTrustedSubnetOnly(subnet *net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := net.ParseIP(clientIP(r))
+			if subnet == nil || ip == nil || !subnet.Contains(ip) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func InternalStatsHandler(svc *shortener.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := svc.InternalStats(r.Context())
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("internal stats error %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := json.Marshal(stats)
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("internal stats error %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(contentTypeKey, applicationJSONValue)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(resp)
+	}
+}
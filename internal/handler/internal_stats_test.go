@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInternalStatsHandler(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	signer := auth.NewSigner([]byte("test"))
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	_, proxyNet, err := net.ParseCIDR("172.16.0.0/12")
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Hour)
+	repo := inmemory.NewRepo()
+	repo.Seed([]repository.Record{
+		{ID: 0, URL: "https://github.com", ShortURL: "a1", UserID: "user-1"},
+		{ID: 1, URL: "https://go.dev", ShortURL: "a2", UserID: "user-1"},
+		{ID: 2, URL: "https://ya.ru", ShortURL: "a3", UserID: "user-2", Domain: "b.example"},
+		{ID: 3, URL: "https://deleted.ru", ShortURL: "a4", UserID: "user-3", Deleted: true},
+		{ID: 4, URL: "https://expired.ru", ShortURL: "a5", ExpiresAt: &past},
+	})
+	svc := shortener.New(repo, baseURL)
+
+	tests := []struct {
+		name       string
+		subnet     *net.IPNet
+		realIP     string
+		remoteAddr string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			// без доверенного прокси X-Real-IP может вписать кто угодно
+			name:       "X-Real-IP from untrusted peer",
+			subnet:     subnet,
+			realIP:     "10.1.2.3",
+			remoteAddr: "192.168.1.1:5555",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "remote address in subnet",
+			subnet:     subnet,
+			remoteAddr: "10.0.0.1:5555",
+			wantStatus: http.StatusOK,
+			// у user-3 ссылка удалена, а просроченная ничья
+			wantBody: `{"urls":3,"users":2}`,
+		},
+		{
+			// за прокси проверяется клиент, а не сам прокси
+			name:       "X-Real-IP in subnet from trusted proxy",
+			subnet:     subnet,
+			realIP:     "10.1.2.3",
+			remoteAddr: "172.16.0.1:5555",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "X-Real-IP outside subnet from trusted proxy",
+			subnet:     subnet,
+			realIP:     "192.168.1.1",
+			remoteAddr: "10.0.0.1:5555",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "remote address outside subnet",
+			subnet:     subnet,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "no subnet configured",
+			realIP:     "10.1.2.3",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// часть прокси стоит в той же подсети
+			mux := InitHandlers(svc, baseURL, nil, signer,
+				WithTrustedSubnet(tt.subnet), WithTrustedProxies([]*net.IPNet{subnet, proxyNet}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			if tt.realIP != "" {
+				req.Header.Set(realIPKey, tt.realIP)
+			}
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantBody != "" {
+				require.JSONEq(t, tt.wantBody, rr.Body.String())
+				require.Equal(t, applicationJSONValue, rr.Header().Get(contentTypeKey))
+			}
+		})
+	}
+}
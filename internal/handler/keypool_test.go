@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/model"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+)
+
+// prefixGen выдаёт prefix0001, prefix0002, ... — по префиксу видно, откуда взялся код.
+type prefixGen struct {
+	prefix string
+	n      atomic.Int64
+}
+
+func (g *prefixGen) Next(_ context.Context, _ repository.URL, _ int) (repository.ShortURL, error) {
+	return repository.ShortURL(fmt.Sprintf("%s%04d", g.prefix, g.n.Add(1))), nil
+}
+
+func TestKeyPool(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	ctx := context.Background()
+
+	repo := inmemory.NewRepo()
+	// p0001 уже занят — в пул он попасть не должен
+	repo.Seed([]repository.Record{{ID: 0, URL: "https://github.com", ShortURL: "p0001"}})
+	pool := inmemory.NewKeyPool(repo)
+
+	filler := shortener.NewKeyPoolFiller(pool, &prefixGen{prefix: "p"}, 20, time.Hour)
+	go filler.Run()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, filler.Close(ctx))
+	}()
+	require.Eventually(t, func() bool {
+		n, err := pool.PoolSize(ctx)
+		return err == nil && n == 20
+	}, time.Second, 5*time.Millisecond)
+
+	svc := shortener.New(repo, baseURL,
+		shortener.WithGenerator(&prefixGen{prefix: "g"}),
+		shortener.WithKeyPool(pool, filler),
+	)
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(contentTypeKey, applicationJSONValue)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// пользовательский alias совпал с ключом из пула; первый, кто вытащит этот ключ,
+	// должен не упасть, а сгенерировать код сам
+	rr := post("/api/shorten", `{"url":"https://alias.ru","custom_alias":"p0021"}`)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	codes := make(map[string]struct{})
+	for i := 0; i < 15; i++ {
+		rr := post("/api/shorten", fmt.Sprintf(`{"url":"https://example.com/%d"}`, i))
+		require.Equal(t, http.StatusCreated, rr.Code)
+		var res model.ResponseBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		code := strings.TrimPrefix(res.Result, baseURL+"/")
+		require.NotEqual(t, "p0001", code)
+		codes[code] = struct{}{}
+	}
+
+	rr = post("/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://a.ru"},{"correlation_id":"2","original_url":"https://b.ru"},{"correlation_id":"3","original_url":"https://c.ru"}]`)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var batch []model.ResponseBatchBody
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &batch))
+	for _, b := range batch {
+		codes[strings.TrimPrefix(b.ShortURL, baseURL+"/")] = struct{}{}
+	}
+
+	require.Len(t, codes, 18)
+	fromPool, generated := 0, 0
+	for code := range codes {
+		switch {
+		case strings.HasPrefix(code, "p"):
+			fromPool++
+		case strings.HasPrefix(code, "g"):
+			generated++
+		}
+	}
+	require.Equal(t, 17, fromPool)
+	require.Equal(t, 1, generated)
+}
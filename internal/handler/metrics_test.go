@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/metrics"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/repository/metered"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	baseURL := "http://localhost:8080/"
+	signer := auth.NewSigner([]byte("test"))
+	m := metrics.New()
+
+	svc := shortener.New(metered.New(inmemory.NewRepo(), m), baseURL,
+		shortener.WithGenerator(m.TrackGenerator(usvc.NewRandomGenerator(6))))
+	mux := InitHandlers(svc, baseURL, nil, signer, WithMetrics(m, m.Handler()))
+
+	do := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set(contentTypeKey, contentType)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/", textPlainValue, "https://practicum.yandex.ru").Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/shorten/batch", applicationJSONValue,
+		`[{"correlation_id":"1","original_url":"https://go.dev"}]`).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/AbCdEf", "", "").Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/a/b/c", "", "").Code)
+
+	rr := do(http.MethodGet, "/metrics", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+
+	for _, want := range []string{
+		`shortener_http_requests_total{code="201",method="POST",route="/"} 1`,
+		`shortener_http_requests_total{code="201",method="POST",route="/api/shorten/batch"} 1`,
+		`shortener_http_requests_total{code="404",method="GET",route="/{id}"} 1`,
+		`shortener_http_requests_total{code="404",method="GET",route="/*"} 1`,
+		`shortener_http_request_duration_seconds_count{code="201",method="POST",route="/"} 1`,
+		`shortener_repo_operation_duration_seconds_count{op="Add"} 1`,
+		`shortener_repo_operation_duration_seconds_count{op="Get"} 1`,
+		`shortener_repo_operation_duration_seconds_count{op="AddMany"} 1`,
+		`shortener_repo_operation_duration_seconds_count{op="GetByURLs"} 1`,
+		`shortener_codegen_attempts_total{outcome="inserted"} 2`,
+	} {
+		require.Contains(t, string(body), want)
+	}
+	// ненайденная ссылка — обычный ответ, а не ошибка хранилища
+	require.NotContains(t, string(body), `shortener_repo_operation_errors_total{op="Get"}`)
+}
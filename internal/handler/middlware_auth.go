@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+)
+
+const authCookieName = "auth"
+
+// WithAuth проверяет подписанную куку с id пользователя.
+// Если куки нет или подпись не сходится, выдаётся новый id и новая кука.
+// secure ставит куке флаг Secure, чтобы браузер не отправлял её по голому HTTP.
+func WithAuth(signer *auth.Signer, secure bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			if c, err := r.Cookie(authCookieName); err == nil {
+				if userID, err := signer.Verify(c.Value); err == nil {
+					ctx := auth.WithUser(r.Context(), auth.User{ID: userID})
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				}
+			}
+
+			userID, err := auth.NewUserID()
+			if err != nil {
+				logger.FromContext(r.Context()).Errorf("auth error %s", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{
+				Name:     authCookieName,
+				Value:    signer.Sign(userID),
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   secure,
+				SameSite: http.SameSiteLaxMode,
+			})
+			ctx := auth.WithUser(r.Context(), auth.User{ID: userID, IsNew: true})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		return http.HandlerFunc(f)
+	}
+}
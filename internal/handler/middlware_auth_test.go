@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAuthCookie(t *testing.T) {
+	signer := auth.NewSigner([]byte("test"))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, secure := range []bool{false, true} {
+		rr := httptest.NewRecorder()
+		WithAuth(signer, secure)(next).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		cookies := rr.Result().Cookies()
+		require.Len(t, cookies, 1)
+		c := cookies[0]
+		require.Equal(t, authCookieName, c.Name)
+		require.True(t, c.HttpOnly)
+		// с TLS кука не должна уходить по голому HTTP
+		require.Equal(t, secure, c.Secure)
+
+		userID, err := signer.Verify(c.Value)
+		require.NoError(t, err)
+		require.NotEmpty(t, userID)
+	}
+
+	// подписанная кука принимается, новая не выдаётся
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: authCookieName, Value: signer.Sign("user-1")})
+	rr := httptest.NewRecorder()
+	WithAuth(signer, true)(next).ServeHTTP(rr, req)
+	require.Empty(t, rr.Result().Cookies())
+}
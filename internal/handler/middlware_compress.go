@@ -12,6 +12,7 @@ type gzipWriter struct {
 	http.ResponseWriter
 	req         *http.Request
 	gz          *gzip.Writer
+	level       int
 	compress    bool
 	wroteHeader bool
 	statusCode  int
@@ -44,7 +45,7 @@ func (w *gzipWriter) initGzip() {
 		return
 	}
 	if w.shouldCompress() {
-		gz, err := gzip.NewWriterLevel(w.ResponseWriter, gzip.BestSpeed)
+		gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.level)
 		if err != nil {
 			return
 		}
@@ -80,12 +81,18 @@ func (w *gzipWriter) Write(p []byte) (int, error) {
 }
 
 func CompressGzip(next http.Handler) http.Handler {
+	return compressGzip(next, func() (bool, int) { return true, gzip.BestSpeed })
+}
+
+// compressGzip берёт настройки на каждый запрос, чтобы их можно было менять без перезапуска.
+func compressGzip(next http.Handler, settings func() (enabled bool, level int)) http.Handler {
 	compressFunc := func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get(acceptEncodingKey), "gzip") {
+		enabled, level := settings()
+		if !enabled || !strings.Contains(r.Header.Get(acceptEncodingKey), "gzip") {
 			next.ServeHTTP(w, r)
 			return
 		}
-		newWriter := gzipWriter{ResponseWriter: w, req: r}
+		newWriter := gzipWriter{ResponseWriter: w, req: r, level: level}
 		defer func() {
 			if newWriter.gz != nil {
 				_ = newWriter.gz.Close()
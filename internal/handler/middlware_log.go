@@ -52,7 +52,8 @@ func WithLogging(next http.Handler) http.Handler {
 		next.ServeHTTP(&lw, r)
 		duration := time.Since(start)
 
-		l.Log.WithFields(logrus.Fields{
+		// request_id и trace_id уже в логгере запроса из WithRequestID
+		l.FromContext(r.Context()).WithFields(logrus.Fields{
 			"uri":      uri,
 			"method":   method,
 			"status":   responseData.status,
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// unmatchedRoute метка для запросов без шаблона маршрута (например, отбитых Guard
+// до маршрутизации), чтобы произвольные пути не раздували число серий.
+const unmatchedRoute = "unmatched"
+
+// HTTPObserver получает исход каждого запроса; route — шаблон маршрута chi.
+type HTTPObserver interface {
+	ObserveHTTP(method, route string, status int, d time.Duration)
+}
+
+// CollectMetrics отдаёт obs метод, шаблон маршрута, статус и длительность запроса.
+func CollectMetrics(obs HTTPObserver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			data := &responseData{}
+			next.ServeHTTP(&loggingResponseWriter{ResponseWriter: w, responseData: data}, r)
+
+			status := data.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			obs.ObserveHTTP(r.Method, route, status, time.Since(start))
+		})
+	}
+}
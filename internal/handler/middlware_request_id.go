@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	l "github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	requestIDKey    = "X-Request-ID"
+	maxRequestIDLen = 128
+)
+
+// WithRequestID берёт X-Request-ID клиента или прокси, а если его нет или он
+// странный — выдаёт новый, и возвращает его в ответе. Логгер из контекста запроса
+// получает request_id и, если есть спан, trace_id и span_id.
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDKey)
+		if !validRequestID(id) {
+			id = l.NewRequestID()
+		}
+		w.Header().Set(requestIDKey, id)
+
+		ctx := l.WithRequestID(r.Context(), id)
+		if traceID, spanID := tracing.IDs(ctx); traceID != "" {
+			ctx = l.WithFields(ctx, logrus.Fields{"trace_id": traceID, "span_id": spanID})
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Recoverer превращает панику в хендлере в 500 и пишет её в лог со стеком.
+// http.ErrAbortHandler пробрасывается дальше: им хендлер сам обрывает ответ.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			l.FromContext(r.Context()).WithFields(logrus.Fields{
+				"panic": fmt.Sprint(rec),
+				"stack": string(debug.Stack()),
+			}).Error("handler panic recovered")
+			w.WriteHeader(http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimiter token bucket на каждый адрес клиента. Корзины, которые давно
+// наполнились до краёв, выкидываются, чтобы карта не росла бесконечно.
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+	// maxBuckets предел корзин на случай, когда адресов много и все активны
+	maxBuckets int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+const (
+	rateSweepInterval = time.Minute
+	maxRateBuckets    = 100_000
+)
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket), now: time.Now, maxBuckets: maxRateBuckets}
+}
+
+// allow списывает токен у key. Если токенов нет, возвращает, сколько ждать следующего.
+func (l *rateLimiter) allow(key string, rate float64, burst int) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= rateSweepInterval {
+		l.sweep(now, rate, burst)
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.maxBuckets {
+			l.evict(now, rate, burst)
+		}
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait
+}
+
+func (l *rateLimiter) sweep(now time.Time, rate float64, burst int) {
+	full := time.Duration(float64(burst) / rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// evict освобождает место под новую корзину: сначала обычная чистка, а если все корзины
+// свежие — выкидываются случайные, их клиенты просто начнут с полной корзины.
+func (l *rateLimiter) evict(now time.Time, rate float64, burst int) {
+	l.sweep(now, rate, burst)
+	l.lastSweep = now
+	for key := range l.buckets {
+		if len(l.buckets) < l.maxBuckets {
+			return
+		}
+		delete(l.buckets, key)
+	}
+}
+
+// reset забывает все корзины; вызывается при смене лимитов.
+func (l *rateLimiter) reset() {
+	l.mu.Lock()
+	l.buckets = make(map[string]*bucket)
+	l.mu.Unlock()
+}
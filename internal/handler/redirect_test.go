@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
 	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
 	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
 	mock_repo "github.com/IvanOplesnin/url-shortener/mock"
@@ -66,6 +67,30 @@ func TestRedirectHandler(t *testing.T) {
 				// Location не ожидается, так как редирект не происходит
 			},
 		},
+		// Тест 3: ссылка удалена владельцем
+		{
+			name:   "deleted",
+			method: http.MethodGet,
+			path:   baseURL + "/abc123",
+			setupMock: func(m *mock_repo.MockRepository) {
+				m.EXPECT().Get(gomock.Any(), repo.ShortURL("abc123")).Return(repo.URL(""), repo.ErrDeleted).Times(1)
+			},
+			want: want{
+				statusCode: http.StatusGone, // Ожидается статус 410
+			},
+		},
+		// Тест 4: срок жизни ссылки истёк
+		{
+			name:   "expired",
+			method: http.MethodGet,
+			path:   baseURL + "/abc123",
+			setupMock: func(m *mock_repo.MockRepository) {
+				m.EXPECT().Get(gomock.Any(), repo.ShortURL("abc123")).Return(repo.URL(""), repo.ErrExpired).Times(1)
+			},
+			want: want{
+				statusCode: http.StatusGone,
+			},
+		},
 	}
 
 	// Запуск каждого тестового случая
@@ -89,7 +114,7 @@ func TestRedirectHandler(t *testing.T) {
 			// InitHandlers настраивает маршруты, включая обработчик редиректа
 			newService := shortener.New(storage, baseURL)
 
-			mux := InitHandlers(newService, baseURL, nil)
+			mux := InitHandlers(newService, baseURL, nil, auth.NewSigner([]byte("test")))
 			// Обработка запроса
 			mux.ServeHTTP(rr, req)
 
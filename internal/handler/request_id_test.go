@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	l "github.com/IvanOplesnin/url-shortener/internal/logger"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	formatter := l.Log.Formatter
+	l.Log.SetOutput(buf)
+	l.Log.SetFormatter(&logrus.JSONFormatter{})
+	t.Cleanup(func() {
+		l.Log.SetOutput(os.Stdout)
+		l.Log.SetFormatter(formatter)
+	})
+	return buf
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestWithRequestID(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	svc := shortener.New(inmemory.NewRepo(), baseURL)
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "generated", incoming: "", keep: false},
+		{name: "propagated", incoming: "abc-123", keep: true},
+		{name: "with spaces replaced", incoming: "bad id", keep: false},
+		{name: "too long replaced", incoming: strings.Repeat("a", maxRequestIDLen+1), keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLog(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestIDKey, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			got := rr.Header().Get(requestIDKey)
+			require.NotEmpty(t, got)
+			if tt.keep {
+				require.Equal(t, tt.incoming, got)
+			} else {
+				require.NotEqual(t, tt.incoming, got)
+				require.Len(t, got, 32)
+			}
+
+			lines := logLines(t, buf)
+			require.NotEmpty(t, lines)
+			require.Equal(t, got, lines[len(lines)-1][l.RequestIDField])
+		})
+	}
+}
+
+func TestRecoverer(t *testing.T) {
+	buf := captureLog(t)
+
+	h := WithRequestID(Recoverer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestIDKey, "req-1")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.Equal(t, "req-1", rr.Header().Get(requestIDKey))
+
+	lines := logLines(t, buf)
+	require.Len(t, lines, 1)
+	require.Equal(t, "req-1", lines[0][l.RequestIDField])
+	require.Equal(t, "boom", lines[0]["panic"])
+	require.Contains(t, lines[0]["stack"], "request_id_test.go")
+}
+
+func TestRecovererAbortHandler(t *testing.T) {
+	h := Recoverer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
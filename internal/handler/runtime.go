@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// RuntimeSettings настройки обработки запросов, которые меняются без перезапуска.
+type RuntimeSettings struct {
+	GzipEnabled bool
+	GzipLevel   int
+	// MaxBodyBytes 0 — тело не ограничено.
+	MaxBodyBytes int64
+	// RateLimit запросов в секунду с одного адреса; 0 — без ограничения.
+	RateLimit      float64
+	RateBurst      int
+	BlockedClients []*net.IPNet
+}
+
+// DefaultRuntimeSettings то, как сервер работал до появления настроек: gzip со
+// скоростью в приоритете и никаких ограничений.
+func DefaultRuntimeSettings() RuntimeSettings {
+	return RuntimeSettings{GzipEnabled: true, GzipLevel: gzip.BestSpeed}
+}
+
+// Runtime хранит текущие RuntimeSettings; Apply подменяет их атомарно,
+// запросы в полёте дорабатывают со старыми.
+type Runtime struct {
+	settings atomic.Pointer[RuntimeSettings]
+	limiter  *rateLimiter
+}
+
+func NewRuntime(s RuntimeSettings) *Runtime {
+	rt := &Runtime{limiter: newRateLimiter()}
+	rt.settings.Store(&s)
+	return rt
+}
+
+func (rt *Runtime) Settings() RuntimeSettings {
+	return *rt.settings.Load()
+}
+
+func (rt *Runtime) Apply(s RuntimeSettings) {
+	old := rt.settings.Swap(&s)
+	if old.RateLimit != s.RateLimit || old.RateBurst != s.RateBurst {
+		rt.limiter.reset()
+	}
+}
+
+// Guard отсекает заблокированных клиентов (403), превысивших лимит запросов (429)
+// и ограничивает размер тела. Клиент определяется по адресу собеседника, а X-Real-IP
+// учитывается только от доверенных прокси (WithClientIP).
+func (rt *Runtime) Guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := rt.settings.Load()
+		ip := clientIP(r)
+
+		if containsIP(s.BlockedClients, ip) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if s.RateLimit > 0 {
+			if ok, wait := rt.limiter.allow(ip, s.RateLimit, s.RateBurst); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		}
+		if s.MaxBodyBytes > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, s.MaxBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rt *Runtime) CompressGzip(next http.Handler) http.Handler {
+	return compressGzip(next, func() (bool, int) {
+		s := rt.settings.Load()
+		return s.GzipEnabled, s.GzipLevel
+	})
+}
+
+func containsIP(nets []*net.IPNet, addr string) bool {
+	if len(nets) == 0 {
+		return false
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// readErrorStatus 413 для слишком большого тела, иначе 400.
+func readErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntimeReload(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	svc := shortener.New(inmemory.NewRepo(), baseURL)
+	rt := NewRuntime(DefaultRuntimeSettings())
+
+	_, blockedNet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	var reloadErr error
+	reload := func(context.Context) ([]string, error) {
+		if reloadErr != nil {
+			return nil, reloadErr
+		}
+		rt.Apply(RuntimeSettings{
+			GzipEnabled:    false,
+			MaxBodyBytes:   64,
+			RateLimit:      1,
+			RateBurst:      2,
+			BlockedClients: []*net.IPNet{blockedNet},
+		})
+		svc.SetBlockedDomains([]string{"evil.com"})
+		return []string{"Server"}, nil
+	}
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")),
+		WithRuntime(rt), WithAdmin("secret", reload))
+
+	do := func(ip, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(contentTypeKey, applicationJSONValue)
+		req.Header.Set(acceptEncodingKey, "gzip")
+		req.RemoteAddr = ip + ":5000"
+		if token != "" {
+			req.Header.Set(adminTokenKey, token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// до перезагрузки: сжатие включено, лимитов нет
+	rr := do("10.1.1.1", "/api/shorten", "", `{"url":"https://evil.com/a"}`)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	require.Equal(t, "gzip", rr.Header().Get(contentEncodingKey))
+
+	require.Equal(t, http.StatusUnauthorized, do("1.1.1.1", "/api/admin/reload", "wrong", "").Code)
+
+	reloadErr = errors.New("invalid gzip level 0")
+	rr = do("1.1.1.1", "/api/admin/reload", "secret", "")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	require.JSONEq(t, `{"error":"invalid gzip level 0"}`, readBody(t, rr.Result()))
+	require.True(t, rt.Settings().GzipEnabled)
+
+	reloadErr = nil
+	rr = do("1.1.1.1", "/api/admin/reload", "secret", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"ignored":["Server"]}`, readBody(t, rr.Result()))
+
+	tests := []struct {
+		name     string
+		ip       string
+		body     string
+		wantCode int
+	}{
+		{name: "blocked client", ip: "10.1.1.1", body: `{"url":"https://ok.ru"}`, wantCode: http.StatusForbidden},
+		{name: "blocked domain", ip: "2.2.2.2", body: `{"url":"https://www.evil.com/b"}`, wantCode: http.StatusBadRequest},
+		{name: "body too large", ip: "3.3.3.3", body: `{"url":"https://ok.ru/` + strings.Repeat("a", 64) + `"}`, wantCode: http.StatusRequestEntityTooLarge},
+		{name: "allowed", ip: "4.4.4.4", body: `{"url":"https://ok.ru"}`, wantCode: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := do(tt.ip, "/api/shorten", "", tt.body)
+			require.Equal(t, tt.wantCode, rr.Code)
+			require.Empty(t, rr.Header().Get(contentEncodingKey))
+		})
+	}
+
+	t.Run("rate limit", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			require.Equal(t, http.StatusConflict, do("5.5.5.5", "/api/shorten", "", `{"url":"https://ok.ru"}`).Code)
+		}
+		rr := do("5.5.5.5", "/api/shorten", "", `{"url":"https://ok.ru"}`)
+		require.Equal(t, http.StatusTooManyRequests, rr.Code)
+		require.Equal(t, "1", rr.Header().Get("Retry-After"))
+		// лимит считается на каждый адрес отдельно
+		require.Equal(t, http.StatusConflict, do("6.6.6.6", "/api/shorten", "", `{"url":"https://ok.ru"}`).Code)
+	})
+
+	t.Run("spoofed X-Real-IP", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://ok.ru"}`))
+		req.Header.Set(contentTypeKey, applicationJSONValue)
+		req.RemoteAddr = "10.1.1.1:5000"
+		req.Header.Set(realIPKey, "7.7.7.7")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		// заголовок от недоверенного собеседника не выводит из блок-листа
+		require.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.allow("a", 2, 1)
+	require.True(t, ok)
+	ok, wait := l.allow("a", 2, 1)
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	now = now.Add(wait)
+	ok, _ = l.allow("a", 2, 1)
+	require.True(t, ok)
+
+	// давно молчавшие адреса выкидываются при очередной чистке
+	now = now.Add(2 * rateSweepInterval)
+	l.allow("b", 2, 1)
+	require.NotContains(t, l.buckets, "a")
+}
+
+func TestRateLimiterMaxBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+	l.maxBuckets = 3
+
+	// адреса всё время новые, а корзины не успевают наполниться
+	for i := range 10 {
+		l.allow(strconv.Itoa(i), 0.001, 1)
+		require.LessOrEqual(t, len(l.buckets), 3)
+	}
+	require.Contains(t, l.buckets, "9")
+}
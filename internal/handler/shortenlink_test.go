@@ -55,7 +55,7 @@ func TestShortenLinkHandler(t *testing.T) {
 					Times(1)
 
 				m.EXPECT().
-					Add(gomock.Any(), gomock.Any(), repo.URL("https://google.com")).
+					Add(gomock.Any(), gomock.Cond(func(rec repo.Record) bool { return rec.URL == "https://google.com" })).
 					Return(nil).
 					Times(1)
 			},
@@ -83,7 +83,7 @@ func TestShortenLinkHandler(t *testing.T) {
 					Times(1)
 
 				m.EXPECT().
-					Add(gomock.Any(), gomock.Any(), gomock.Any()).
+					Add(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			want: want{
@@ -105,7 +105,7 @@ func TestShortenLinkHandler(t *testing.T) {
 			body:        "https://google.com",
 			contentType: "application/json", // Неподдерживаемый Content-Type
 			setupMock: func(m *mock_repo.MockRepository) {
-				m.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
 				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
 			},
 			want: want{
@@ -126,7 +126,7 @@ func TestShortenLinkHandler(t *testing.T) {
 			body:        "",
 			contentType: "text/plain",
 			setupMock: func(m *mock_repo.MockRepository) {
-				m.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
 				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
 			},
 			want: want{
@@ -142,7 +142,7 @@ func TestShortenLinkHandler(t *testing.T) {
 			body:        "a,jshda\naslkjdgh7162//\\",
 			contentType: "text/plain",
 			setupMock: func(m *mock_repo.MockRepository) {
-				m.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().Add(gomock.Any(), gomock.Any()).Times(0)
 				m.EXPECT().Search(gomock.Any(), gomock.Any()).Times(0)
 			},
 			want: want{
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/go-chi/chi/v5"
+)
+
+func StatsHandler(svc *shortener.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		stats, err := svc.Stats(r.Context(), r.URL.Query().Get(domainParam), repo.ShortURL(id))
+		if errors.Is(err, repo.ErrNotFoundShortURL) {
+			http.NotFound(w, r)
+			return
+		}
+		if errors.Is(err, shortener.ErrUnknownDomain) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("stats error %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := json.Marshal(stats)
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("stats error %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(contentTypeKey, applicationJSONValue)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(resp)
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/model"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	repo := inmemory.NewRepo()
+	repo.Seed([]repository.Record{
+		{ID: 0, URL: "https://github.com", ShortURL: "AbCdE1"},
+		{ID: 1, URL: "https://google.com", ShortURL: "ZxYwV2"},
+	})
+	clicks := inmemory.NewClickRepo()
+	recorder := shortener.NewClickRecorder(clicks, 100, 10, 10*time.Millisecond)
+	go recorder.Run()
+
+	svc := shortener.New(repo, baseURL, shortener.WithClicks(clicks, recorder))
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")))
+
+	do := func(path, ip, ua string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ip != "" {
+			req.RemoteAddr = ip + ":5000"
+		}
+		req.Header.Set("User-Agent", ua)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// три перехода от двух посетителей и один по другой ссылке
+	require.Equal(t, http.StatusTemporaryRedirect, do("/AbCdE1", "10.0.0.1", "curl").Code)
+	require.Equal(t, http.StatusTemporaryRedirect, do("/AbCdE1", "10.0.0.1", "curl").Code)
+	require.Equal(t, http.StatusTemporaryRedirect, do("/AbCdE1", "10.0.0.2", "firefox").Code)
+	require.Equal(t, http.StatusTemporaryRedirect, do("/ZxYwV2", "10.0.0.1", "curl").Code)
+	// несуществующая ссылка не считается
+	require.Equal(t, http.StatusNotFound, do("/nope00", "10.0.0.1", "curl").Code)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, recorder.Close(ctx))
+
+	rr := do("/api/urls/AbCdE1/stats", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, applicationJSONValue, rr.Header().Get(contentTypeKey))
+
+	var got model.ResponseStats
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	require.Equal(t, baseURL+"/AbCdE1", got.ShortURL)
+	require.EqualValues(t, 3, got.TotalClicks)
+	require.EqualValues(t, 2, got.UniqueVisitors)
+	require.Len(t, got.Daily, 1)
+	require.Equal(t, time.Now().UTC().Format(time.DateOnly), got.Daily[0].Date)
+	require.EqualValues(t, 3, got.Daily[0].Clicks)
+
+	rr = do("/api/urls/nope00/stats", "", "")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestStatsHandlerDomains(t *testing.T) {
+	baseURL := "http://localhost:8080"
+
+	repo := inmemory.NewRepo()
+	repo.Seed([]repository.Record{
+		{ID: 0, URL: "https://github.com", ShortURL: "promo"},
+		{ID: 1, URL: "https://google.com", ShortURL: "promo", Domain: "a.example"},
+		{ID: 2, URL: "https://yandex.ru", ShortURL: "only", Domain: "a.example"},
+	})
+	clicks := inmemory.NewClickRepo()
+	recorder := shortener.NewClickRecorder(clicks, 100, 10, 10*time.Millisecond)
+	go recorder.Run()
+
+	svc := shortener.New(repo, baseURL, shortener.WithClicks(clicks, recorder), shortener.WithDomains([]string{"a.example"}))
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")))
+
+	do := func(host, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// один код в двух доменах — две ссылки со своими счётчиками
+	require.Equal(t, http.StatusTemporaryRedirect, do("a.example", "/promo").Code)
+	require.Equal(t, http.StatusTemporaryRedirect, do("a.example", "/promo").Code)
+	require.Equal(t, http.StatusTemporaryRedirect, do("localhost:8080", "/promo").Code)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, recorder.Close(ctx))
+
+	stats := func(path string) model.ResponseStats {
+		rr := do("localhost:8080", path)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var got model.ResponseStats
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		return got
+	}
+	got := stats("/api/urls/promo/stats")
+	require.Equal(t, baseURL+"/promo", got.ShortURL)
+	require.EqualValues(t, 1, got.TotalClicks)
+	got = stats("/api/urls/promo/stats?domain=a.example")
+	require.Equal(t, "http://a.example/promo", got.ShortURL)
+	require.EqualValues(t, 2, got.TotalClicks)
+
+	// код из другого домена в основном не находится
+	require.Equal(t, http.StatusNotFound, do("localhost:8080", "/api/urls/only/stats").Code)
+	require.Equal(t, http.StatusBadRequest, do("localhost:8080", "/api/urls/promo/stats?domain=c.example").Code)
+}
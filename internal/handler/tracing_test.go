@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	l "github.com/IvanOplesnin/url-shortener/internal/logger"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+
+	var logs bytes.Buffer
+	prevOut := l.Log.Out
+	l.Log.SetOutput(&logs)
+	t.Cleanup(func() { l.Log.SetOutput(prevOut) })
+
+	baseURL := "http://localhost:8080/"
+	svc := shortener.New(inmemory.NewRepo(), baseURL)
+	mux := InitHandlers(svc, baseURL, nil, auth.NewSigner([]byte("test")))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://practicum.yandex.ru"))
+	req.Header.Set(contentTypeKey, textPlainValue)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	spans := rec.Ended()
+	byName := make(map[string]sdktrace.ReadOnlySpan, len(spans))
+	for _, s := range spans {
+		byName[s.Name()] = s
+	}
+	require.Contains(t, byName, "POST /")
+	require.Contains(t, byName, "Service.Shorten")
+	require.Contains(t, byName, "GET /{id}")
+	require.Contains(t, byName, "Service.ResolveHost")
+
+	server := byName["POST /"]
+	require.Equal(t, traceID, server.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	require.Equal(t, server.SpanContext().SpanID(), byName["Service.Shorten"].Parent().SpanID())
+
+	// без traceparent начинается новая трасса; ненайденная ссылка — ответ клиенту, а не сбой
+	require.NotEqual(t, traceID, byName["GET /{id}"].SpanContext().TraceID().String())
+	resolve := byName["Service.ResolveHost"]
+	require.Equal(t, "Unset", resolve.Status().Code.String())
+	require.Empty(t, resolve.Events())
+	require.Contains(t, resolve.Attributes(), attribute.String("shortener.outcome", "not found shortURL"))
+
+	require.Contains(t, logs.String(), "trace_id="+traceID)
+	require.Contains(t, logs.String(), "span_id="+server.SpanContext().SpanID().String())
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+)
+
+func UserURLsHandler(svc *shortener.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok || user.IsNew {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		urls, err := svc.UserURLs(r.Context(), user.ID)
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("user urls error %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(urls) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		resp, err := json.Marshal(urls)
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("user urls error %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(contentTypeKey, applicationJSONValue)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(resp)
+	}
+}
+
+func DeleteUserURLsHandler(svc *shortener.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(contentTypeKey) != applicationJSONValue {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok || user.IsNew {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("delete user urls error %s", err)
+			w.WriteHeader(readErrorStatus(err))
+			return
+		}
+		var shorts []repository.ShortURL
+		if err := json.Unmarshal(body, &shorts); err != nil {
+			logger.FromContext(r.Context()).Errorf("delete user urls error %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := svc.DeleteUserURLs(r.Context(), user.ID, r.URL.Query().Get(domainParam), shorts); err != nil {
+			logger.FromContext(r.Context()).Errorf("delete user urls error %s", err)
+			switch {
+			case errors.Is(err, shortener.ErrUnknownDomain):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, shortener.ErrDeleterClosed),
+				errors.Is(err, context.DeadlineExceeded),
+				errors.Is(err, context.Canceled):
+				// очередь удалений переполнена или сервер останавливается
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/model"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/IvanOplesnin/url-shortener/internal/service/shortener"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserURLsHandler(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	signer := auth.NewSigner([]byte("test"))
+
+	type want struct {
+		statusCode int
+		newCookie  bool
+		bodyCheck  func(t *testing.T, body []byte)
+	}
+
+	tests := []struct {
+		name   string
+		cookie string
+		seed   []repository.Record
+		want   want
+	}{
+		{
+			name:   "no cookie -> unauthorized and new cookie",
+			cookie: "",
+			want: want{
+				statusCode: http.StatusUnauthorized,
+				newCookie:  true,
+			},
+		},
+		{
+			name:   "bad signature -> unauthorized",
+			cookie: "user-1.AAAA",
+			want: want{
+				statusCode: http.StatusUnauthorized,
+				newCookie:  true,
+			},
+		},
+		{
+			name:   "valid cookie without urls -> no content",
+			cookie: signer.Sign("user-1"),
+			seed: []repository.Record{
+				{ID: 0, URL: "https://github.com", ShortURL: "AbCdE1", UserID: "user-2"},
+			},
+			want: want{
+				statusCode: http.StatusNoContent,
+			},
+		},
+		{
+			name:   "valid cookie -> only own urls",
+			cookie: signer.Sign("user-1"),
+			seed: []repository.Record{
+				{ID: 0, URL: "https://github.com", ShortURL: "AbCdE1", UserID: "user-1"},
+				{ID: 1, URL: "https://google.com", ShortURL: "ZxYwV2", UserID: "user-2"},
+				{ID: 2, URL: "https://ya.ru", ShortURL: "QwErT3", UserID: "user-1"},
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				bodyCheck: func(t *testing.T, body []byte) {
+					t.Helper()
+					var got []model.ResponseUserURL
+					require.NoError(t, json.Unmarshal(body, &got))
+					require.Equal(t, []model.ResponseUserURL{
+						{ShortURL: baseURL + "/AbCdE1", OriginalURL: "https://github.com"},
+						{ShortURL: baseURL + "/QwErT3", OriginalURL: "https://ya.ru"},
+					}, got)
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := inmemory.NewRepo()
+			repo.Seed(tt.seed)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: authCookieName, Value: tt.cookie})
+			}
+			rr := httptest.NewRecorder()
+
+			mux := InitHandlers(shortener.New(repo, baseURL), baseURL, nil, signer)
+			mux.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.want.statusCode, rr.Code)
+
+			var issued *http.Cookie
+			for _, c := range rr.Result().Cookies() {
+				if c.Name == authCookieName {
+					issued = c
+				}
+			}
+			if tt.want.newCookie {
+				require.NotNil(t, issued)
+				_, err := signer.Verify(issued.Value)
+				require.NoError(t, err)
+			} else {
+				require.Nil(t, issued)
+			}
+
+			if tt.want.bodyCheck != nil {
+				tt.want.bodyCheck(t, rr.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestShortenStoresUserID(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	repo := inmemory.NewRepo()
+	svc := shortener.New(repo, baseURL)
+
+	ctx := auth.WithUser(context.Background(), auth.User{ID: "user-1"})
+	res, err := svc.Shorten(ctx, "https://github.com", shortener.ShortenParams{})
+	require.NoError(t, err)
+
+	got, err := repo.GetByUser(context.Background(), "user-1")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, res.Short, got[0].ShortURL)
+}
+
+func TestDeleteUserURLsHandler(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	signer := auth.NewSigner([]byte("test"))
+
+	repo := inmemory.NewRepo()
+	repo.Seed([]repository.Record{
+		{ID: 0, URL: "https://github.com", ShortURL: "AbCdE1", UserID: "user-1"},
+		{ID: 1, URL: "https://google.com", ShortURL: "ZxYwV2", UserID: "user-2"},
+	})
+	deleter := shortener.NewDeleter(repo, 10, 10*time.Millisecond)
+	go deleter.Run()
+
+	mux := InitHandlers(shortener.New(repo, baseURL, shortener.WithDeleter(deleter)), baseURL, nil, signer)
+
+	do := func(method, path, body, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(contentTypeKey, applicationJSONValue)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: authCookieName, Value: cookie})
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodDelete, "/api/user/urls", `["AbCdE1","ZxYwV2"]`, "")
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = do(http.MethodDelete, "/api/user/urls", `not json`, signer.Sign("user-1"))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = do(http.MethodDelete, "/api/user/urls", `["AbCdE1","ZxYwV2"]`, signer.Sign("user-1"))
+	require.Equal(t, http.StatusAccepted, rr.Code)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, deleter.Close(ctx))
+
+	// своя ссылка удалена, чужая осталась
+	rr = do(http.MethodGet, "/AbCdE1", "", "")
+	require.Equal(t, http.StatusGone, rr.Code)
+	rr = do(http.MethodGet, "/ZxYwV2", "", "")
+	require.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+
+	// удалённый URL сокращается заново под новым кодом
+	rr = do(http.MethodPost, "/api/shorten", `{"url":"https://github.com"}`, signer.Sign("user-1"))
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var resp model.ResponseBody
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.NotContains(t, resp.Result, "AbCdE1")
+	rr = do(http.MethodPost, "/api/shorten", `{"url":"https://github.com"}`, signer.Sign("user-1"))
+	require.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestDeleteUserURLsQueueFull(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	signer := auth.NewSigner([]byte("test"))
+
+	repo := inmemory.NewRepo()
+	// Run не запущен, и очередь на один код сразу забивается
+	deleter := shortener.NewDeleter(repo, 1, time.Minute)
+	mux := InitHandlers(shortener.New(repo, baseURL, shortener.WithDeleter(deleter)), baseURL, nil, signer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequestWithContext(ctx, http.MethodDelete, "/api/user/urls", strings.NewReader(`["a","b"]`))
+	req.Header.Set(contentTypeKey, applicationJSONValue)
+	req.AddCookie(&http.Cookie{Name: authCookieName, Value: signer.Sign("user-1")})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	go deleter.Run()
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), time.Second)
+	defer closeCancel()
+	require.NoError(t, deleter.Close(closeCtx))
+}
+
+func TestDeleteUserURLsDomain(t *testing.T) {
+	baseURL := "http://localhost:8080"
+	signer := auth.NewSigner([]byte("test"))
+
+	repo := inmemory.NewRepo()
+	repo.Seed([]repository.Record{
+		{ID: 0, URL: "https://github.com", ShortURL: "promo", UserID: "user-1"},
+		{ID: 1, URL: "https://google.com", ShortURL: "promo", UserID: "user-1", Domain: "a.example"},
+	})
+	svc := shortener.New(repo, baseURL, shortener.WithDomains([]string{"a.example"}))
+	mux := InitHandlers(svc, baseURL, nil, signer)
+
+	do := func(method, host, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Host = host
+		req.Header.Set(contentTypeKey, applicationJSONValue)
+		req.AddCookie(&http.Cookie{Name: authCookieName, Value: signer.Sign("user-1")})
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodDelete, "localhost:8080", "/api/user/urls?domain=c.example", `["promo"]`)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// удаляется код только в указанном домене
+	rr = do(http.MethodDelete, "localhost:8080", "/api/user/urls?domain=a.example", `["promo"]`)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	require.Equal(t, http.StatusGone, do(http.MethodGet, "a.example", "/promo", "").Code)
+	require.Equal(t, http.StatusTemporaryRedirect, do(http.MethodGet, "localhost:8080", "/promo", "").Code)
+}
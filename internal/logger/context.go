@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDField поле лога с id запроса.
+const RequestIDField = "request_id"
+
+type ctxKey struct{}
+
+type requestIDKey struct{}
+
+// WithFields кладёт в ctx логгер с fields поверх уже лежащих там полей.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return context.WithValue(ctx, ctxKey{}, FromContext(ctx).WithFields(fields))
+}
+
+// FromContext логгер запроса с его полями; вне запроса — глобальный Log.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if e, ok := ctx.Value(ctxKey{}).(*logrus.Entry); ok {
+		return e
+	}
+	return logrus.NewEntry(Log)
+}
+
+// WithRequestID запоминает id запроса и добавляет его в поля логгера из ctx.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return WithFields(ctx, logrus.Fields{RequestIDField: id})
+}
+
+// RequestID id текущего запроса или пустая строка.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID случайный id для запроса, пришедшего без своего.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read не возвращает ошибок с Go 1.24
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -25,18 +25,30 @@ func SetupLogger(level string, format Formatter) error {
 	if err != nil {
 		return fmt.Errorf("%s fail parse level string %s: %w", msg, level, err)
 	}
-
-	Log.SetLevel(logLevel)
+	// формат проверяем до смены уровня, чтобы при ошибке не применить половину настроек
 	formatter, err := getFormatter(format)
 	if err != nil {
 		return fmt.Errorf("%s fail get formatter: %w", msg, err)
 	}
+
+	Log.SetLevel(logLevel)
 	Log.SetFormatter(formatter)
 	Log.SetOutput(os.Stdout)
 	
 	return nil
 }
 
+// Check проверяет уровень и формат, ничего не меняя в логгере.
+func Check(level string, format Formatter) error {
+	if _, err := logrus.ParseLevel(level); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	if _, err := getFormatter(format); err != nil {
+		return fmt.Errorf("invalid log format: %w", err)
+	}
+	return nil
+}
+
 func getFormatter(format Formatter) (logrus.Formatter, error) {
 	form := Formatter(strings.ToLower(string(format)))
 
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TrackGenerator оборачивает gen, чтобы считать попытки вставки кода в циклах
+// сервиса. Исходы по-прежнему доходят до gen, если он сам их учитывает, а Stats
+// остаётся доступен, если gen его реализует.
+func (m *Metrics) TrackGenerator(gen usvc.CodeGenerator) usvc.CodeGenerator {
+	tracker, _ := gen.(usvc.CollisionTracker)
+	t := &trackedGenerator{CodeGenerator: gen, tracker: tracker, m: m}
+	if r, ok := gen.(usvc.StatsReporter); ok {
+		return &reportingGenerator{trackedGenerator: t, r: r}
+	}
+	return t
+}
+
+type trackedGenerator struct {
+	usvc.CodeGenerator
+	tracker usvc.CollisionTracker
+	m       *Metrics
+}
+
+func (g *trackedGenerator) Observe(collided bool, attempt int) {
+	outcome := "inserted"
+	if collided {
+		outcome = "collision"
+	}
+	g.m.codeAttempts.WithLabelValues(outcome).Inc()
+	if attempt > 0 {
+		g.m.codeRetries.Inc()
+	}
+	if g.tracker != nil {
+		g.tracker.Observe(collided, attempt)
+	}
+}
+
+type reportingGenerator struct {
+	*trackedGenerator
+	r usvc.StatsReporter
+}
+
+func (g *reportingGenerator) Stats() usvc.GeneratorStats {
+	return g.r.Stats()
+}
+
+// RegisterGeneratorStats публикует счётчики генератора, если он их ведёт (см. Service.GeneratorStats).
+func (m *Metrics) RegisterGeneratorStats(stats func() (usvc.GeneratorStats, bool)) {
+	m.reg.MustRegister(&generatorCollector{stats: stats})
+}
+
+var (
+	genLengthDesc     = prometheus.NewDesc(namespace+"_codegen_length", "Current length of generated short codes.", nil, nil)
+	genGeneratedDesc  = prometheus.NewDesc(namespace+"_codegen_generated_total", "Short codes generated.", nil, nil)
+	genCollisionsDesc = prometheus.NewDesc(namespace+"_codegen_collisions_total", "Generated short codes that were already taken.", nil, nil)
+	genGrowsDesc      = prometheus.NewDesc(namespace+"_codegen_grows_total", "Times the short code length was increased.", nil, nil)
+)
+
+type generatorCollector struct {
+	stats func() (usvc.GeneratorStats, bool)
+}
+
+func (c *generatorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- genLengthDesc
+	ch <- genGeneratedDesc
+	ch <- genCollisionsDesc
+	ch <- genGrowsDesc
+}
+
+func (c *generatorCollector) Collect(ch chan<- prometheus.Metric) {
+	s, ok := c.stats()
+	if !ok {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(genLengthDesc, prometheus.GaugeValue, float64(s.Length))
+	ch <- prometheus.MustNewConstMetric(genGeneratedDesc, prometheus.CounterValue, float64(s.Generated))
+	ch <- prometheus.MustNewConstMetric(genCollisionsDesc, prometheus.CounterValue, float64(s.Collisions))
+	ch <- prometheus.MustNewConstMetric(genGrowsDesc, prometheus.CounterValue, float64(s.Grows))
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shortener"
+
+// Metrics метрики сервиса в собственном реестре; реализует handlers.HTTPObserver,
+// metered.Observer и persisted.Observer.
+type Metrics struct {
+	reg *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	repoDuration *prometheus.HistogramVec
+	repoErrors   *prometheus.CounterVec
+
+	codeAttempts *prometheus.CounterVec
+	codeRetries  prometheus.Counter
+
+	saveDuration prometheus.Histogram
+	saveSize     prometheus.Gauge
+
+	queueLength prometheus.Gauge
+	flushLag    prometheus.Histogram
+	flushErrors prometheus.Counter
+	queueFull   prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		reg: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "code"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repo_operation_duration_seconds",
+			Help:      "Repository operation latency.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"op"}),
+		repoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repo_operation_errors_total",
+			Help:      "Repository operations failed with unexpected errors; not found and conflicts are not counted.",
+		}, []string{"op"}),
+		codeAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "codegen_attempts_total",
+			Help:      "Attempts to insert a generated short code by outcome.",
+		}, []string{"outcome"}),
+		codeRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "codegen_retries_total",
+			Help:      "Attempts to insert a generated short code after a collision.",
+		}),
+		saveDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "filestore_save_duration_seconds",
+			Help:      "Duration of writing the storage snapshot file.",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}),
+		saveSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "filestore_size_bytes",
+			Help:      "Size of the last written storage snapshot file.",
+		}),
+		queueLength: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "filestore_queue_length",
+			Help:      "Changes waiting in the write-behind queue after the last flush.",
+		}),
+		flushLag: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "filestore_flush_lag_seconds",
+			Help:      "Age of the oldest change in a write-behind flush.",
+			Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}),
+		flushErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "filestore_flush_errors_total",
+			Help:      "Failed write-behind flushes; changes are retried on the next tick.",
+		}),
+		queueFull: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "filestore_queue_full_total",
+			Help:      "Writes that waited for room in the full write-behind queue.",
+		}),
+	}
+	m.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.repoDuration, m.repoErrors,
+		m.codeAttempts, m.codeRetries,
+		m.saveDuration, m.saveSize,
+		m.queueLength, m.flushLag, m.flushErrors, m.queueFull,
+	)
+	return m
+}
+
+// Handler отдаёт метрики в формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.reg, promhttp.HandlerOpts{Registry: m.reg})
+}
+
+func (m *Metrics) ObserveHTTP(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+func (m *Metrics) ObserveRepo(op string, d time.Duration, err error) {
+	m.repoDuration.WithLabelValues(op).Observe(d.Seconds())
+	if err != nil && !expected(err) {
+		m.repoErrors.WithLabelValues(op).Inc()
+	}
+}
+
+// ObserveSave подходит для filestorage.WithSaveObserver.
+func (m *Metrics) ObserveSave(d time.Duration, size int64) {
+	m.saveDuration.Observe(d.Seconds())
+	m.saveSize.Set(float64(size))
+}
+
+func (m *Metrics) ObserveFlush(queued int, lag time.Duration, err error) {
+	m.queueLength.Set(float64(queued))
+	if err != nil {
+		m.flushErrors.Inc()
+		return
+	}
+	m.flushLag.Observe(lag.Seconds())
+}
+
+func (m *Metrics) ObserveQueueFull() {
+	m.queueFull.Inc()
+}
+
+// expected ошибки, которыми хранилище отвечает на обычные запросы.
+func expected(err error) bool {
+	return errors.Is(err, repo.ErrNotFoundURL) ||
+		errors.Is(err, repo.ErrNotFoundShortURL) ||
+		errors.Is(err, repo.ErrDeleted) ||
+		errors.Is(err, repo.ErrExpired) ||
+		errors.Is(err, repo.ErrAlreadyExists) ||
+		errors.Is(err, repo.ErrShortURLAlreadyExists)
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterPool публикует статистику пула соединений с БД.
+func (m *Metrics) RegisterPool(pool *pgxpool.Pool) {
+	m.reg.MustRegister(&poolCollector{pool: pool})
+}
+
+var (
+	poolTotalDesc    = prometheus.NewDesc(namespace+"_db_pool_total_conns", "Connections in the pool.", nil, nil)
+	poolIdleDesc     = prometheus.NewDesc(namespace+"_db_pool_idle_conns", "Idle connections in the pool.", nil, nil)
+	poolAcquiredDesc = prometheus.NewDesc(namespace+"_db_pool_acquired_conns", "Connections currently in use.", nil, nil)
+	poolMaxDesc      = prometheus.NewDesc(namespace+"_db_pool_max_conns", "Maximum size of the pool.", nil, nil)
+	poolAcquireDesc  = prometheus.NewDesc(namespace+"_db_pool_acquires_total", "Successful connection acquires.", nil, nil)
+	poolWaitDesc     = prometheus.NewDesc(namespace+"_db_pool_acquire_duration_seconds_total", "Total time spent acquiring connections.", nil, nil)
+	poolEmptyDesc    = prometheus.NewDesc(namespace+"_db_pool_empty_acquires_total", "Acquires that had to wait for a connection.", nil, nil)
+	poolCanceledDesc = prometheus.NewDesc(namespace+"_db_pool_canceled_acquires_total", "Acquires canceled by context.", nil, nil)
+)
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{poolTotalDesc, poolIdleDesc, poolAcquiredDesc, poolMaxDesc, poolAcquireDesc, poolWaitDesc, poolEmptyDesc, poolCanceledDesc} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiredDesc, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDesc, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolWaitDesc, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolEmptyDesc, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledDesc, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
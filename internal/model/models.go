@@ -1,21 +1,65 @@
 package model
 
-import "github.com/IvanOplesnin/url-shortener/internal/repository"
+import (
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
 
 type RequestBody struct {
-	URL repository.URL `json:"url"`
+	URL         repository.URL      `json:"url"`
+	ExpiresAt   *time.Time          `json:"expires_at,omitempty"`
+	TTLSeconds  int64               `json:"ttl_seconds,omitempty"`
+	CustomAlias repository.ShortURL `json:"custom_alias,omitempty"`
+	Domain      string              `json:"domain,omitempty"`
 }
 
 type ResponseBody struct {
 	Result string `json:"result"`
 }
 
+type ResponseError struct {
+	Error string `json:"error"`
+}
+
 type RequestBatchBody struct {
-	CorrelationID string         `json:"correlation_id"`
-	OriginalURL   repository.URL `json:"original_url"`
+	CorrelationID string              `json:"correlation_id"`
+	OriginalURL   repository.URL      `json:"original_url"`
+	ExpiresAt     *time.Time          `json:"expires_at,omitempty"`
+	TTLSeconds    int64               `json:"ttl_seconds,omitempty"`
+	CustomAlias   repository.ShortURL `json:"custom_alias,omitempty"`
 }
 
 type ResponseBatchBody struct {
-	CorrelationID string              `json:"correlation_id"`
+	CorrelationID string `json:"correlation_id"`
 	ShortURL      string `json:"short_url"`
 }
+
+type ResponseUserURL struct {
+	ShortURL    string         `json:"short_url"`
+	OriginalURL repository.URL `json:"original_url"`
+}
+
+type ResponseStats struct {
+	ShortURL       string                `json:"short_url"`
+	TotalClicks    int64                 `json:"total_clicks"`
+	UniqueVisitors int64                 `json:"unique_visitors"`
+	Daily          []ResponseDailyClicks `json:"daily"`
+}
+
+type ResponseDailyClicks struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
+
+// ResponseInternalStats счётчики для /api/internal/stats.
+type ResponseInternalStats struct {
+	URLs  int64 `json:"urls"`
+	Users int64 `json:"users"`
+}
+
+// ResponseReload результат перезагрузки конфига; Ignored — изменённые настройки,
+// которым нужен перезапуск.
+type ResponseReload struct {
+	Ignored []string `json:"ignored"`
+}
@@ -0,0 +1,70 @@
+package bolt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// DSNPrefix форма DATABASE_DSN для bbolt: bolt://data.db или bolt:///var/lib/shortener.db.
+const DSNPrefix = "bolt://"
+
+var (
+	// shortBucket domain\x00short → запись в JSON; его счётчик выдаёт Record.ID
+	shortBucket = []byte("short")
+	// urlBucket domain\x00url → short; его счётчик отдаёт NextVal
+	urlBucket = []byte("url")
+	// userBucket user\x00domain\x00short → пусто: ссылки пользователя без обхода всех записей
+	userBucket = []byte("user")
+	// expiryBucket срок + domain\x00short → пусто: записи по порядку истечения
+	expiryBucket = []byte("expiry")
+	// countBucket счётчики неудалённых ссылок для CountURLs и CountUsers
+	countBucket = []byte("count")
+
+	buckets = [][]byte{shortBucket, urlBucket, userBucket, expiryBucket, countBucket}
+)
+
+// PathFromDSN путь к файлу базы, если dsn в форме bolt://path.
+func PathFromDSN(dsn string) (string, bool) {
+	path, ok := strings.CutPrefix(dsn, DSNPrefix)
+	if !ok || path == "" {
+		return "", false
+	}
+	return path, true
+}
+
+// Open открывает файл базы и создаёт бакеты. Файл держит эксклюзивная блокировка,
+// так что второй процесс на том же файле упадёт по таймауту, а не испортит данные.
+func Open(path string) (*bbolt.DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("bolt mkdir: %w", err)
+		}
+	}
+	db, err := bbolt.Open(path, 0o644, &bbolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("bolt open: %w", err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		// в базе без счётчиков индексов ещё нет: строим их один раз по записям
+		indexed := tx.Bucket(countBucket) != nil
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if indexed {
+			return nil
+		}
+		return reindex(tx)
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("bolt create buckets: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,155 @@
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var (
+	// liveKey и usersKey в countBucket: число неудалённых ссылок и пользователей, у которых они есть
+	liveKey  = []byte("live")
+	usersKey = []byte("users")
+)
+
+// userKey ключ userBucket: пользователь, затем ключ записи в shortBucket.
+func userKey(rec repo.Record) []byte {
+	return append([]byte(rec.UserID+"\x00"), key(rec.Domain, string(rec.ShortURL))...)
+}
+
+// userCountKey счётчик неудалённых ссылок пользователя в countBucket.
+func userCountKey(userID string) []byte {
+	return []byte("user\x00" + userID)
+}
+
+// expiryKey срок в наносекундах big-endian, чтобы курсор шёл от ранних к поздним,
+// затем ключ записи в shortBucket.
+func expiryKey(at time.Time, k []byte) []byte {
+	b := make([]byte, 8, 8+len(k))
+	binary.BigEndian.PutUint64(b, uint64(max(at.UnixNano(), 0)))
+	return append(b, k...)
+}
+
+// index заносит запись в индексы и счётчики; unindex убирает ту же запись оттуда.
+func index(tx *bbolt.Tx, rec repo.Record) error {
+	if rec.UserID != "" {
+		if err := tx.Bucket(userBucket).Put(userKey(rec), []byte{}); err != nil {
+			return err
+		}
+	}
+	if rec.ExpiresAt != nil {
+		k := expiryKey(*rec.ExpiresAt, key(rec.Domain, string(rec.ShortURL)))
+		if err := tx.Bucket(expiryBucket).Put(k, []byte{}); err != nil {
+			return err
+		}
+	}
+	if rec.Deleted {
+		return nil
+	}
+	return countLive(tx, rec.UserID, 1)
+}
+
+func unindex(tx *bbolt.Tx, rec repo.Record) error {
+	if rec.UserID != "" {
+		if err := tx.Bucket(userBucket).Delete(userKey(rec)); err != nil {
+			return err
+		}
+	}
+	if rec.ExpiresAt != nil {
+		k := expiryKey(*rec.ExpiresAt, key(rec.Domain, string(rec.ShortURL)))
+		if err := tx.Bucket(expiryBucket).Delete(k); err != nil {
+			return err
+		}
+	}
+	if rec.Deleted {
+		return nil
+	}
+	return countLive(tx, rec.UserID, -1)
+}
+
+// countLive сдвигает на delta общий счётчик неудалённых ссылок и счётчик пользователя,
+// а число пользователей меняет, когда у него появляется первая или пропадает последняя.
+func countLive(tx *bbolt.Tx, userID string, delta int64) error {
+	b := tx.Bucket(countBucket)
+	if err := setCount(b, liveKey, getCount(b, liveKey)+delta); err != nil {
+		return err
+	}
+	if userID == "" {
+		return nil
+	}
+	before := getCount(b, userCountKey(userID))
+	after := before + delta
+	if err := setCount(b, userCountKey(userID), after); err != nil {
+		return err
+	}
+	switch {
+	case before <= 0 && after > 0:
+		return setCount(b, usersKey, getCount(b, usersKey)+1)
+	case before > 0 && after <= 0:
+		return setCount(b, usersKey, getCount(b, usersKey)-1)
+	}
+	return nil
+}
+
+func getCount(b *bbolt.Bucket, k []byte) int64 {
+	v := b.Get(k)
+	if len(v) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(v))
+}
+
+func setCount(b *bbolt.Bucket, k []byte, n int64) error {
+	if n <= 0 {
+		return b.Delete(k)
+	}
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, uint64(n))
+	return b.Put(k, v)
+}
+
+// expiredBy обходит по индексу сроков записи, истёкшие к now; менять бакеты внутри fn нельзя.
+func expiredBy(tx *bbolt.Tx, now time.Time, fn func(rec repo.Record) error) error {
+	limit := expiryKey(now, nil)
+	short := tx.Bucket(shortBucket)
+	c := tx.Bucket(expiryBucket).Cursor()
+	for k, _ := c.First(); k != nil && bytes.Compare(k[:8], limit) <= 0; k, _ = c.Next() {
+		v := short.Get(k[8:])
+		if v == nil {
+			continue
+		}
+		var rec repo.Record
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return fmt.Errorf("bolt decode %q: %w", k[8:], err)
+		}
+		if !rec.Expired(now) {
+			continue
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reindex строит индексы и счётчики заново по shortBucket.
+func reindex(tx *bbolt.Tx) error {
+	for _, name := range [][]byte{userBucket, expiryBucket, countBucket} {
+		if tx.Bucket(name) != nil {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+	return forEach(tx, func(_ []byte, rec repo.Record) error {
+		return index(tx, rec)
+	})
+}
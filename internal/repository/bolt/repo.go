@@ -0,0 +1,555 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Repo хранилище на bbolt: каждое изменение — транзакция с fsync, в памяти держатся
+// только страницы, которые читают. Копии из InDomain делят с ним базу и отличаются доменом.
+type Repo struct {
+	db *bbolt.DB
+	// tx есть у копии внутри InTx
+	tx     *bbolt.Tx
+	domain string
+	// w открытая InTx транзакция, общая для всех копий
+	w *writer
+}
+
+// writer bbolt.Tx нельзя трогать из нескольких горутин, поэтому обращения к открытой
+// транзакции идут под mu.
+type writer struct {
+	// run пускает в InTx по одному: иначе вторая ждала бы базу, держа mu
+	run sync.Mutex
+	mu  sync.Mutex
+	tx  *bbolt.Tx
+}
+
+func NewRepo(db *bbolt.DB) *Repo {
+	return &Repo{db: db, w: &writer{}}
+}
+
+// Ping проверяет, что база открыта: сети у bbolt нет, падать можно только на закрытом файле.
+func (r *Repo) Ping(_ context.Context) error {
+	return r.db.View(func(*bbolt.Tx) error { return nil })
+}
+
+// key ключ бакета: домен и значение через нулевой байт, которого нет ни в домене, ни в коде.
+func key(domain, v string) []byte {
+	return []byte(domain + "\x00" + v)
+}
+
+// InDomain то же хранилище, но ссылки ищутся и создаются в domain.
+func (r *Repo) InDomain(domain string) (repo.Repository, error) {
+	scoped := *r
+	scoped.domain = domain
+	return &scoped, nil
+}
+
+func (r *Repo) Get(_ context.Context, shortURL repo.ShortURL) (repo.URL, error) {
+	var rec repo.Record
+	err := r.view(func(tx *bbolt.Tx) error {
+		var err error
+		rec, err = getRecord(tx, r.domain, shortURL)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if rec.Deleted {
+		return "", repo.ErrDeleted
+	}
+	if rec.Expired(time.Now()) {
+		return "", repo.ErrExpired
+	}
+	return rec.URL, nil
+}
+
+func (r *Repo) Search(_ context.Context, url repo.URL) (repo.ShortURL, error) {
+	var short repo.ShortURL
+	err := r.view(func(tx *bbolt.Tx) error {
+		rec, ok, err := live(tx, r.domain, string(url), time.Now())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return repo.ErrNotFoundURL
+		}
+		short = rec.ShortURL
+		return nil
+	})
+	return short, err
+}
+
+func (r *Repo) Add(_ context.Context, rec repo.Record) error {
+	rec.Domain = r.domain
+	return r.update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(shortBucket).Get(key(rec.Domain, string(rec.ShortURL))) != nil {
+			return fmt.Errorf("%w: %v", repo.ErrShortURLAlreadyExists, rec.ShortURL)
+		}
+		_, ok, err := live(tx, rec.Domain, string(rec.URL), time.Now())
+		if err != nil {
+			return err
+		}
+		if ok {
+			return fmt.Errorf("%w: %v", repo.ErrAlreadyExists, rec.URL)
+		}
+		_, err = insert(tx, rec)
+		return err
+	})
+}
+
+func (r *Repo) GetByURLs(_ context.Context, urls []string) ([]repo.Record, error) {
+	if len(urls) == 0 {
+		return []repo.Record{}, nil
+	}
+	now := time.Now()
+	out := make([]repo.Record, 0, len(urls))
+	err := r.view(func(tx *bbolt.Tx) error {
+		for _, u := range urls {
+			rec, ok, err := live(tx, r.domain, u, now)
+			if err != nil {
+				return err
+			}
+			if ok {
+				out = append(out, rec)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AddMany вставляет пакет одной транзакцией: занятые коды пропускаются, а занятый URL
+// откатывает весь пакет.
+func (r *Repo) AddMany(_ context.Context, records []repo.ArgAddMany) ([]repo.Record, error) {
+	if len(records) == 0 {
+		return []repo.Record{}, nil
+	}
+	now := time.Now()
+	var out []repo.Record
+	err := r.update(func(tx *bbolt.Tx) error {
+		out = make([]repo.Record, 0, len(records))
+		for _, rec := range records {
+			if tx.Bucket(shortBucket).Get(key(r.domain, string(rec.ShortURL))) != nil {
+				continue
+			}
+			_, ok, err := live(tx, r.domain, string(rec.URL), now)
+			if err != nil {
+				return err
+			}
+			if ok {
+				return fmt.Errorf("%w: %v", repo.ErrAlreadyExists, rec.URL)
+			}
+			stored, err := insert(tx, repo.Record{
+				URL:       rec.URL,
+				ShortURL:  rec.ShortURL,
+				UserID:    rec.UserID,
+				ExpiresAt: rec.ExpiresAt,
+				Domain:    r.domain,
+			})
+			if err != nil {
+				return err
+			}
+			out = append(out, stored)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetByUser читает ссылки пользователя по userBucket.
+func (r *Repo) GetByUser(_ context.Context, userID string) ([]repo.Record, error) {
+	now := time.Now()
+	out := make([]repo.Record, 0)
+	prefix := []byte(userID + "\x00")
+	err := r.view(func(tx *bbolt.Tx) error {
+		short := tx.Bucket(shortBucket)
+		c := tx.Bucket(userBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			v := short.Get(k[len(prefix):])
+			if v == nil {
+				continue
+			}
+			var rec repo.Record
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return fmt.Errorf("bolt decode %q: %w", k[len(prefix):], err)
+			}
+			if !rec.Deleted && !rec.Expired(now) {
+				out = append(out, rec)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// DeleteByUser удаляет ссылку только в домене запроса и только у её владельца.
+func (r *Repo) DeleteByUser(_ context.Context, reqs []repo.DeleteRequest) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	return r.update(func(tx *bbolt.Tx) error {
+		for _, req := range reqs {
+			rec, err := getRecord(tx, req.Domain, req.ShortURL)
+			if errors.Is(err, repo.ErrNotFoundShortURL) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if rec.UserID != req.UserID || rec.Deleted {
+				continue
+			}
+			rec.Deleted = true
+			if err := putRecord(tx, key(rec.Domain, string(rec.ShortURL)), rec); err != nil {
+				return err
+			}
+			if err := release(tx, rec); err != nil {
+				return err
+			}
+			if err := countLive(tx, rec.UserID, -1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PurgeExpired находит истёкшие записи по expiryBucket, не обходя остальные.
+func (r *Repo) PurgeExpired(_ context.Context, now time.Time) (int64, error) {
+	var n int64
+	err := r.update(func(tx *bbolt.Tx) error {
+		var expired []repo.Record
+		err := expiredBy(tx, now, func(rec repo.Record) error {
+			expired = append(expired, rec)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, rec := range expired {
+			if err := drop(tx, rec); err != nil {
+				return err
+			}
+		}
+		n = int64(len(expired))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// CountURLs считает живые ссылки во всех доменах: счётчик неудалённых за вычетом
+// истёкших, но ещё не вычищенных.
+func (r *Repo) CountURLs(_ context.Context) (int64, error) {
+	now := time.Now()
+	var n int64
+	err := r.view(func(tx *bbolt.Tx) error {
+		n = getCount(tx.Bucket(countBucket), liveKey)
+		return expiredBy(tx, now, func(rec repo.Record) error {
+			if !rec.Deleted {
+				n--
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// CountUsers считает пользователей, у которых есть хотя бы одна живая ссылка: из тех,
+// у кого есть неудалённые, вычитаются те, у кого все они уже истекли.
+func (r *Repo) CountUsers(_ context.Context) (int64, error) {
+	now := time.Now()
+	var n int64
+	err := r.view(func(tx *bbolt.Tx) error {
+		counts := tx.Bucket(countBucket)
+		n = getCount(counts, usersKey)
+		expired := make(map[string]int64)
+		err := expiredBy(tx, now, func(rec repo.Record) error {
+			if rec.UserID != "" && !rec.Deleted {
+				expired[rec.UserID]++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for userID, k := range expired {
+			if getCount(counts, userCountKey(userID)) <= k {
+				n--
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// NextVal во время InTx идёт через её транзакцию: генератор кодов зовут изнутри пакетной
+// вставки, а второй писатель ждал бы, пока эта же транзакция не отпустит базу.
+// Номер из откатившейся транзакции может выдаться снова; повтор ловит уникальность кода.
+func (r *Repo) NextVal(_ context.Context) (int64, error) {
+	next := func(tx *bbolt.Tx) (int64, error) {
+		v, err := tx.Bucket(urlBucket).NextSequence()
+		if err != nil {
+			return 0, fmt.Errorf("bolt error NextVal: %w", err)
+		}
+		return int64(v), nil
+	}
+
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	if r.w.tx != nil {
+		return next(r.w.tx)
+	}
+	var v int64
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		v, err = next(tx)
+		return err
+	})
+	return v, err
+}
+
+// InTx выполняет fn в одной транзакции bbolt: ошибка fn откатывает всё, что она записала.
+func (r *Repo) InTx(_ context.Context, fn func(r repo.Repository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	r.w.run.Lock()
+	defer r.w.run.Unlock()
+
+	r.w.mu.Lock()
+	tx, err := r.db.Begin(true)
+	if err != nil {
+		r.w.mu.Unlock()
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	r.w.tx = tx
+	r.w.mu.Unlock()
+
+	defer func() {
+		r.w.mu.Lock()
+		r.w.tx = nil
+		r.w.mu.Unlock()
+		// после Commit откатывать уже нечего
+		_ = tx.Rollback()
+	}()
+
+	txRepo := &Repo{db: r.db, tx: tx, domain: r.domain, w: r.w}
+	if err := fn(txRepo); err != nil {
+		return err
+	}
+
+	r.w.mu.Lock()
+	r.w.tx = nil
+	r.w.mu.Unlock()
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// Seed заменяет содержимое базы записями records; счётчики продолжают после наибольшего ID.
+func (r *Repo) Seed(records []repo.Record) {
+	err := r.update(func(tx *bbolt.Tx) error {
+		for _, name := range buckets {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		var nextID int
+		for _, rec := range records {
+			if err := put(tx, rec); err != nil {
+				return err
+			}
+			if rec.ID >= nextID {
+				nextID = rec.ID + 1
+			}
+		}
+		if err := tx.Bucket(shortBucket).SetSequence(uint64(nextID)); err != nil {
+			return err
+		}
+		// номеров больше, чем записей, раньше выдать не могли; пропуски не страшны
+		return tx.Bucket(urlBucket).SetSequence(uint64(nextID))
+	})
+	if err != nil {
+		logger.Log.Errorf("bolt seed: %s", err)
+	}
+}
+
+func (r *Repo) Snapshot() []repo.Record {
+	out := make([]repo.Record, 0)
+	err := r.view(func(tx *bbolt.Tx) error {
+		return forEach(tx, func(_ []byte, rec repo.Record) error {
+			out = append(out, rec)
+			return nil
+		})
+	})
+	if err != nil {
+		logger.Log.Errorf("bolt snapshot: %s", err)
+	}
+	return out
+}
+
+// Empty сообщает, что в базе ещё нет ни одной ссылки.
+func (r *Repo) Empty() (bool, error) {
+	var empty bool
+	err := r.view(func(tx *bbolt.Tx) error {
+		k, _ := tx.Bucket(shortBucket).Cursor().First()
+		empty = k == nil
+		return nil
+	})
+	return empty, err
+}
+
+// view и update выполняют fn в транзакции InTx, если это её копия, иначе в своей.
+func (r *Repo) view(fn func(tx *bbolt.Tx) error) error {
+	if r.tx != nil {
+		r.w.mu.Lock()
+		defer r.w.mu.Unlock()
+		return fn(r.tx)
+	}
+	return r.db.View(fn)
+}
+
+func (r *Repo) update(fn func(tx *bbolt.Tx) error) error {
+	if r.tx != nil {
+		r.w.mu.Lock()
+		defer r.w.mu.Unlock()
+		return fn(r.tx)
+	}
+	return r.db.Update(fn)
+}
+
+func getRecord(tx *bbolt.Tx, domain string, short repo.ShortURL) (repo.Record, error) {
+	v := tx.Bucket(shortBucket).Get(key(domain, string(short)))
+	if v == nil {
+		return repo.Record{}, repo.ErrNotFoundShortURL
+	}
+	var rec repo.Record
+	if err := json.Unmarshal(v, &rec); err != nil {
+		return repo.Record{}, fmt.Errorf("bolt decode %q: %w", short, err)
+	}
+	return rec, nil
+}
+
+// live запись, на которую сейчас ведёт URL; просроченная URL уже не держит,
+// и новая ссылка перебивает её в urlBucket.
+func live(tx *bbolt.Tx, domain, url string, now time.Time) (repo.Record, bool, error) {
+	short := tx.Bucket(urlBucket).Get(key(domain, url))
+	if short == nil {
+		return repo.Record{}, false, nil
+	}
+	rec, err := getRecord(tx, domain, repo.ShortURL(short))
+	if err != nil {
+		return repo.Record{}, false, err
+	}
+	return rec, !rec.Expired(now), nil
+}
+
+// insert выдаёт записи следующий ID и кладёт её в оба бакета.
+func insert(tx *bbolt.Tx, rec repo.Record) (repo.Record, error) {
+	id, err := tx.Bucket(shortBucket).NextSequence()
+	if err != nil {
+		return repo.Record{}, err
+	}
+	// счётчик бакета начинается с 1, а ID — с 0, как в памяти
+	rec.ID = int(id) - 1
+	return rec, put(tx, rec)
+}
+
+// put кладёт запись и её индексы; в urlBucket попадают только живые, чтобы удалённый
+// или просроченный URL можно было сократить заново.
+func put(tx *bbolt.Tx, rec repo.Record) error {
+	old, err := getRecord(tx, rec.Domain, rec.ShortURL)
+	switch {
+	case err == nil:
+		if err := unindex(tx, old); err != nil {
+			return err
+		}
+	case !errors.Is(err, repo.ErrNotFoundShortURL):
+		return err
+	}
+	if err := putRecord(tx, key(rec.Domain, string(rec.ShortURL)), rec); err != nil {
+		return err
+	}
+	if err := index(tx, rec); err != nil {
+		return err
+	}
+	if rec.Deleted || rec.Expired(time.Now()) {
+		return nil
+	}
+	return tx.Bucket(urlBucket).Put(key(rec.Domain, string(rec.URL)), []byte(rec.ShortURL))
+}
+
+func putRecord(tx *bbolt.Tx, k []byte, rec repo.Record) error {
+	v, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(shortBucket).Put(k, v)
+}
+
+// drop удаляет сохранённую запись rec вместе с индексами.
+func drop(tx *bbolt.Tx, rec repo.Record) error {
+	if err := tx.Bucket(shortBucket).Delete(key(rec.Domain, string(rec.ShortURL))); err != nil {
+		return err
+	}
+	if err := unindex(tx, rec); err != nil {
+		return err
+	}
+	return release(tx, rec)
+}
+
+// release освобождает URL, если он всё ещё ведёт на rec.
+func release(tx *bbolt.Tx, rec repo.Record) error {
+	k := key(rec.Domain, string(rec.URL))
+	if string(tx.Bucket(urlBucket).Get(k)) != string(rec.ShortURL) {
+		return nil
+	}
+	return tx.Bucket(urlBucket).Delete(k)
+}
+
+// forEach обходит все записи во всех доменах; менять бакет внутри fn нельзя.
+func forEach(tx *bbolt.Tx, fn func(k []byte, rec repo.Record) error) error {
+	return tx.Bucket(shortBucket).ForEach(func(k, v []byte) error {
+		var rec repo.Record
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return fmt.Errorf("bolt decode %q: %w", k, err)
+		}
+		return fn(k, rec)
+	})
+}
@@ -0,0 +1,340 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/stretchr/testify/require"
+	bbolt "go.etcd.io/bbolt"
+)
+
+func newTestRepo(t *testing.T) (*Repo, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.bolt")
+	db, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return NewRepo(db), path
+}
+
+func TestRepoAddGet(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepo(t)
+
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "a", UserID: "u1"}))
+	got, err := r.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, repo.URL("https://a.ru"), got)
+
+	short, err := r.Search(ctx, "https://a.ru")
+	require.NoError(t, err)
+	require.Equal(t, repo.ShortURL("a"), short)
+
+	require.ErrorIs(t, r.Add(ctx, repo.Record{URL: "https://b.ru", ShortURL: "a"}), repo.ErrShortURLAlreadyExists)
+	require.ErrorIs(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "b"}), repo.ErrAlreadyExists)
+
+	// в другом домене тот же код свободен
+	other, err := r.InDomain("x.example")
+	require.NoError(t, err)
+	require.NoError(t, other.Add(ctx, repo.Record{URL: "https://b.ru", ShortURL: "a"}))
+	got, err = other.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, repo.URL("https://b.ru"), got)
+
+	_, err = r.Get(ctx, "missing")
+	require.ErrorIs(t, err, repo.ErrNotFoundShortURL)
+	_, err = r.Search(ctx, "https://missing.ru")
+	require.ErrorIs(t, err, repo.ErrNotFoundURL)
+}
+
+func TestRepoInTx(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepo(t)
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "a"}))
+
+	err := r.InTx(ctx, func(tx repo.Repository) error {
+		// генератор кодов зовут изнутри транзакции
+		v, err := r.NextVal(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), v)
+
+		added, err := tx.(repo.BatchRepo).AddMany(ctx, []repo.ArgAddMany{
+			{URL: "https://b.ru", ShortURL: "b", UserID: "u1"},
+			// занятый код пропускается
+			{URL: "https://c.ru", ShortURL: "a", UserID: "u1"},
+		})
+		require.NoError(t, err)
+		require.Len(t, added, 1)
+		require.Equal(t, 1, added[0].ID)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// ошибка внутри InTx откатывает и вставку, и запись в пакете до неё
+	err = r.InTx(ctx, func(tx repo.Repository) error {
+		require.NoError(t, tx.Add(ctx, repo.Record{URL: "https://d.ru", ShortURL: "d"}))
+		_, err := tx.(repo.BatchRepo).AddMany(ctx, []repo.ArgAddMany{
+			{URL: "https://e.ru", ShortURL: "e"},
+			{URL: "https://b.ru", ShortURL: "f"},
+		})
+		return err
+	})
+	require.ErrorIs(t, err, repo.ErrAlreadyExists)
+	for _, short := range []repo.ShortURL{"d", "e", "f"} {
+		_, err = r.Get(ctx, short)
+		require.ErrorIs(t, err, repo.ErrNotFoundShortURL)
+	}
+
+	found, err := r.GetByURLs(ctx, []string{"https://a.ru", "https://b.ru", "https://d.ru"})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+}
+
+func TestRepoInTxConcurrent(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepo(t)
+	errTx := errors.New("rollback")
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = r.InTx(ctx, func(tx repo.Repository) error {
+				if _, err := r.NextVal(ctx); err != nil {
+					return err
+				}
+				short := repo.ShortURL(rune('a' + i))
+				if err := tx.Add(ctx, repo.Record{URL: repo.URL("https://" + string(short)), ShortURL: short}); err != nil {
+					return err
+				}
+				if i%2 == 1 {
+					return errTx
+				}
+				return nil
+			})
+			// запись вне транзакции ждёт её конца, а не падает
+			_, _ = r.NextVal(ctx)
+		}()
+	}
+	wg.Wait()
+
+	n, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), n)
+}
+
+func TestRepoDeletePurgeCount(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepo(t)
+	past := time.Now().Add(-time.Minute)
+
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "a", UserID: "u1"}))
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://b.ru", ShortURL: "b", UserID: "u2"}))
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://old.ru", ShortURL: "old", UserID: "u1", ExpiresAt: &past}))
+
+	_, err := r.Get(ctx, "old")
+	require.ErrorIs(t, err, repo.ErrExpired)
+
+	// чужую ссылку удалить нельзя
+	require.NoError(t, r.DeleteByUser(ctx, []repo.DeleteRequest{
+		{UserID: "u1", ShortURL: "a"},
+		{UserID: "u1", ShortURL: "b"},
+	}))
+	_, err = r.Get(ctx, "a")
+	require.ErrorIs(t, err, repo.ErrDeleted)
+	_, err = r.Get(ctx, "b")
+	require.NoError(t, err)
+
+	// до чистки истёкшая ссылка уже не считается
+	urls, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), urls)
+	users, err := r.CountUsers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), users)
+
+	n, err := r.PurgeExpired(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	// у u1 остались только удалённые ссылки
+	users, err = r.CountUsers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), users)
+
+	// после чистки URL снова свободен
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://old.ru", ShortURL: "new", UserID: "u1"}))
+
+	urls, err = r.CountURLs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), urls)
+	users, err = r.CountUsers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), users)
+
+	records, err := r.GetByUser(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, repo.ShortURL("new"), records[0].ShortURL)
+}
+
+func TestRepoSeedSnapshotReopen(t *testing.T) {
+	ctx := context.Background()
+	r, path := newTestRepo(t)
+
+	empty, err := r.Empty()
+	require.NoError(t, err)
+	require.True(t, empty)
+
+	r.Seed([]repo.Record{
+		{ID: 3, URL: "https://a.ru", ShortURL: "a", UserID: "u1"},
+		{ID: 7, URL: "https://b.ru", ShortURL: "b", Domain: "x.example", Deleted: true},
+	})
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://c.ru", ShortURL: "c"}))
+	v, err := r.NextVal(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(9), v)
+
+	require.NoError(t, r.db.Close())
+	db, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	r = NewRepo(db)
+
+	records := r.Snapshot()
+	require.ElementsMatch(t, []repo.Record{
+		{ID: 3, URL: "https://a.ru", ShortURL: "a", UserID: "u1"},
+		{ID: 7, URL: "https://b.ru", ShortURL: "b", Domain: "x.example", Deleted: true},
+		{ID: 8, URL: "https://c.ru", ShortURL: "c"},
+	}, records)
+
+	other, err := r.InDomain("x.example")
+	require.NoError(t, err)
+	_, err = other.Get(ctx, "b")
+	require.ErrorIs(t, err, repo.ErrDeleted)
+}
+
+func TestRepoReshortenDeleted(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepo(t)
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "a", UserID: "u1"}))
+	require.NoError(t, r.DeleteByUser(ctx, []repo.DeleteRequest{{UserID: "u1", ShortURL: "a"}}))
+
+	// удалённая ссылка не находится по URL
+	_, err := r.Search(ctx, "https://a.ru")
+	require.ErrorIs(t, err, repo.ErrNotFoundURL)
+	found, err := r.GetByURLs(ctx, []string{"https://a.ru"})
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	// и URL можно сократить заново
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "b", UserID: "u1"}))
+	short, err := r.Search(ctx, "https://a.ru")
+	require.NoError(t, err)
+	require.Equal(t, repo.ShortURL("b"), short)
+	// живая ссылка на URL снова единственная
+	require.ErrorIs(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "c"}), repo.ErrAlreadyExists)
+	_, err = r.Get(ctx, "a")
+	require.ErrorIs(t, err, repo.ErrDeleted)
+}
+
+func TestRepoReshortenExpired(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepo(t)
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "a", ExpiresAt: &past}))
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://c.ru", ShortURL: "c", ExpiresAt: &past}))
+
+	// просроченная ссылка не находится по URL
+	_, err := r.Search(ctx, "https://a.ru")
+	require.ErrorIs(t, err, repo.ErrNotFoundURL)
+	found, err := r.GetByURLs(ctx, []string{"https://a.ru", "https://c.ru"})
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	// и уступает URL новой, по одной и пачкой
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "b"}))
+	short, err := r.Search(ctx, "https://a.ru")
+	require.NoError(t, err)
+	require.Equal(t, repo.ShortURL("b"), short)
+	added, err := r.AddMany(ctx, []repo.ArgAddMany{{URL: "https://c.ru", ShortURL: "d"}})
+	require.NoError(t, err)
+	require.Len(t, added, 1)
+	found, err = r.GetByURLs(ctx, []string{"https://c.ru"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, repo.ShortURL("d"), found[0].ShortURL)
+}
+
+func TestRepoDeleteDomain(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepo(t)
+	other, err := r.InDomain("x.example")
+	require.NoError(t, err)
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "a", UserID: "u1"}))
+	require.NoError(t, other.Add(ctx, repo.Record{URL: "https://b.ru", ShortURL: "a", UserID: "u1"}))
+
+	// тот же код в другом домене не удаляется
+	require.NoError(t, r.DeleteByUser(ctx, []repo.DeleteRequest{{Domain: "x.example", UserID: "u1", ShortURL: "a"}}))
+	_, err = other.Get(ctx, "a")
+	require.ErrorIs(t, err, repo.ErrDeleted)
+	_, err = r.Get(ctx, "a")
+	require.NoError(t, err)
+}
+
+func TestOpenIndexesOldBase(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "old.bolt")
+	past := time.Now().Add(-time.Minute)
+
+	// база из версии без индексов: только записи и URL
+	db, err := bbolt.Open(path, 0o644, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		short, err := tx.CreateBucket(shortBucket)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(urlBucket); err != nil {
+			return err
+		}
+		for _, rec := range []repo.Record{
+			{ID: 0, URL: "https://a.ru", ShortURL: "a", UserID: "u1"},
+			{ID: 1, URL: "https://b.ru", ShortURL: "b", UserID: "u2", ExpiresAt: &past},
+		} {
+			v, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			if err := short.Put(key(rec.Domain, string(rec.ShortURL)), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	require.NoError(t, db.Close())
+
+	db, err = Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	r := NewRepo(db)
+
+	records, err := r.GetByUser(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	users, err := r.CountUsers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), users)
+	n, err := r.PurgeExpired(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	urls, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), urls)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type ClickRepo interface {
+	AddClicks(ctx context.Context, clicks []Click) error
+	ClickStats(ctx context.Context, domain string, short ShortURL) (ClickStats, error)
+}
+
+type Click struct {
+	// Domain домен ссылки, как в Record: один код в разных доменах — разные ссылки.
+	Domain    string    `json:"domain,omitempty"`
+	ShortURL  ShortURL  `json:"short_url"`
+	At        time.Time `json:"at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+}
+
+type ClickStats struct {
+	Total int64
+	// Unique считает уникальные пары (ip, user agent).
+	Unique int64
+	Daily  []DailyClicks
+}
+
+type DailyClicks struct {
+	Day   time.Time
+	Count int64
+}
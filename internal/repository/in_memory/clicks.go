@@ -0,0 +1,56 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+type ClickRepo struct {
+	mu     sync.RWMutex
+	clicks map[shortKey][]repo.Click
+}
+
+func NewClickRepo() *ClickRepo {
+	return &ClickRepo{clicks: make(map[shortKey][]repo.Click)}
+}
+
+func (r *ClickRepo) AddClicks(_ context.Context, clicks []repo.Click) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range clicks {
+		key := shortKey{c.Domain, c.ShortURL}
+		r.clicks[key] = append(r.clicks[key], c)
+	}
+	return nil
+}
+
+func (r *ClickRepo) ClickStats(_ context.Context, domain string, short repo.ShortURL) (repo.ClickStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type visitor struct{ ip, ua string }
+	visitors := make(map[visitor]struct{})
+	days := make(map[time.Time]int64)
+
+	clicks := r.clicks[shortKey{domain, short}]
+	for _, c := range clicks {
+		visitors[visitor{c.IP, c.UserAgent}] = struct{}{}
+		days[c.At.UTC().Truncate(24*time.Hour)]++
+	}
+
+	stats := repo.ClickStats{
+		Total:  int64(len(clicks)),
+		Unique: int64(len(visitors)),
+		Daily:  make([]repo.DailyClicks, 0, len(days)),
+	}
+	for day, n := range days {
+		stats.Daily = append(stats.Daily, repo.DailyClicks{Day: day, Count: n})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool { return stats.Daily[i].Day.Before(stats.Daily[j].Day) })
+	return stats, nil
+}
@@ -0,0 +1,73 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+// KeyPool резервуар свободных коротких id в памяти; занятость ключа проверяется через r.Get.
+type KeyPool struct {
+	r    repo.Repository
+	mu   sync.Mutex
+	keys []repo.ShortURL
+	set  map[repo.ShortURL]struct{}
+}
+
+func NewKeyPool(r repo.Repository) *KeyPool {
+	return &KeyPool{r: r, set: make(map[repo.ShortURL]struct{})}
+}
+
+func (p *KeyPool) ClaimKeys(_ context.Context, n int) ([]repo.ShortURL, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n = min(n, len(p.keys))
+	if n <= 0 {
+		return nil, nil
+	}
+	out := make([]repo.ShortURL, n)
+	copy(out, p.keys[len(p.keys)-n:])
+	p.keys = p.keys[:len(p.keys)-n]
+	for _, k := range out {
+		delete(p.set, k)
+	}
+	return out, nil
+}
+
+func (p *KeyPool) AddKeys(ctx context.Context, keys []repo.ShortURL) (int64, error) {
+	free := make([]repo.ShortURL, 0, len(keys))
+	for _, k := range keys {
+		_, err := p.r.Get(ctx, k)
+		if errors.Is(err, repo.ErrNotFoundShortURL) {
+			free = append(free, k)
+			continue
+		}
+		// удалённые и истёкшие ссылки тоже занимают ключ
+		if err != nil && !errors.Is(err, repo.ErrDeleted) && !errors.Is(err, repo.ErrExpired) {
+			return 0, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var n int64
+	for _, k := range free {
+		if _, ok := p.set[k]; ok {
+			continue
+		}
+		p.set[k] = struct{}{}
+		p.keys = append(p.keys, k)
+		n++
+	}
+	return n, nil
+}
+
+func (p *KeyPool) PoolSize(_ context.Context) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return int64(len(p.keys)), nil
+}
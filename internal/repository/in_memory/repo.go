@@ -3,55 +3,130 @@ package inmemory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
 )
 
+// Repo хранилище в памяти. Копии из InDomain делят с ним store и отличаются только доменом.
 type Repo struct {
+	*store
+	domain string
+}
+
+type store struct {
 	mu        sync.RWMutex
-	dataShort map[repo.ShortURL]repo.URL
-	dataURL   map[repo.URL]repo.ShortURL
+	dataShort map[shortKey]repo.Record
+	dataURL   map[urlKey]repo.ShortURL // только неудалённые: удалённый URL сокращается заново
+	nextID    int
+	seq       int64
+}
+
+type shortKey struct {
+	domain string
+	short  repo.ShortURL
+}
+
+type urlKey struct {
+	domain string
+	url    repo.URL
 }
 
 func NewRepo() *Repo {
-	return &Repo{
-		dataShort: make(map[repo.ShortURL]repo.URL),
-		dataURL:   make(map[repo.URL]repo.ShortURL),
-	}
+	return &Repo{store: &store{
+		dataShort: make(map[shortKey]repo.Record),
+		dataURL:   make(map[urlKey]repo.ShortURL),
+	}}
+}
+
+// InDomain то же хранилище, но ссылки ищутся и создаются в domain.
+func (r *Repo) InDomain(domain string) (repo.Repository, error) {
+	return &Repo{store: r.store, domain: domain}, nil
 }
 
 func (r *Repo) Get(_ context.Context, shortURL repo.ShortURL) (repo.URL, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if url, ok := r.dataShort[shortURL]; ok {
-		return url, nil
+	rec, ok := r.dataShort[shortKey{r.domain, shortURL}]
+	if !ok {
+		return "", repo.ErrNotFoundShortURL
 	}
-	return "", repo.ErrNotFoundShortURL
+	if rec.Deleted {
+		return "", repo.ErrDeleted
+	}
+	if rec.Expired(time.Now()) {
+		return "", repo.ErrExpired
+	}
+	return rec.URL, nil
 }
 
-func (r *Repo) Add(_ context.Context, shortURL repo.ShortURL, url repo.URL) error {
+func (r *Repo) Add(_ context.Context, rec repo.Record) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.dataShort[shortURL]; ok {
-		return fmt.Errorf("%w: %v", repo.ErrShortURLAlreadyExists, shortURL)
+	rec.Domain = r.domain
+	if _, ok := r.dataShort[shortKey{rec.Domain, rec.ShortURL}]; ok {
+		return fmt.Errorf("%w: %v", repo.ErrShortURLAlreadyExists, rec.ShortURL)
 	}
-	if _, ok := r.dataURL[url]; ok {
-		return fmt.Errorf("%w: %v", repo.ErrAlreadyExists, url)
+	if _, ok := r.live(rec.Domain, rec.URL, time.Now()); ok {
+		return fmt.Errorf("%w: %v", repo.ErrAlreadyExists, rec.URL)
 	}
-	r.dataShort[shortURL] = url
-	r.dataURL[url] = shortURL
+	r.insert(rec)
 	return nil
 }
 
+// live запись, на которую сейчас ведёт URL; просроченная URL уже не держит,
+// и новая ссылка перебивает её в dataURL.
+func (s *store) live(domain string, url repo.URL, now time.Time) (repo.Record, bool) {
+	short, ok := s.dataURL[urlKey{domain, url}]
+	if !ok {
+		return repo.Record{}, false
+	}
+	rec := s.dataShort[shortKey{domain, short}]
+	if rec.Expired(now) {
+		return repo.Record{}, false
+	}
+	return rec, true
+}
+
+// insert вызывается под r.mu.Lock.
+func (r *Repo) insert(rec repo.Record) repo.Record {
+	rec.ID = r.nextID
+	r.nextID++
+	r.put(rec)
+	return rec
+}
+
+func (s *store) put(rec repo.Record) {
+	s.dataShort[shortKey{rec.Domain, rec.ShortURL}] = rec
+	// снимок может прийти в любом порядке: просроченная запись не перебьёт живую
+	if !rec.Deleted && !rec.Expired(time.Now()) {
+		s.dataURL[urlKey{rec.Domain, rec.URL}] = rec.ShortURL
+	}
+}
+
+func (s *store) drop(rec repo.Record) {
+	delete(s.dataShort, shortKey{rec.Domain, rec.ShortURL})
+	s.release(rec)
+}
+
+// release освобождает URL, если он всё ещё ведёт на rec.
+func (s *store) release(rec repo.Record) {
+	key := urlKey{rec.Domain, rec.URL}
+	if s.dataURL[key] == rec.ShortURL {
+		delete(s.dataURL, key)
+	}
+}
+
 func (r *Repo) Search(_ context.Context, url repo.URL) (repo.ShortURL, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if short, ok := r.dataURL[url]; ok {
-		return short, nil
+	if rec, ok := r.live(r.domain, url, time.Now()); ok {
+		return rec.ShortURL, nil
 	}
 	return "", repo.ErrNotFoundURL
 }
@@ -60,13 +135,18 @@ func (r *Repo) Seed(records []repo.Record) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.dataShort = make(map[repo.ShortURL]repo.URL, len(records))
-	r.dataURL = make(map[repo.URL]repo.ShortURL, len(records))
+	r.dataShort = make(map[shortKey]repo.Record, len(records))
+	r.dataURL = make(map[urlKey]repo.ShortURL, len(records))
+	r.nextID = 0
 
 	for _, rec := range records {
-		r.dataShort[rec.ShortURL] = rec.URL
-		r.dataURL[rec.URL] = rec.ShortURL
+		r.put(rec)
+		if rec.ID >= r.nextID {
+			r.nextID = rec.ID + 1
+		}
 	}
+	// номеров больше, чем записей, раньше выдать не могли; пропуски не страшны
+	r.seq = int64(r.nextID)
 }
 
 func (r *Repo) Snapshot() []repo.Record {
@@ -74,10 +154,8 @@ func (r *Repo) Snapshot() []repo.Record {
 	defer r.mu.RUnlock()
 
 	out := make([]repo.Record, 0, len(r.dataShort))
-	id := 0
-	for short, url := range r.dataShort {
-		out = append(out, repo.Record{ID: id, ShortURL: short, URL: url})
-		id++
+	for _, rec := range r.dataShort {
+		out = append(out, rec)
 	}
 	return out
 }
@@ -86,8 +164,7 @@ func (r *Repo) Remove(short repo.ShortURL, url repo.URL) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	delete(r.dataShort, short)
-	delete(r.dataURL, url)
+	r.drop(repo.Record{Domain: r.domain, ShortURL: short, URL: url})
 }
 
 func (r *Repo) GetByURLs(_ context.Context, urls []string) ([]repo.Record, error) {
@@ -98,14 +175,11 @@ func (r *Repo) GetByURLs(_ context.Context, urls []string) ([]repo.Record, error
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	now := time.Now()
 	out := make([]repo.Record, 0, len(urls))
 	for _, u := range urls {
-		url := repo.URL(u)
-		if short, ok := r.dataURL[url]; ok {
-			out = append(out, repo.Record{
-				ShortURL: short,
-				URL:      url,
-			})
+		if rec, ok := r.live(r.domain, repo.URL(u), now); ok {
+			out = append(out, rec)
 		}
 	}
 	return out, nil
@@ -119,22 +193,113 @@ func (r *Repo) AddMany(_ context.Context, records []repo.ArgAddMany) ([]repo.Rec
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	now := time.Now()
 	out := make([]repo.Record, 0, len(records))
 	for _, rec := range records {
-		if _, ok := r.dataShort[rec.ShortURL]; ok {
+		if _, ok := r.dataShort[shortKey{r.domain, rec.ShortURL}]; ok {
 			continue
 		}
-		if _, ok := r.dataURL[rec.URL]; ok {
+		if _, ok := r.live(r.domain, rec.URL, now); ok {
 			return nil, fmt.Errorf("%w: %v", repo.ErrAlreadyExists, rec.URL)
 		}
 
-		r.dataShort[rec.ShortURL] = rec.URL
-		r.dataURL[rec.URL] = rec.ShortURL
+		out = append(out, r.insert(repo.Record{
+			URL:       rec.URL,
+			ShortURL:  rec.ShortURL,
+			UserID:    rec.UserID,
+			ExpiresAt: rec.ExpiresAt,
+			Domain:    r.domain,
+		}))
+	}
+	return out, nil
+}
+
+func (r *Repo) GetByUser(_ context.Context, userID string) ([]repo.Record, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-		out = append(out, repo.Record{
-			URL:      rec.URL,
-			ShortURL: rec.ShortURL,
-		})
+	now := time.Now()
+	out := make([]repo.Record, 0)
+	for _, rec := range r.dataShort {
+		if rec.UserID == userID && !rec.Deleted && !rec.Expired(now) {
+			out = append(out, rec)
+		}
 	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
 	return out, nil
 }
+
+// DeleteByUser удаляет ссылку только в домене запроса и только у её владельца.
+func (r *Repo) DeleteByUser(_ context.Context, reqs []repo.DeleteRequest) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, req := range reqs {
+		key := shortKey{req.Domain, req.ShortURL}
+		rec, ok := r.dataShort[key]
+		if !ok || rec.UserID != req.UserID {
+			continue
+		}
+		rec.Deleted = true
+		r.dataShort[key] = rec
+		r.release(rec)
+	}
+	return nil
+}
+
+func (r *Repo) PurgeExpired(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for _, rec := range r.dataShort {
+		if rec.Expired(now) {
+			r.drop(rec)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CountURLs считает живые ссылки во всех доменах.
+func (r *Repo) CountURLs(_ context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var n int64
+	for _, rec := range r.dataShort {
+		if !rec.Deleted && !rec.Expired(now) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CountUsers считает пользователей, у которых есть хотя бы одна живая ссылка.
+func (r *Repo) CountUsers(_ context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	users := make(map[string]struct{})
+	for _, rec := range r.dataShort {
+		if rec.UserID != "" && !rec.Deleted && !rec.Expired(now) {
+			users[rec.UserID] = struct{}{}
+		}
+	}
+	return int64(len(users)), nil
+}
+
+// NextVal счётчик для генераторов коротких id.
+func (r *Repo) NextVal(_ context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	return r.seq, nil
+}
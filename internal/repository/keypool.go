@@ -0,0 +1,12 @@
+package repository
+
+import "context"
+
+// KeyPool запас заранее сгенерированных свободных коротких id.
+type KeyPool interface {
+	// ClaimKeys атомарно забирает из пула до n ключей; пустой пул — не ошибка.
+	ClaimKeys(ctx context.Context, n int) ([]ShortURL, error)
+	// AddKeys кладёт ключи в пул, пропуская уже занятые ссылками или самим пулом; возвращает число добавленных.
+	AddKeys(ctx context.Context, keys []ShortURL) (int64, error)
+	PoolSize(ctx context.Context) (int64, error)
+}
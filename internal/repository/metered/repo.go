@@ -0,0 +1,122 @@
+package metered
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+// Observer получает длительность и ошибку каждой операции хранилища.
+type Observer interface {
+	ObserveRepo(op string, d time.Duration, err error)
+}
+
+// Repo декоратор, который замеряет Get, Search, Add, AddMany и GetByURLs. Остальные
+// методы проксируются как есть; репозитории из InTx и InDomain тоже оборачиваются.
+type Repo struct {
+	base repo.Repository
+	obs  Observer
+}
+
+func New(base repo.Repository, obs Observer) *Repo {
+	return &Repo{base: base, obs: obs}
+}
+
+func (r *Repo) Get(ctx context.Context, s repo.ShortURL) (repo.URL, error) {
+	start := time.Now()
+	u, err := r.base.Get(ctx, s)
+	r.obs.ObserveRepo("Get", time.Since(start), err)
+	return u, err
+}
+
+func (r *Repo) Search(ctx context.Context, u repo.URL) (repo.ShortURL, error) {
+	start := time.Now()
+	s, err := r.base.Search(ctx, u)
+	r.obs.ObserveRepo("Search", time.Since(start), err)
+	return s, err
+}
+
+func (r *Repo) Add(ctx context.Context, rec repo.Record) error {
+	start := time.Now()
+	err := r.base.Add(ctx, rec)
+	r.obs.ObserveRepo("Add", time.Since(start), err)
+	return err
+}
+
+func (r *Repo) GetByURLs(ctx context.Context, urls []string) ([]repo.Record, error) {
+	br, ok := r.base.(repo.BatchRepo)
+	if !ok {
+		return nil, fmt.Errorf("no implement batch in repo")
+	}
+	start := time.Now()
+	res, err := br.GetByURLs(ctx, urls)
+	r.obs.ObserveRepo("GetByURLs", time.Since(start), err)
+	return res, err
+}
+
+func (r *Repo) AddMany(ctx context.Context, records []repo.ArgAddMany) ([]repo.Record, error) {
+	br, ok := r.base.(repo.BatchRepo)
+	if !ok {
+		return nil, fmt.Errorf("no implement batch in repo")
+	}
+	start := time.Now()
+	res, err := br.AddMany(ctx, records)
+	r.obs.ObserveRepo("AddMany", time.Since(start), err)
+	return res, err
+}
+
+func (r *Repo) InTx(ctx context.Context, fn func(r repo.Repository) error) error {
+	tx, ok := r.base.(repo.TxRunner)
+	if !ok {
+		return fn(r)
+	}
+	return tx.InTx(ctx, func(inner repo.Repository) error {
+		return fn(New(inner, r.obs))
+	})
+}
+
+func (r *Repo) InDomain(domain string) (repo.Repository, error) {
+	dr, ok := r.base.(repo.DomainRepo)
+	if !ok {
+		return nil, fmt.Errorf("no implement domain methods in repo")
+	}
+	scoped, err := dr.InDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	return New(scoped, r.obs), nil
+}
+
+func (r *Repo) GetByUser(ctx context.Context, userID string) ([]repo.Record, error) {
+	ur, ok := r.base.(repo.UserRepo)
+	if !ok {
+		return nil, fmt.Errorf("no implement user methods in repo")
+	}
+	return ur.GetByUser(ctx, userID)
+}
+
+func (r *Repo) DeleteByUser(ctx context.Context, reqs []repo.DeleteRequest) error {
+	dr, ok := r.base.(repo.DeleteRepo)
+	if !ok {
+		return fmt.Errorf("no implement delete methods in repo")
+	}
+	return dr.DeleteByUser(ctx, reqs)
+}
+
+func (r *Repo) CountURLs(ctx context.Context) (int64, error) {
+	cr, ok := r.base.(repo.CountRepo)
+	if !ok {
+		return 0, fmt.Errorf("no implement count methods in repo")
+	}
+	return cr.CountURLs(ctx)
+}
+
+func (r *Repo) CountUsers(ctx context.Context) (int64, error) {
+	cr, ok := r.base.(repo.CountRepo)
+	if !ok {
+		return 0, fmt.Errorf("no implement count methods in repo")
+	}
+	return cr.CountUsers(ctx)
+}
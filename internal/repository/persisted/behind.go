@@ -0,0 +1,207 @@
+package persisted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/filestorage"
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+)
+
+// ErrStopped изменение пришло после остановки отложенной записи.
+var ErrStopped = errors.New("persisted: write-behind stopped")
+
+// Observer метрики отложенной записи.
+type Observer interface {
+	// ObserveFlush после каждой попытки сброса: сколько изменений ждёт в очереди
+	// и сколько ждало самое старое из сброшенных.
+	ObserveFlush(queued int, lag time.Duration, err error)
+	// ObserveQueueFull писатель ждёт, потому что очередь полна.
+	ObserveQueueFull()
+}
+
+type Option func(*Repo)
+
+// WithWriteBehind включает отложенную запись: изменения сразу видны в base, а в Persister
+// уходят пачкой раз в interval или по набору batch строк. В очереди не больше queue
+// изменений, дальше писатели ждут. Без этой опции каждое изменение сохраняется сразу,
+// а при ошибке откатывается через repo.Rollback.
+func WithWriteBehind(interval time.Duration, batch, queue int, obs Observer) Option {
+	return func(r *Repo) {
+		r.wb = &writeBehind{
+			queue:    make(chan change, queue),
+			interval: interval,
+			batch:    batch,
+			obs:      obs,
+			stop:     make(chan struct{}),
+			idle:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+	}
+}
+
+type change struct {
+	entries []filestorage.Entry
+	at      time.Time
+}
+
+type writeBehind struct {
+	queue    chan change
+	interval time.Duration
+	batch    int
+	obs      Observer
+
+	mu        sync.Mutex
+	closed    bool
+	producers sync.WaitGroup
+
+	// stop закрывает Close: новые изменения не принимаются, а ждущие места писатели
+	// получают ErrStopped; idle закрывается, когда ушёл последний писатель
+	stop chan struct{}
+	idle chan struct{}
+	done chan struct{}
+	err  error
+}
+
+// enqueue ставит изменение в очередь; если она полна, ждёт места или отмены ctx.
+func (r *Repo) enqueue(ctx context.Context, entries ...filestorage.Entry) error {
+	wb := r.wb
+	c := change{entries: entries, at: time.Now()}
+
+	wb.mu.Lock()
+	if wb.closed {
+		wb.mu.Unlock()
+		return ErrStopped
+	}
+	wb.producers.Add(1)
+	wb.mu.Unlock()
+	defer wb.producers.Done()
+
+	select {
+	case wb.queue <- c:
+		return nil
+	default:
+	}
+	if wb.obs != nil {
+		wb.obs.ObserveQueueFull()
+	}
+	select {
+	case wb.queue <- c:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wb.stop:
+		return ErrStopped
+	}
+}
+
+// Run сбрасывает очередь отложенной записи; без WithWriteBehind сразу возвращается.
+func (r *Repo) Run() {
+	wb := r.wb
+	if wb == nil {
+		return
+	}
+	defer close(wb.done)
+
+	ticker := time.NewTicker(wb.interval)
+	defer ticker.Stop()
+
+	var (
+		pending []change
+		size    int
+		// после неудачного сброса очередь не разбирается до следующего тика,
+		// чтобы писатели упёрлись в её размер, а не копили изменения в памяти
+		in = wb.queue
+	)
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		err := r.flushChanges(pending)
+		if wb.obs != nil {
+			wb.obs.ObserveFlush(len(wb.queue), time.Since(pending[0].at), err)
+		}
+		if err != nil {
+			return err
+		}
+		pending, size = pending[:0], 0
+		return nil
+	}
+
+	for {
+		select {
+		case c := <-in:
+			pending = append(pending, c)
+			size += max(len(c.entries), 1)
+			if size >= wb.batch {
+				if err := flush(); err != nil {
+					logger.Log.Errorf("write-behind flush: %s", err)
+					in = nil
+				}
+			}
+		case <-ticker.C:
+			if err := flush(); err != nil {
+				logger.Log.Errorf("write-behind flush: %s", err)
+				in = nil
+				continue
+			}
+			in = wb.queue
+		case <-wb.idle:
+			// писателей больше нет: забираем остаток очереди и сбрасываем всё разом
+		drain:
+			for {
+				select {
+				case c := <-wb.queue:
+					pending = append(pending, c)
+				default:
+					break drain
+				}
+			}
+			wb.err = flush()
+			return
+		}
+	}
+}
+
+// Close перестаёт принимать изменения и дожидается последнего сброса, пока жив ctx.
+func (r *Repo) Close(ctx context.Context) error {
+	wb := r.wb
+	if wb == nil {
+		return nil
+	}
+	wb.mu.Lock()
+	if !wb.closed {
+		wb.closed = true
+		close(wb.stop)
+		go func() {
+			wb.producers.Wait()
+			close(wb.idle)
+		}()
+	}
+	wb.mu.Unlock()
+
+	select {
+	case <-wb.done:
+		if wb.err != nil {
+			return fmt.Errorf("persisted: final flush: %w", wb.err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flushChanges журналу дописывает накопленные строки, снимку хватает одного сохранения на всю пачку.
+func (r *Repo) flushChanges(changes []change) error {
+	if r.app == nil {
+		return r.save()
+	}
+	var entries []filestorage.Entry
+	for _, c := range changes {
+		entries = append(entries, c.entries...)
+	}
+	return r.app.Append(entries...)
+}
@@ -0,0 +1,151 @@
+package persisted
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/filestorage"
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	inmemory "github.com/IvanOplesnin/url-shortener/internal/repository/in_memory"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLog struct {
+	mu      sync.Mutex
+	entries []filestorage.Entry
+	err     error
+}
+
+func (f *fakeLog) Load() ([]repo.Record, error) { return nil, nil }
+func (f *fakeLog) Save(_ []repo.Record) error   { return nil }
+func (f *fakeLog) setErr(err error)             { f.mu.Lock(); f.err = err; f.mu.Unlock() }
+func (f *fakeLog) Append(entries ...filestorage.Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.entries = append(f.entries, entries...)
+	return nil
+}
+
+func (f *fakeLog) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.entries)
+}
+
+type fakeObserver struct {
+	mu      sync.Mutex
+	flushes int
+	full    int
+}
+
+func (o *fakeObserver) ObserveFlush(int, time.Duration, error) {
+	o.mu.Lock()
+	o.flushes++
+	o.mu.Unlock()
+}
+func (o *fakeObserver) ObserveQueueFull() { o.mu.Lock(); o.full++; o.mu.Unlock() }
+
+func newBehindRepo(t *testing.T, p *fakeLog, interval time.Duration, batch, queue int, obs Observer) *Repo {
+	t.Helper()
+	base := inmemory.NewRepo()
+	r, err := New(base, base, base, p, base, nil, base, WithWriteBehind(interval, batch, queue, obs))
+	require.NoError(t, err)
+	return r
+}
+
+func TestWriteBehindBatch(t *testing.T) {
+	ctx := context.Background()
+	p := &fakeLog{}
+	obs := &fakeObserver{}
+	r := newBehindRepo(t, p, time.Hour, 2, 10, obs)
+	go r.Run()
+
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "a"}))
+	// запись видна сразу, хотя в файл ещё не ушла
+	got, err := r.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, repo.URL("https://a.ru"), got)
+	require.Equal(t, 0, p.len())
+
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://b.ru", ShortURL: "b"}))
+	require.Eventually(t, func() bool { return p.len() == 2 }, time.Second, 5*time.Millisecond)
+
+	// остаток меньше пачки сбрасывается при остановке
+	require.NoError(t, r.DeleteByUser(ctx, []repo.DeleteRequest{{ShortURL: "a"}}))
+	require.NoError(t, r.Close(ctx))
+	require.Equal(t, 3, p.len())
+	require.Equal(t, filestorage.OpDelete, p.entries[2].Op)
+	require.Equal(t, 2, obs.flushes)
+
+	require.ErrorIs(t, r.Add(ctx, repo.Record{URL: "https://c.ru", ShortURL: "c"}), ErrStopped)
+}
+
+func TestWriteBehindBackpressure(t *testing.T) {
+	ctx := context.Background()
+	p := &fakeLog{}
+	p.setErr(errors.New("disk full"))
+	obs := &fakeObserver{}
+	r := newBehindRepo(t, p, 20*time.Millisecond, 1, 1, obs)
+	go r.Run()
+
+	// первый сброс падает, и очередь больше не разбирается
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "a"}))
+	require.Eventually(t, func() bool {
+		obs.mu.Lock()
+		defer obs.mu.Unlock()
+		return obs.flushes > 0
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://b.ru", ShortURL: "b"}))
+
+	// очередь полна: писатель ждёт, а по таймауту запись откатывается
+	short, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	err := r.Add(short, repo.Record{URL: "https://c.ru", ShortURL: "c"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = r.Get(ctx, "c")
+	require.ErrorIs(t, err, repo.ErrNotFoundShortURL)
+	obs.mu.Lock()
+	require.Positive(t, obs.full)
+	obs.mu.Unlock()
+
+	// диск ожил: всё накопленное уходит при следующем тике
+	p.setErr(nil)
+	require.Eventually(t, func() bool { return p.len() == 2 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, r.Close(ctx))
+}
+
+func TestWriteBehindCloseUnblocksWriters(t *testing.T) {
+	ctx := context.Background()
+	p := &fakeLog{}
+	// Run не запущен: очередь на одно изменение сразу полна
+	r := newBehindRepo(t, p, time.Hour, 10, 1, nil)
+	require.NoError(t, r.Add(ctx, repo.Record{URL: "https://a.ru", ShortURL: "a"}))
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.Add(ctx, repo.Record{URL: "https://b.ru", ShortURL: "b"})
+	}()
+	require.Eventually(t, func() bool {
+		_, err := r.Get(ctx, "b")
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+
+	// Close не ждёт писателя без дедлайна: тот получает ErrStopped, а Close — свой ctx
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, r.Close(closeCtx), context.DeadlineExceeded)
+	require.ErrorIs(t, <-errc, ErrStopped)
+	_, err := r.Get(ctx, "b")
+	require.ErrorIs(t, err, repo.ErrNotFoundShortURL)
+
+	// запущенный Run дописывает то, что успело попасть в очередь
+	go r.Run()
+	require.NoError(t, r.Close(ctx))
+	require.Equal(t, 1, p.len())
+}
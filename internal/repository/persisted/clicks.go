@@ -0,0 +1,36 @@
+package persisted
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/IvanOplesnin/url-shortener/internal/filestorage"
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+type ClickRepo struct {
+	base repo.ClickRepo
+	p    filestorage.ClickPersister
+}
+
+func NewClickRepo(base repo.ClickRepo, p filestorage.ClickPersister) (*ClickRepo, error) {
+	clicks, err := p.LoadClicks()
+	if err != nil {
+		return nil, fmt.Errorf("persisted: load clicks: %w", err)
+	}
+	if err := base.AddClicks(context.Background(), clicks); err != nil {
+		return nil, fmt.Errorf("persisted: seed clicks: %w", err)
+	}
+	return &ClickRepo{base: base, p: p}, nil
+}
+
+func (r *ClickRepo) AddClicks(ctx context.Context, clicks []repo.Click) error {
+	if err := r.p.AppendClicks(clicks); err != nil {
+		return fmt.Errorf("persisted: append clicks: %w", err)
+	}
+	return r.base.AddClicks(ctx, clicks)
+}
+
+func (r *ClickRepo) ClickStats(ctx context.Context, domain string, short repo.ShortURL) (repo.ClickStats, error) {
+	return r.base.ClickStats(ctx, domain, short)
+}
@@ -3,6 +3,8 @@ package persisted
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/IvanOplesnin/url-shortener/internal/filestorage"
 	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
@@ -13,12 +15,27 @@ type Repo struct {
 	s     repo.Seeder
 	snap  repo.Snapshoter
 	p     filestorage.Persister
+	app   filestorage.Appender // есть, если Persister дописывает изменения, а не пишет весь снимок
 	rb    repo.Rollback
 	tx    repo.TxRunner
 	batch repo.BatchRepo
+	users repo.UserRepo
+	del   repo.DeleteRepo
+	purge repo.ExpiredPurger
+	seq   repo.Sequence
+	dom   repo.DomainRepo
+	count repo.CountRepo
+	// domain домен копии из InDomain, пишется в журнал вместе с записью
+	domain string
+	// wb очередь отложенной записи, общая с копиями из InDomain; nil — запись сразу
+	wb *writeBehind
+
+	// saveMu не даёт параллельным сохранениям записать более старый снимок поверх нового;
+	// общий с копиями из InDomain
+	saveMu *sync.Mutex
 }
 
-func New(base repo.Repository, s repo.Seeder, snap repo.Snapshoter, p filestorage.Persister, rb repo.Rollback, tx repo.TxRunner, batch repo.BatchRepo) (*Repo, error) {
+func New(base repo.Repository, s repo.Seeder, snap repo.Snapshoter, p filestorage.Persister, rb repo.Rollback, tx repo.TxRunner, batch repo.BatchRepo, opts ...Option) (*Repo, error) {
 	records, err := p.Load()
 	if err != nil {
 		return nil, fmt.Errorf("persisted: load: %w", err)
@@ -27,15 +44,67 @@ func New(base repo.Repository, s repo.Seeder, snap repo.Snapshoter, p filestorag
 		s.Seed(records)
 	}
 
-	return &Repo{
-		base:  base,
-		s:     s,
-		snap:  snap,
-		p:     p,
-		rb:    rb,
-		tx:    tx,
-		batch: batch,
-	}, nil
+	users, _ := base.(repo.UserRepo)
+	del, _ := base.(repo.DeleteRepo)
+	purge, _ := base.(repo.ExpiredPurger)
+	seq, _ := base.(repo.Sequence)
+	dom, _ := base.(repo.DomainRepo)
+	count, _ := base.(repo.CountRepo)
+	var app filestorage.Appender
+	if snap != nil {
+		app, _ = p.(filestorage.Appender)
+	}
+
+	r := &Repo{
+		base:   base,
+		s:      s,
+		snap:   snap,
+		p:      p,
+		app:    app,
+		rb:     rb,
+		tx:     tx,
+		batch:  batch,
+		users:  users,
+		del:    del,
+		purge:  purge,
+		seq:    seq,
+		dom:    dom,
+		count:  count,
+		saveMu: &sync.Mutex{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// InDomain копия Repo поверх base.InDomain(domain): снимок и файл общие, откат и пакетные
+// методы идут в тот же домен.
+func (r *Repo) InDomain(domain string) (repo.Repository, error) {
+	if r.dom == nil {
+		return nil, fmt.Errorf("no implement domain methods in repo")
+	}
+	base, err := r.dom.InDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	scoped := *r
+	scoped.base = base
+	scoped.domain = domain
+	scoped.users, _ = base.(repo.UserRepo)
+	scoped.del, _ = base.(repo.DeleteRepo)
+	scoped.purge, _ = base.(repo.ExpiredPurger)
+	scoped.seq, _ = base.(repo.Sequence)
+	if r.rb != nil {
+		scoped.rb, _ = base.(repo.Rollback)
+	}
+	if r.tx != nil {
+		scoped.tx, _ = base.(repo.TxRunner)
+	}
+	if r.batch != nil {
+		scoped.batch, _ = base.(repo.BatchRepo)
+	}
+	return &scoped, nil
 }
 
 func (r *Repo) Get(ctx context.Context, s repo.ShortURL) (repo.URL, error) {
@@ -46,15 +115,17 @@ func (r *Repo) Search(ctx context.Context, url repo.URL) (repo.ShortURL, error)
 	return r.base.Search(ctx, url)
 }
 
-func (r *Repo) Add(ctx context.Context, short repo.ShortURL, url repo.URL) error {
-	if err := r.base.Add(ctx, short, url); err != nil {
+func (r *Repo) Add(ctx context.Context, rec repo.Record) error {
+	if err := r.base.Add(ctx, rec); err != nil {
 		return err
 	}
 
 	if r.snap != nil {
-		if err := r.p.Save(r.snap.Snapshot()); err != nil {
+		stored := rec
+		stored.Domain = r.domain
+		if err := r.persist(ctx, filestorage.PutEntry(stored)); err != nil {
 			if r.rb != nil {
-				r.rb.Remove(short, url)
+				r.rb.Remove(rec.ShortURL, rec.URL)
 			}
 			return fmt.Errorf("persisted: save: %w", err)
 		}
@@ -84,7 +155,11 @@ func (r *Repo) AddMany(ctx context.Context, records []repo.ArgAddMany) ([]repo.R
 			return nil, err
 		}
 		if r.snap != nil {
-			if err := r.p.Save(r.snap.Snapshot()); err != nil {
+			entries := make([]filestorage.Entry, 0, len(res))
+			for _, rec := range res {
+				entries = append(entries, filestorage.PutEntry(rec))
+			}
+			if err := r.persist(ctx, entries...); err != nil {
 				if r.rb != nil {
 					for _, rec := range res {
 						r.rb.Remove(rec.ShortURL, rec.URL)
@@ -98,3 +173,96 @@ func (r *Repo) AddMany(ctx context.Context, records []repo.ArgAddMany) ([]repo.R
 		return nil, fmt.Errorf("no implement batch in repo")
 	}
 }
+
+func (r *Repo) GetByUser(ctx context.Context, userID string) ([]repo.Record, error) {
+	if r.users != nil {
+		return r.users.GetByUser(ctx, userID)
+	}
+	return nil, fmt.Errorf("no implement user methods in repo")
+}
+
+func (r *Repo) DeleteByUser(ctx context.Context, reqs []repo.DeleteRequest) error {
+	if r.del == nil {
+		return fmt.Errorf("no implement delete methods in repo")
+	}
+	if err := r.del.DeleteByUser(ctx, reqs); err != nil {
+		return err
+	}
+	if r.snap != nil {
+		entries := make([]filestorage.Entry, 0, len(reqs))
+		for _, req := range reqs {
+			entries = append(entries, filestorage.DeleteEntry(req))
+		}
+		if err := r.persist(ctx, entries...); err != nil {
+			return fmt.Errorf("persisted: save: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *Repo) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	if r.purge == nil {
+		return 0, fmt.Errorf("no implement purge methods in repo")
+	}
+	n, err := r.purge.PurgeExpired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 && r.snap != nil {
+		if err := r.persist(ctx, filestorage.PurgeEntry(now)); err != nil {
+			return n, fmt.Errorf("persisted: save: %w", err)
+		}
+	}
+	return n, nil
+}
+
+func (r *Repo) NextVal(ctx context.Context) (int64, error) {
+	if r.seq == nil {
+		return 0, fmt.Errorf("no implement sequence in repo")
+	}
+	return r.seq.NextVal(ctx)
+}
+
+func (r *Repo) CountURLs(ctx context.Context) (int64, error) {
+	if r.count == nil {
+		return 0, fmt.Errorf("no implement count methods in repo")
+	}
+	return r.count.CountURLs(ctx)
+}
+
+func (r *Repo) CountUsers(ctx context.Context) (int64, error) {
+	if r.count == nil {
+		return 0, fmt.Errorf("no implement count methods in repo")
+	}
+	return r.count.CountUsers(ctx)
+}
+
+// persist ставит изменения в очередь отложенной записи или сохраняет сразу: журналу
+// дописывает их, остальным Persister сохраняет весь снимок.
+func (r *Repo) persist(ctx context.Context, entries ...filestorage.Entry) error {
+	if r.wb != nil {
+		return r.enqueue(ctx, entries...)
+	}
+	if r.app != nil {
+		return r.app.Append(entries...)
+	}
+	return r.save()
+}
+
+func (r *Repo) save() error {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+	return r.p.Save(r.snap.Snapshot())
+}
+
+// Flush пишет текущий снимок в Persister; вызывается при остановке, когда писателей уже нет.
+// Журналу сбрасывать нечего: все изменения в нём уже есть.
+func (r *Repo) Flush() error {
+	if r.snap == nil || r.app != nil {
+		return nil
+	}
+	if err := r.save(); err != nil {
+		return fmt.Errorf("persisted: flush: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/IvanOplesnin/url-shortener/internal/repository/psql/query"
+)
+
+func (r *Repo) AddClicks(ctx context.Context, clicks []repository.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	params := query.AddClicksParams{
+		Domains:    make([]string, 0, len(clicks)),
+		ShortUrls:  make([]string, 0, len(clicks)),
+		ClickedAts: make([]time.Time, 0, len(clicks)),
+		Referrers:  make([]string, 0, len(clicks)),
+		UserAgents: make([]string, 0, len(clicks)),
+		Ips:        make([]string, 0, len(clicks)),
+	}
+	for _, c := range clicks {
+		params.Domains = append(params.Domains, c.Domain)
+		params.ShortUrls = append(params.ShortUrls, string(c.ShortURL))
+		params.ClickedAts = append(params.ClickedAts, c.At)
+		params.Referrers = append(params.Referrers, c.Referrer)
+		params.UserAgents = append(params.UserAgents, c.UserAgent)
+		params.Ips = append(params.Ips, c.IP)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := r.queries.AddClicks(ctx, params); err != nil {
+		return fmt.Errorf("psql error AddClicks: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) ClickStats(ctx context.Context, domain string, short repository.ShortURL) (repository.ClickStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	totals, err := r.queries.ClickTotals(ctx, query.ClickTotalsParams{Domain: domain, ShortURL: short})
+	if err != nil {
+		return repository.ClickStats{}, fmt.Errorf("psql error ClickTotals: %w", err)
+	}
+	rows, err := r.queries.ClickDaily(ctx, query.ClickDailyParams{Domain: domain, ShortURL: short})
+	if err != nil {
+		return repository.ClickStats{}, fmt.Errorf("psql error ClickDaily: %w", err)
+	}
+	stats := repository.ClickStats{
+		Total:  totals.Total,
+		Unique: totals.Uniq,
+		Daily:  make([]repository.DailyClicks, 0, len(rows)),
+	}
+	for _, row := range rows {
+		stats.Daily = append(stats.Daily, repository.DailyClicks{Day: row.Day.UTC(), Count: row.Clicks})
+	}
+	return stats, nil
+}
@@ -9,7 +9,12 @@ import (
 )
 
 func Connect(dsn string) (*pgxpool.Pool, error) {
-	db, err := pgxpool.New(context.Background(), dsn)
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ConnConfig.Tracer = queryTracer{}
+	db, err := pgxpool.NewWithConfig(context.Background(), cfg)
 	if err != nil {
 		return nil, err
 	}
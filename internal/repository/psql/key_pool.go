@@ -0,0 +1,49 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+func (r *Repo) ClaimKeys(ctx context.Context, n int) ([]repository.ShortURL, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	keys, err := r.queries.ClaimKeys(ctx, int32(n))
+	if err != nil {
+		return nil, fmt.Errorf("psql error ClaimKeys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *Repo) AddKeys(ctx context.Context, keys []repository.ShortURL) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	arg := make([]string, 0, len(keys))
+	for _, k := range keys {
+		arg = append(arg, string(k))
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	n, err := r.queries.AddKeys(ctx, arg)
+	if err != nil {
+		return 0, fmt.Errorf("psql error AddKeys: %w", err)
+	}
+	return n, nil
+}
+
+func (r *Repo) PoolSize(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	n, err := r.queries.PoolSize(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("psql error PoolSize: %w", err)
+	}
+	return n, nil
+}
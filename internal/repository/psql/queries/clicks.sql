@@ -0,0 +1,30 @@
+-- name: AddClicks :exec
+INSERT INTO clicks (domain, short_url, clicked_at, referrer, user_agent, ip)
+SELECT d.domain, s.short_url, c.clicked_at, r.referrer, ua.user_agent, ip.ip
+FROM unnest(sqlc.arg(domains)::text[])            WITH ORDINALITY AS d(domain, ord)
+JOIN unnest(sqlc.arg(short_urls)::text[])         WITH ORDINALITY AS s(short_url, ord)
+  USING (ord)
+JOIN unnest(sqlc.arg(clicked_ats)::timestamptz[]) WITH ORDINALITY AS c(clicked_at, ord)
+  USING (ord)
+JOIN unnest(sqlc.arg(referrers)::text[])          WITH ORDINALITY AS r(referrer, ord)
+  USING (ord)
+JOIN unnest(sqlc.arg(user_agents)::text[])        WITH ORDINALITY AS ua(user_agent, ord)
+  USING (ord)
+JOIN unnest(sqlc.arg(ips)::text[])                WITH ORDINALITY AS ip(ip, ord)
+  USING (ord);
+
+-- name: ClickTotals :one
+SELECT
+  COUNT(*)::bigint AS total,
+  COUNT(DISTINCT (ip, user_agent))::bigint AS uniq
+FROM clicks
+WHERE domain = $1 AND short_url = $2;
+
+-- name: ClickDaily :many
+SELECT
+  date_trunc('day', clicked_at, 'UTC')::timestamptz AS day,
+  COUNT(*)::bigint AS clicks
+FROM clicks
+WHERE domain = $1 AND short_url = $2
+GROUP BY day
+ORDER BY day;
@@ -1,7 +1,22 @@
 -- name: GetByURLs :many
 SELECT id, short_url, "url"
 FROM alias_url
-WHERE "url" = ANY(sqlc.arg(urls)::text[]);
+WHERE domain = sqlc.arg(domain) AND "url" = ANY(sqlc.arg(urls)::text[])
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now());
+
+-- name: ReleaseExpiredURLs :one
+WITH released AS (
+  DELETE FROM alias_url a
+  WHERE a.domain = sqlc.arg(domain) AND a."url" = ANY(sqlc.arg(urls)::text[])
+    AND a.expires_at IS NOT NULL AND a.expires_at <= now()
+  RETURNING a.domain, a.short_url
+), dropped AS (
+  DELETE FROM clicks c
+  USING released r
+  WHERE c.domain = r.domain AND c.short_url = r.short_url
+)
+SELECT count(*) FROM released;
 
 
 -- name: AddMany :many
@@ -9,19 +24,26 @@ WITH input AS (
   SELECT
     s.short_url,
     u.url,
-    c.created_at
+    c.created_at,
+    us.user_id,
+    NULLIF(e.expires_at, '')::timestamptz AS expires_at
   FROM unnest(sqlc.arg(short_urls)::text[])        WITH ORDINALITY AS s(short_url, ord)
   JOIN unnest(sqlc.arg(urls)::text[])             WITH ORDINALITY AS u(url, ord)
     USING (ord)
   JOIN unnest(sqlc.arg(created_ats)::timestamptz[]) WITH ORDINALITY AS c(created_at, ord)
     USING (ord)
+  JOIN unnest(sqlc.arg(user_ids)::text[])          WITH ORDINALITY AS us(user_id, ord)
+    USING (ord)
+  -- пустая строка — бессрочная ссылка
+  JOIN unnest(sqlc.arg(expires_ats)::text[])       WITH ORDINALITY AS e(expires_at, ord)
+    USING (ord)
 ),
 inserted AS (
-  INSERT INTO alias_url (short_url, "url", created_at)
-  SELECT short_url, url, created_at
+  INSERT INTO alias_url (short_url, "url", created_at, user_id, expires_at, domain)
+  SELECT short_url, url, created_at, user_id, expires_at, sqlc.arg(domain)
   FROM input
   ON CONFLICT DO NOTHING
-  RETURNING id, short_url, "url", created_at
+  RETURNING id, short_url, "url", created_at, user_id, expires_at
 )
-SELECT id, short_url, "url", created_at
+SELECT id, short_url, "url", created_at, user_id, expires_at
 FROM inserted;
@@ -0,0 +1,19 @@
+-- name: ClaimKeys :many
+DELETE FROM key_pool
+WHERE short_url IN (
+    SELECT short_url
+    FROM key_pool
+    LIMIT sqlc.arg(n)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING short_url;
+
+-- name: AddKeys :execrows
+INSERT INTO key_pool (short_url)
+SELECT k
+FROM unnest(sqlc.arg(keys)::text[]) AS k
+WHERE NOT EXISTS (SELECT 1 FROM alias_url a WHERE a.short_url = k)
+ON CONFLICT DO NOTHING;
+
+-- name: PoolSize :one
+SELECT count(*) FROM key_pool;
@@ -1,22 +1,70 @@
 -- name: Search :one
 SELECT short_url 
 FROM alias_url
-WHERE "url" = $1
+WHERE domain = $1 AND "url" = $2
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now())
 LIMIT 1;
 
 -- name: Get :one
-SELECT "url"
+SELECT "url", is_deleted, expires_at
 FROM alias_url
-WHERE short_url = $1;
+WHERE domain = $1 AND short_url = $2;
 
 -- name: Add :exec
 INSERT INTO alias_url (
-    short_url, "url", created_at
+    short_url, "url", created_at, user_id, expires_at, domain
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6
 );
 
 -- name: GetAllRecords :many
 SELECT * 
 FROM alias_url
-ORDER BY id;
\ No newline at end of file
+ORDER BY id;
+
+-- name: GetByUser :many
+SELECT id, short_url, "url", domain
+FROM alias_url
+WHERE user_id = $1
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now())
+ORDER BY id;
+
+-- name: DeleteByUser :exec
+UPDATE alias_url AS a
+SET is_deleted = true
+FROM (
+  SELECT dm.domain, u.user_id, s.short_url
+  FROM unnest(sqlc.arg(domains)::text[])    WITH ORDINALITY AS dm(domain, ord)
+  JOIN unnest(sqlc.arg(user_ids)::text[])   WITH ORDINALITY AS u(user_id, ord)
+    USING (ord)
+  JOIN unnest(sqlc.arg(short_urls)::text[]) WITH ORDINALITY AS s(short_url, ord)
+    USING (ord)
+) AS d
+WHERE a.domain = d.domain AND a.user_id = d.user_id AND a.short_url = d.short_url;
+
+-- name: PurgeExpired :one
+WITH purged AS (
+  DELETE FROM alias_url a
+  WHERE a.expires_at IS NOT NULL AND a.expires_at <= sqlc.arg(now)::timestamptz
+  RETURNING a.domain, a.short_url
+), dropped AS (
+  DELETE FROM clicks c
+  USING purged p
+  WHERE c.domain = p.domain AND c.short_url = p.short_url
+)
+SELECT count(*) FROM purged;
+
+-- name: CountURLs :one
+SELECT count(*)
+FROM alias_url
+WHERE NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now());
+
+-- name: CountUsers :one
+SELECT count(DISTINCT user_id)
+FROM alias_url
+WHERE user_id <> ''
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now());
@@ -0,0 +1,2 @@
+-- name: NextCodeSeq :one
+SELECT nextval('short_code_seq')::bigint;
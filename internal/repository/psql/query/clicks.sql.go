@@ -0,0 +1,115 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: clicks.sql
+
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+const addClicks = `-- name: AddClicks :exec
+INSERT INTO clicks (domain, short_url, clicked_at, referrer, user_agent, ip)
+SELECT d.domain, s.short_url, c.clicked_at, r.referrer, ua.user_agent, ip.ip
+FROM unnest($1::text[])            WITH ORDINALITY AS d(domain, ord)
+JOIN unnest($2::text[])         WITH ORDINALITY AS s(short_url, ord)
+  USING (ord)
+JOIN unnest($3::timestamptz[]) WITH ORDINALITY AS c(clicked_at, ord)
+  USING (ord)
+JOIN unnest($4::text[])          WITH ORDINALITY AS r(referrer, ord)
+  USING (ord)
+JOIN unnest($5::text[])        WITH ORDINALITY AS ua(user_agent, ord)
+  USING (ord)
+JOIN unnest($6::text[])                WITH ORDINALITY AS ip(ip, ord)
+  USING (ord)
+`
+
+type AddClicksParams struct {
+	Domains    []string
+	ShortUrls  []string
+	ClickedAts []time.Time
+	Referrers  []string
+	UserAgents []string
+	Ips        []string
+}
+
+func (q *Queries) AddClicks(ctx context.Context, arg AddClicksParams) error {
+	_, err := q.db.Exec(ctx, addClicks,
+		arg.Domains,
+		arg.ShortUrls,
+		arg.ClickedAts,
+		arg.Referrers,
+		arg.UserAgents,
+		arg.Ips,
+	)
+	return err
+}
+
+const clickDaily = `-- name: ClickDaily :many
+SELECT
+  date_trunc('day', clicked_at, 'UTC')::timestamptz AS day,
+  COUNT(*)::bigint AS clicks
+FROM clicks
+WHERE domain = $1 AND short_url = $2
+GROUP BY day
+ORDER BY day
+`
+
+type ClickDailyParams struct {
+	Domain   string
+	ShortURL repository.ShortURL
+}
+
+type ClickDailyRow struct {
+	Day    time.Time
+	Clicks int64
+}
+
+func (q *Queries) ClickDaily(ctx context.Context, arg ClickDailyParams) ([]ClickDailyRow, error) {
+	rows, err := q.db.Query(ctx, clickDaily, arg.Domain, arg.ShortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClickDailyRow
+	for rows.Next() {
+		var i ClickDailyRow
+		if err := rows.Scan(&i.Day, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clickTotals = `-- name: ClickTotals :one
+SELECT
+  COUNT(*)::bigint AS total,
+  COUNT(DISTINCT (ip, user_agent))::bigint AS uniq
+FROM clicks
+WHERE domain = $1 AND short_url = $2
+`
+
+type ClickTotalsParams struct {
+	Domain   string
+	ShortURL repository.ShortURL
+}
+
+type ClickTotalsRow struct {
+	Total int64
+	Uniq  int64
+}
+
+func (q *Queries) ClickTotals(ctx context.Context, arg ClickTotalsParams) (ClickTotalsRow, error) {
+	row := q.db.QueryRow(ctx, clickTotals, arg.Domain, arg.ShortURL)
+	var i ClickTotalsRow
+	err := row.Scan(&i.Total, &i.Uniq)
+	return i, err
+}
@@ -17,21 +17,28 @@ WITH input AS (
   SELECT
     s.short_url,
     u.url,
-    c.created_at
+    c.created_at,
+    us.user_id,
+    NULLIF(e.expires_at, '')::timestamptz AS expires_at
   FROM unnest($1::text[])        WITH ORDINALITY AS s(short_url, ord)
   JOIN unnest($2::text[])             WITH ORDINALITY AS u(url, ord)
     USING (ord)
   JOIN unnest($3::timestamptz[]) WITH ORDINALITY AS c(created_at, ord)
     USING (ord)
+  JOIN unnest($4::text[])          WITH ORDINALITY AS us(user_id, ord)
+    USING (ord)
+  -- пустая строка — бессрочная ссылка
+  JOIN unnest($5::text[])       WITH ORDINALITY AS e(expires_at, ord)
+    USING (ord)
 ),
 inserted AS (
-  INSERT INTO alias_url (short_url, "url", created_at)
-  SELECT short_url, url, created_at
+  INSERT INTO alias_url (short_url, "url", created_at, user_id, expires_at, domain)
+  SELECT short_url, url, created_at, user_id, expires_at, $6
   FROM input
   ON CONFLICT DO NOTHING
-  RETURNING id, short_url, "url", created_at
+  RETURNING id, short_url, "url", created_at, user_id, expires_at
 )
-SELECT id, short_url, "url", created_at
+SELECT id, short_url, "url", created_at, user_id, expires_at
 FROM inserted
 `
 
@@ -39,6 +46,9 @@ type AddManyParams struct {
 	ShortUrls  []string
 	Urls       []string
 	CreatedAts []time.Time
+	UserIds    []string
+	ExpiresAts []string
+	Domain     string
 }
 
 type AddManyRow struct {
@@ -46,10 +56,19 @@ type AddManyRow struct {
 	ShortURL  string
 	URL       string
 	CreatedAt time.Time
+	UserID    string
+	ExpiresAt *time.Time
 }
 
 func (q *Queries) AddMany(ctx context.Context, arg AddManyParams) ([]AddManyRow, error) {
-	rows, err := q.db.Query(ctx, addMany, arg.ShortUrls, arg.Urls, arg.CreatedAts)
+	rows, err := q.db.Query(ctx, addMany,
+		arg.ShortUrls,
+		arg.Urls,
+		arg.CreatedAts,
+		arg.UserIds,
+		arg.ExpiresAts,
+		arg.Domain,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +81,8 @@ func (q *Queries) AddMany(ctx context.Context, arg AddManyParams) ([]AddManyRow,
 			&i.ShortURL,
 			&i.URL,
 			&i.CreatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
 		); err != nil {
 			return nil, err
 		}
@@ -76,17 +97,24 @@ func (q *Queries) AddMany(ctx context.Context, arg AddManyParams) ([]AddManyRow,
 const getByURLs = `-- name: GetByURLs :many
 SELECT id, short_url, "url"
 FROM alias_url
-WHERE "url" = ANY($1::text[])
+WHERE domain = $1 AND "url" = ANY($2::text[])
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now())
 `
 
+type GetByURLsParams struct {
+	Domain string
+	Urls   []string
+}
+
 type GetByURLsRow struct {
 	ID       int64
 	ShortURL repository.ShortURL
 	URL      repository.URL
 }
 
-func (q *Queries) GetByURLs(ctx context.Context, urls []string) ([]GetByURLsRow, error) {
-	rows, err := q.db.Query(ctx, getByURLs, urls)
+func (q *Queries) GetByURLs(ctx context.Context, arg GetByURLsParams) ([]GetByURLsRow, error) {
+	rows, err := q.db.Query(ctx, getByURLs, arg.Domain, arg.Urls)
 	if err != nil {
 		return nil, err
 	}
@@ -104,3 +132,29 @@ func (q *Queries) GetByURLs(ctx context.Context, urls []string) ([]GetByURLsRow,
 	}
 	return items, nil
 }
+
+const releaseExpiredURLs = `-- name: ReleaseExpiredURLs :one
+WITH released AS (
+  DELETE FROM alias_url a
+  WHERE a.domain = $1 AND a."url" = ANY($2::text[])
+    AND a.expires_at IS NOT NULL AND a.expires_at <= now()
+  RETURNING a.domain, a.short_url
+), dropped AS (
+  DELETE FROM clicks c
+  USING released r
+  WHERE c.domain = r.domain AND c.short_url = r.short_url
+)
+SELECT count(*) FROM released
+`
+
+type ReleaseExpiredURLsParams struct {
+	Domain string
+	Urls   []string
+}
+
+func (q *Queries) ReleaseExpiredURLs(ctx context.Context, arg ReleaseExpiredURLsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, releaseExpiredURLs, arg.Domain, arg.Urls)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
@@ -0,0 +1,70 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: key_pool.sql
+
+package query
+
+import (
+	"context"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+const addKeys = `-- name: AddKeys :execrows
+INSERT INTO key_pool (short_url)
+SELECT k
+FROM unnest($1::text[]) AS k
+WHERE NOT EXISTS (SELECT 1 FROM alias_url a WHERE a.short_url = k)
+ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) AddKeys(ctx context.Context, keys []string) (int64, error) {
+	result, err := q.db.Exec(ctx, addKeys, keys)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const claimKeys = `-- name: ClaimKeys :many
+DELETE FROM key_pool
+WHERE short_url IN (
+    SELECT short_url
+    FROM key_pool
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING short_url
+`
+
+func (q *Queries) ClaimKeys(ctx context.Context, n int32) ([]repository.ShortURL, error) {
+	rows, err := q.db.Query(ctx, claimKeys, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []repository.ShortURL
+	for rows.Next() {
+		var short_url repository.ShortURL
+		if err := rows.Scan(&short_url); err != nil {
+			return nil, err
+		}
+		items = append(items, short_url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const poolSize = `-- name: PoolSize :one
+SELECT count(*) FROM key_pool
+`
+
+func (q *Queries) PoolSize(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, poolSize)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
@@ -15,4 +15,22 @@ type AliasUrl struct {
 	URL       repository.URL
 	ShortURL  repository.ShortURL
 	CreatedAt time.Time
+	UserID    string
+	IsDeleted bool
+	ExpiresAt *time.Time
+	Domain    string
+}
+
+type Click struct {
+	ID        int64
+	ShortURL  repository.ShortURL
+	ClickedAt time.Time
+	Referrer  string
+	UserAgent string
+	Ip        string
+	Domain    string
+}
+
+type KeyPool struct {
+	ShortURL repository.ShortURL
 }
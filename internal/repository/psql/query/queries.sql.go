@@ -14,9 +14,9 @@ import (
 
 const add = `-- name: Add :exec
 INSERT INTO alias_url (
-    short_url, "url", created_at
+    short_url, "url", created_at, user_id, expires_at, domain
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6
 )
 `
 
@@ -24,28 +24,103 @@ type AddParams struct {
 	ShortURL  repository.ShortURL
 	URL       repository.URL
 	CreatedAt time.Time
+	UserID    string
+	ExpiresAt *time.Time
+	Domain    string
 }
 
 func (q *Queries) Add(ctx context.Context, arg AddParams) error {
-	_, err := q.db.Exec(ctx, add, arg.ShortURL, arg.URL, arg.CreatedAt)
+	_, err := q.db.Exec(ctx, add,
+		arg.ShortURL,
+		arg.URL,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.Domain,
+	)
+	return err
+}
+
+const countURLs = `-- name: CountURLs :one
+SELECT count(*)
+FROM alias_url
+WHERE NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now())
+`
+
+func (q *Queries) CountURLs(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countURLs)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUsers = `-- name: CountUsers :one
+SELECT count(DISTINCT user_id)
+FROM alias_url
+WHERE user_id <> ''
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now())
+`
+
+func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteByUser = `-- name: DeleteByUser :exec
+UPDATE alias_url AS a
+SET is_deleted = true
+FROM (
+  SELECT dm.domain, u.user_id, s.short_url
+  FROM unnest($1::text[])    WITH ORDINALITY AS dm(domain, ord)
+  JOIN unnest($2::text[])   WITH ORDINALITY AS u(user_id, ord)
+    USING (ord)
+  JOIN unnest($3::text[]) WITH ORDINALITY AS s(short_url, ord)
+    USING (ord)
+) AS d
+WHERE a.domain = d.domain AND a.user_id = d.user_id AND a.short_url = d.short_url
+`
+
+type DeleteByUserParams struct {
+	Domains   []string
+	UserIds   []string
+	ShortUrls []string
+}
+
+func (q *Queries) DeleteByUser(ctx context.Context, arg DeleteByUserParams) error {
+	_, err := q.db.Exec(ctx, deleteByUser, arg.Domains, arg.UserIds, arg.ShortUrls)
 	return err
 }
 
 const get = `-- name: Get :one
-SELECT "url"
+SELECT "url", is_deleted, expires_at
 FROM alias_url
-WHERE short_url = $1
+WHERE domain = $1 AND short_url = $2
 `
 
-func (q *Queries) Get(ctx context.Context, shortUrl repository.ShortURL) (repository.URL, error) {
-	row := q.db.QueryRow(ctx, get, shortUrl)
-	var url repository.URL
-	err := row.Scan(&url)
-	return url, err
+type GetParams struct {
+	Domain   string
+	ShortURL repository.ShortURL
+}
+
+type GetRow struct {
+	URL       repository.URL
+	IsDeleted bool
+	ExpiresAt *time.Time
+}
+
+func (q *Queries) Get(ctx context.Context, arg GetParams) (GetRow, error) {
+	row := q.db.QueryRow(ctx, get, arg.Domain, arg.ShortURL)
+	var i GetRow
+	err := row.Scan(&i.URL, &i.IsDeleted, &i.ExpiresAt)
+	return i, err
 }
 
 const getAllRecords = `-- name: GetAllRecords :many
-SELECT id, url, short_url, created_at 
+SELECT id, url, short_url, created_at, user_id, is_deleted, expires_at, domain 
 FROM alias_url
 ORDER BY id
 `
@@ -64,6 +139,10 @@ func (q *Queries) GetAllRecords(ctx context.Context) ([]AliasUrl, error) {
 			&i.URL,
 			&i.ShortURL,
 			&i.CreatedAt,
+			&i.UserID,
+			&i.IsDeleted,
+			&i.ExpiresAt,
+			&i.Domain,
 		); err != nil {
 			return nil, err
 		}
@@ -75,15 +154,83 @@ func (q *Queries) GetAllRecords(ctx context.Context) ([]AliasUrl, error) {
 	return items, nil
 }
 
+const getByUser = `-- name: GetByUser :many
+SELECT id, short_url, "url", domain
+FROM alias_url
+WHERE user_id = $1
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now())
+ORDER BY id
+`
+
+type GetByUserRow struct {
+	ID       int64
+	ShortURL repository.ShortURL
+	URL      repository.URL
+	Domain   string
+}
+
+func (q *Queries) GetByUser(ctx context.Context, userID string) ([]GetByUserRow, error) {
+	rows, err := q.db.Query(ctx, getByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetByUserRow
+	for rows.Next() {
+		var i GetByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortURL,
+			&i.URL,
+			&i.Domain,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeExpired = `-- name: PurgeExpired :one
+WITH purged AS (
+  DELETE FROM alias_url a
+  WHERE a.expires_at IS NOT NULL AND a.expires_at <= $1::timestamptz
+  RETURNING a.domain, a.short_url
+), dropped AS (
+  DELETE FROM clicks c
+  USING purged p
+  WHERE c.domain = p.domain AND c.short_url = p.short_url
+)
+SELECT count(*) FROM purged
+`
+
+func (q *Queries) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	row := q.db.QueryRow(ctx, purgeExpired, now)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const search = `-- name: Search :one
 SELECT short_url 
 FROM alias_url
-WHERE "url" = $1
+WHERE domain = $1 AND "url" = $2
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > now())
 LIMIT 1
 `
 
-func (q *Queries) Search(ctx context.Context, url repository.URL) (repository.ShortURL, error) {
-	row := q.db.QueryRow(ctx, search, url)
+type SearchParams struct {
+	Domain string
+	URL    repository.URL
+}
+
+func (q *Queries) Search(ctx context.Context, arg SearchParams) (repository.ShortURL, error) {
+	row := q.db.QueryRow(ctx, search, arg.Domain, arg.URL)
 	var short_url repository.ShortURL
 	err := row.Scan(&short_url)
 	return short_url, err
@@ -0,0 +1,21 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: sequence.sql
+
+package query
+
+import (
+	"context"
+)
+
+const nextCodeSeq = `-- name: NextCodeSeq :one
+SELECT nextval('short_code_seq')::bigint
+`
+
+func (q *Queries) NextCodeSeq(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, nextCodeSeq)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
@@ -17,6 +17,8 @@ import (
 type Repo struct {
 	db      *pgxpool.Pool
 	queries *query.Queries
+	// domain в котором ищут и создают ссылки Get, Search, Add и пакетные методы
+	domain string
 }
 
 func NewRepo(db *pgxpool.Pool) *Repo {
@@ -29,20 +31,26 @@ func NewRepo(db *pgxpool.Pool) *Repo {
 func (r *Repo) Get(ctx context.Context, shortURL repository.ShortURL) (repository.URL, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
-	url, err := r.queries.Get(ctx, shortURL)
+	row, err := r.queries.Get(ctx, query.GetParams{Domain: r.domain, ShortURL: shortURL})
 	if errors.Is(err, pgx.ErrNoRows) {
 		return repository.URL(""), repository.ErrNotFoundShortURL
 	}
 	if err != nil {
 		return repository.URL(""), fmt.Errorf("psql error Get: %w", err)
 	}
-	return repository.URL(url), nil
+	if row.IsDeleted {
+		return repository.URL(""), repository.ErrDeleted
+	}
+	if row.ExpiresAt != nil && !time.Now().Before(*row.ExpiresAt) {
+		return repository.URL(""), repository.ErrExpired
+	}
+	return row.URL, nil
 }
 
 func (r *Repo) Search(ctx context.Context, url repository.URL) (repository.ShortURL, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
-	shortURL, err := r.queries.Search(ctx, url)
+	shortURL, err := r.queries.Search(ctx, query.SearchParams{Domain: r.domain, URL: url})
 	if errors.Is(err, pgx.ErrNoRows) {
 		return repository.ShortURL(shortURL), repository.ErrNotFoundURL
 	}
@@ -52,18 +60,36 @@ func (r *Repo) Search(ctx context.Context, url repository.URL) (repository.Short
 	return repository.ShortURL(shortURL), nil
 }
 
-func (r *Repo) Add(ctx context.Context, shortURL repository.ShortURL, url repository.URL) error {
+func (r *Repo) Add(ctx context.Context, rec repository.Record) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	now := time.Now().UTC()
-	params := query.AddParams{ShortURL: shortURL, URL: url, CreatedAt: now}
-	if err := r.queries.Add(ctx, params); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			switch pgErr.ConstraintName {
-			case "alias_url_short_url_uk":
+	shortURL, url := rec.ShortURL, rec.URL
+	params := query.AddParams{
+		ShortURL:  shortURL,
+		URL:       url,
+		CreatedAt: now,
+		UserID:    rec.UserID,
+		ExpiresAt: rec.ExpiresAt,
+		Domain:    r.domain,
+	}
+	err := r.queries.Add(ctx, params)
+	if c, ok := uniqueViolation(err); ok && c == "alias_url_domain_url_uk" {
+		// URL может держать просроченная ссылка: её место занимает новая
+		n, relErr := r.queries.ReleaseExpiredURLs(ctx, query.ReleaseExpiredURLsParams{Domain: r.domain, Urls: []string{string(url)}})
+		if relErr != nil {
+			return fmt.Errorf("psql error Add: %w", relErr)
+		}
+		if n > 0 {
+			err = r.queries.Add(ctx, params)
+		}
+	}
+	if err != nil {
+		if c, ok := uniqueViolation(err); ok {
+			switch c {
+			case "alias_url_domain_short_url_uk":
 				return fmt.Errorf("%w: %v", repository.ErrShortURLAlreadyExists, shortURL)
-			case "alias_url_url_uk":
+			case "alias_url_domain_url_uk":
 				return fmt.Errorf("%w: %v", repository.ErrAlreadyExists, url)
 			default:
 				return fmt.Errorf("%w", repository.ErrAlreadyExists)
@@ -75,6 +101,15 @@ func (r *Repo) Add(ctx context.Context, shortURL repository.ShortURL, url reposi
 	return nil
 }
 
+// uniqueViolation имя нарушенного уникального ограничения.
+func uniqueViolation(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return "", false
+	}
+	return pgErr.ConstraintName, true
+}
+
 func (r *Repo) Snapshot() []repository.Record {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -86,9 +121,13 @@ func (r *Repo) Snapshot() []repository.Record {
 	recs := make([]repository.Record, 0, len(rows))
 	for _, r := range rows {
 		recs = append(recs, repository.Record{
-			ID:       int(r.ID),
-			URL:      r.URL,
-			ShortURL: r.ShortURL,
+			ID:        int(r.ID),
+			URL:       r.URL,
+			ShortURL:  r.ShortURL,
+			UserID:    r.UserID,
+			Deleted:   r.IsDeleted,
+			ExpiresAt: r.ExpiresAt,
+			Domain:    r.Domain,
 		})
 	}
 	return recs
@@ -100,7 +139,7 @@ func (r *Repo) GetByURLs(ctx context.Context, urls []string) ([]repository.Recor
 	if len(urls) == 0 {
 		return []repository.Record{}, nil
 	} else {
-		rows, err := r.queries.GetByURLs(ctx, urls)
+		rows, err := r.queries.GetByURLs(ctx, query.GetByURLsParams{Domain: r.domain, Urls: urls})
 		if err != nil {
 			return nil, fmt.Errorf("psql error GetByURLs: %w", err)
 		}
@@ -110,6 +149,7 @@ func (r *Repo) GetByURLs(ctx context.Context, urls []string) ([]repository.Recor
 				ID:       int(row.ID),
 				URL:      row.URL,
 				ShortURL: row.ShortURL,
+				Domain:   r.domain,
 			})
 		}
 		return records, nil
@@ -123,19 +163,35 @@ func (r *Repo) AddMany(ctx context.Context, records []repository.ArgAddMany) ([]
 		shortURLs := make([]string, 0, len(records))
 		urls := make([]string, 0, len(records))
 		times := make([]time.Time, 0, len(records))
+		userIDs := make([]string, 0, len(records))
+		expiresAts := make([]string, 0, len(records))
 		now := time.Now().UTC()
 		for _, rec := range records {
 			shortURLs = append(shortURLs, string(rec.ShortURL))
 			urls = append(urls, string(rec.URL))
 			times = append(times, now)
+			userIDs = append(userIDs, rec.UserID)
+			expiresAt := ""
+			if rec.ExpiresAt != nil {
+				expiresAt = rec.ExpiresAt.UTC().Format(time.RFC3339Nano)
+			}
+			expiresAts = append(expiresAts, expiresAt)
 		}
 		paramsAddMany := query.AddManyParams{
 			ShortUrls:  shortURLs,
 			Urls:       urls,
 			CreatedAts: times,
+			UserIds:    userIDs,
+			ExpiresAts: expiresAts,
+			Domain:     r.domain,
 		}
 		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 		defer cancel()
+		// просроченные ссылки уступают URL новым, иначе ON CONFLICT их молча пропустит
+		_, err := r.queries.ReleaseExpiredURLs(ctx, query.ReleaseExpiredURLsParams{Domain: r.domain, Urls: urls})
+		if err != nil {
+			return nil, fmt.Errorf("psql error AddMany: %w", err)
+		}
 		inserts, err := r.queries.AddMany(ctx, paramsAddMany)
 		if err != nil {
 			return nil, fmt.Errorf("psql error AddMany: %w", err)
@@ -146,22 +202,102 @@ func (r *Repo) AddMany(ctx context.Context, records []repository.ArgAddMany) ([]
 		res := make([]repository.Record, 0, len(inserts))
 		for _, insert := range inserts {
 			res = append(res, repository.Record{
-				ID:       int(insert.ID),
-				URL:      repository.URL(insert.URL),
-				ShortURL: repository.ShortURL(insert.ShortURL),
+				ID:        int(insert.ID),
+				URL:       repository.URL(insert.URL),
+				ShortURL:  repository.ShortURL(insert.ShortURL),
+				UserID:    insert.UserID,
+				ExpiresAt: insert.ExpiresAt,
+				Domain:    r.domain,
 			})
 		}
 		return res, nil
 	}
 }
 
+func (r *Repo) GetByUser(ctx context.Context, userID string) ([]repository.Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	rows, err := r.queries.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("psql error GetByUser: %w", err)
+	}
+	records := make([]repository.Record, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, repository.Record{
+			ID:       int(row.ID),
+			URL:      row.URL,
+			ShortURL: row.ShortURL,
+			UserID:   userID,
+			Domain:   row.Domain,
+		})
+	}
+	return records, nil
+}
+
+// DeleteByUser помечает ссылки удалёнными одним UPDATE; чужие ссылки не затрагиваются.
+func (r *Repo) DeleteByUser(ctx context.Context, reqs []repository.DeleteRequest) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+	domains := make([]string, 0, len(reqs))
+	userIDs := make([]string, 0, len(reqs))
+	shortURLs := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		domains = append(domains, req.Domain)
+		userIDs = append(userIDs, req.UserID)
+		shortURLs = append(shortURLs, string(req.ShortURL))
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	params := query.DeleteByUserParams{Domains: domains, UserIds: userIDs, ShortUrls: shortURLs}
+	if err := r.queries.DeleteByUser(ctx, params); err != nil {
+		return fmt.Errorf("psql error DeleteByUser: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	n, err := r.queries.PurgeExpired(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("psql error PurgeExpired: %w", err)
+	}
+	return n, nil
+}
+
+func (r *Repo) CountURLs(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	n, err := r.queries.CountURLs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("psql error CountURLs: %w", err)
+	}
+	return n, nil
+}
+
+func (r *Repo) CountUsers(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	n, err := r.queries.CountUsers(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("psql error CountUsers: %w", err)
+	}
+	return n, nil
+}
+
+// InDomain то же хранилище, но ссылки ищутся и создаются в domain.
+func (r *Repo) InDomain(domain string) (repository.Repository, error) {
+	return &Repo{db: r.db, queries: r.queries, domain: domain}, nil
+}
+
 // InTx(ctx context.Context, fn func(r Repository) error) error
 func (r *Repo) InTx(ctx context.Context, fn func(r repository.Repository) error) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
-	txRepo := &Repo{db: r.db, queries: r.queries.WithTx(tx)}
+	txRepo := &Repo{db: r.db, queries: r.queries.WithTx(tx), domain: r.domain}
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	if err := fn(txRepo); err != nil {
@@ -173,3 +309,13 @@ func (r *Repo) InTx(ctx context.Context, fn func(r repository.Repository) error)
 	}
 	return nil
 }
+
+func (r *Repo) NextVal(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	v, err := r.queries.NextCodeSeq(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("psql error NextVal: %w", err)
+	}
+	return v, nil
+}
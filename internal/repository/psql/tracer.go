@@ -0,0 +1,56 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/IvanOplesnin/url-shortener/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/IvanOplesnin/url-shortener/internal/repository/psql")
+
+// queryTracer открывает спан на каждый запрос пула, в том числе внутри транзакций.
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = tracer.Start(ctx, "psql "+queryName(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.query.text", data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	err := data.Err
+	// пустой результат — обычный ответ, а не сбой запроса
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+	}
+	if err == nil {
+		span.SetAttributes(attribute.Int64("db.response.returned_rows", data.CommandTag.RowsAffected()))
+	}
+	tracing.End(span, err)
+}
+
+// queryName имя из комментария sqlc "-- name: Get :one", иначе первое слово запроса.
+func queryName(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if rest, ok := strings.CutPrefix(sql, "-- name: "); ok {
+		if name, _, ok := strings.Cut(rest, " "); ok {
+			return name
+		}
+	}
+	if word, _, ok := strings.Cut(sql, " "); ok {
+		return strings.ToUpper(word)
+	}
+	return strings.ToUpper(sql)
+}
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Переходы хранятся уже свёрнутыми: сырые события для статистики не нужны.
+const (
+	// clicksPrefix domain/short → хеш день (YYYY-MM-DD в UTC) → число переходов
+	clicksPrefix = "shortener:clicks:"
+	// visitorsPrefix domain/short → множество ip и user-agent через нулевой байт
+	visitorsPrefix = "shortener:visitors:"
+)
+
+func (r *Repo) AddClicks(ctx context.Context, clicks []repo.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := r.rdb.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, c := range clicks {
+			m := member(c.Domain, c.ShortURL)
+			pipe.HIncrBy(ctx, clicksPrefix+m, c.At.UTC().Format(time.DateOnly), 1)
+			pipe.SAdd(ctx, visitorsPrefix+m, c.IP+"\x00"+c.UserAgent)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis error AddClicks: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) ClickStats(ctx context.Context, domain string, short repo.ShortURL) (repo.ClickStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	m := member(domain, short)
+	pipe := r.rdb.Pipeline()
+	days := pipe.HGetAll(ctx, clicksPrefix+m)
+	unique := pipe.SCard(ctx, visitorsPrefix+m)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return repo.ClickStats{}, fmt.Errorf("redis error ClickStats: %w", err)
+	}
+
+	stats := repo.ClickStats{
+		Unique: unique.Val(),
+		Daily:  make([]repo.DailyClicks, 0, len(days.Val())),
+	}
+	for day, v := range days.Val() {
+		at, err := time.Parse(time.DateOnly, day)
+		if err != nil {
+			return repo.ClickStats{}, fmt.Errorf("redis error ClickStats: day %q: %w", day, err)
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return repo.ClickStats{}, fmt.Errorf("redis error ClickStats: day %q: %w", day, err)
+		}
+		stats.Total += n
+		stats.Daily = append(stats.Daily, repo.DailyClicks{Day: at, Count: n})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool { return stats.Daily[i].Day.Before(stats.Daily[j].Day) })
+	return stats, nil
+}
@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// IsDSN сообщает, что DATABASE_DSN указывает на Redis: redis://host:6379/0 или rediss:// с TLS.
+func IsDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "redis://") || strings.HasPrefix(dsn, "rediss://")
+}
+
+func Connect(dsn string) (*goredis.Client, error) {
+	opts, err := goredis.ParseURL(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("redis parse dsn: %w", err)
+	}
+	rdb := goredis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return nil, fmt.Errorf("redis ping: %w", err)
+	}
+	return rdb, nil
+}
@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// poolKey множество свободных коротких id, общее для всех реплик.
+const poolKey = "shortener:pool"
+
+// addKeysScript кладёт в пул ключи, которые не заняты ссылкой в основном домене;
+// занятый в другом домене ключ отсеет сама вставка.
+var addKeysScript = goredis.NewScript(`
+local n = 0
+for i, k in ipairs(ARGV) do
+	if redis.call('EXISTS', KEYS[i + 1]) == 0 then
+		n = n + redis.call('SADD', KEYS[1], k)
+	end
+end
+return n
+`)
+
+func (r *Repo) ClaimKeys(ctx context.Context, n int) ([]repo.ShortURL, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	keys, err := r.rdb.SPopN(ctx, poolKey, int64(n)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis error ClaimKeys: %w", err)
+	}
+	out := make([]repo.ShortURL, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, repo.ShortURL(k))
+	}
+	return out, nil
+}
+
+func (r *Repo) AddKeys(ctx context.Context, keys []repo.ShortURL) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	redisKeys := make([]string, 0, len(keys)+1)
+	redisKeys = append(redisKeys, poolKey)
+	args := make([]any, 0, len(keys))
+	for _, k := range keys {
+		redisKeys = append(redisKeys, shortKey("", k))
+		args = append(args, string(k))
+	}
+	n, err := addKeysScript.Run(ctx, r.rdb, redisKeys, args...).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("redis error AddKeys: %w", err)
+	}
+	return n, nil
+}
+
+func (r *Repo) PoolSize(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	n, err := r.rdb.SCard(ctx, poolKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis error PoolSize: %w", err)
+	}
+	return n, nil
+}
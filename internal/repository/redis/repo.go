@@ -0,0 +1,503 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	repo "github.com/IvanOplesnin/url-shortener/internal/repository"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Ключи в Redis. Домен отделён от кода и URL косой чертой: в имени хоста её не бывает.
+const (
+	// shortPrefix domain/short → запись в JSON
+	shortPrefix = "shortener:s:"
+	// urlPrefix domain/url → short, только для живых ссылок; истекает вместе со ссылкой
+	urlPrefix = "shortener:u:"
+	// userPrefix множество domain/short ссылок пользователя
+	userPrefix = "shortener:user:"
+	// expiresKey domain/short ссылок со сроком жизни, score — момент истечения в мс
+	expiresKey = "shortener:expires"
+	idKey      = "shortener:id"
+	seqKey     = "shortener:seq"
+)
+
+// Результаты insertScript.
+const (
+	inserted         = 0
+	shortURLConflict = 1
+	urlConflict      = 2
+)
+
+// insertScript кладёт обе стороны ссылки атомарно: если URL уже занят, только что
+// поставленный код снимается, и реплики не видят половину записи. Ключ URL живёт
+// столько же, сколько ссылка: просроченная ссылка URL не держит.
+var insertScript = goredis.NewScript(`
+if not redis.call('SET', KEYS[1], ARGV[1], 'NX') then
+	return 1
+end
+local url
+if ARGV[4] ~= '' then
+	url = redis.call('SET', KEYS[2], ARGV[2], 'NX', 'PXAT', ARGV[4])
+else
+	url = redis.call('SET', KEYS[2], ARGV[2], 'NX')
+end
+if not url then
+	redis.call('DEL', KEYS[1])
+	return 2
+end
+if ARGV[3] ~= '' then
+	redis.call('SADD', KEYS[3], ARGV[3])
+end
+if ARGV[4] ~= '' then
+	redis.call('ZADD', KEYS[4], ARGV[4], ARGV[5])
+end
+return 0
+`)
+
+// releaseScript снимает URL удалённой ссылки, только если он всё ещё ведёт на её код:
+// тот же URL могли уже сократить заново.
+var releaseScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// deleteScript ставит удалённую версию записи, только если запись не поменялась с чтения
+// (compare-and-set), и снимает её URL, если он всё ещё ведёт на этот код. Ссылку, которую
+// тем временем вычистил Sweeper или заняли заново, удаление не тронет.
+var deleteScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2])
+if redis.call('GET', KEYS[2]) == ARGV[3] then
+	redis.call('DEL', KEYS[2])
+end
+return 1
+`)
+
+// Repo хранилище поверх Redis, общее для всех реплик. Копии из InDomain делят с ним
+// клиент и отличаются доменом.
+type Repo struct {
+	rdb    *goredis.Client
+	domain string
+}
+
+func NewRepo(rdb *goredis.Client) *Repo {
+	return &Repo{rdb: rdb}
+}
+
+func (r *Repo) Ping(ctx context.Context) error {
+	return r.rdb.Ping(ctx).Err()
+}
+
+// InDomain то же хранилище, но ссылки ищутся и создаются в domain.
+func (r *Repo) InDomain(domain string) (repo.Repository, error) {
+	return &Repo{rdb: r.rdb, domain: domain}, nil
+}
+
+func member(domain string, short repo.ShortURL) string {
+	return domain + "/" + string(short)
+}
+
+func shortKey(domain string, short repo.ShortURL) string {
+	return shortPrefix + member(domain, short)
+}
+
+func urlKey(domain string, url repo.URL) string {
+	return urlPrefix + domain + "/" + string(url)
+}
+
+func (r *Repo) Get(ctx context.Context, shortURL repo.ShortURL) (repo.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	v, err := r.rdb.Get(ctx, shortKey(r.domain, shortURL)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return "", repo.ErrNotFoundShortURL
+	}
+	if err != nil {
+		return "", fmt.Errorf("redis error Get: %w", err)
+	}
+	var rec repo.Record
+	if err := json.Unmarshal(v, &rec); err != nil {
+		return "", fmt.Errorf("redis error Get: decode %q: %w", shortURL, err)
+	}
+	if rec.Deleted {
+		return "", repo.ErrDeleted
+	}
+	if rec.Expired(time.Now()) {
+		return "", repo.ErrExpired
+	}
+	return rec.URL, nil
+}
+
+func (r *Repo) Search(ctx context.Context, url repo.URL) (repo.ShortURL, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	short, err := r.rdb.Get(ctx, urlKey(r.domain, url)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", repo.ErrNotFoundURL
+	}
+	if err != nil {
+		return "", fmt.Errorf("redis error Search: %w", err)
+	}
+	return repo.ShortURL(short), nil
+}
+
+func (r *Repo) Add(ctx context.Context, rec repo.Record) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	id, err := r.rdb.Incr(ctx, idKey).Result()
+	if err != nil {
+		return fmt.Errorf("redis error Add: %w", err)
+	}
+	rec.ID = int(id) - 1
+	rec.Domain = r.domain
+	keys, args, err := insertArgs(rec)
+	if err != nil {
+		return fmt.Errorf("redis error Add: %w", err)
+	}
+	res, err := insertScript.Run(ctx, r.rdb, keys, args...).Int()
+	if err != nil {
+		return fmt.Errorf("redis error Add: %w", err)
+	}
+	switch res {
+	case shortURLConflict:
+		return fmt.Errorf("%w: %v", repo.ErrShortURLAlreadyExists, rec.ShortURL)
+	case urlConflict:
+		return fmt.Errorf("%w: %v", repo.ErrAlreadyExists, rec.URL)
+	}
+	return nil
+}
+
+func insertArgs(rec repo.Record) ([]string, []any, error) {
+	v, err := json.Marshal(rec)
+	if err != nil {
+		return nil, nil, err
+	}
+	var expires string
+	if rec.ExpiresAt != nil {
+		expires = strconv.FormatInt(rec.ExpiresAt.UnixMilli(), 10)
+	}
+	var user string
+	if rec.UserID != "" {
+		user = member(rec.Domain, rec.ShortURL)
+	}
+	keys := []string{
+		shortKey(rec.Domain, rec.ShortURL),
+		urlKey(rec.Domain, rec.URL),
+		userPrefix + rec.UserID,
+		expiresKey,
+	}
+	args := []any{v, string(rec.ShortURL), user, expires, member(rec.Domain, rec.ShortURL)}
+	return keys, args, nil
+}
+
+func (r *Repo) GetByURLs(ctx context.Context, urls []string) ([]repo.Record, error) {
+	if len(urls) == 0 {
+		return []repo.Record{}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	keys := make([]string, 0, len(urls))
+	for _, u := range urls {
+		keys = append(keys, urlKey(r.domain, repo.URL(u)))
+	}
+	shorts, err := r.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis error GetByURLs: %w", err)
+	}
+	keys = keys[:0]
+	for _, s := range shorts {
+		if s, ok := s.(string); ok {
+			keys = append(keys, shortKey(r.domain, repo.ShortURL(s)))
+		}
+	}
+	records, err := r.records(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("redis error GetByURLs: %w", err)
+	}
+	// ключ URL истекает с точностью до миллисекунды, а часы реплик могут расходиться
+	now := time.Now()
+	out := records[:0]
+	for _, rec := range records {
+		if !rec.Expired(now) {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+// AddMany отправляет вставки одним конвейером. Как и в psql, строки с занятым кодом
+// или URL пропускаются, а в ответ идут только вставленные.
+func (r *Repo) AddMany(ctx context.Context, records []repo.ArgAddMany) ([]repo.Record, error) {
+	if len(records) == 0 {
+		return []repo.Record{}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	last, err := r.rdb.IncrBy(ctx, idKey, int64(len(records))).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis error AddMany: %w", err)
+	}
+	first := int(last) - len(records)
+
+	recs := make([]repo.Record, 0, len(records))
+	cmds := make([]*goredis.Cmd, 0, len(records))
+	_, err = r.rdb.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, a := range records {
+			rec := repo.Record{
+				ID:        first + i,
+				URL:       a.URL,
+				ShortURL:  a.ShortURL,
+				UserID:    a.UserID,
+				ExpiresAt: a.ExpiresAt,
+				Domain:    r.domain,
+			}
+			keys, args, err := insertArgs(rec)
+			if err != nil {
+				return err
+			}
+			// в конвейере скрипт едет целиком: откатиться с EVALSHA на EVAL внутри него нельзя
+			cmds = append(cmds, insertScript.Eval(ctx, pipe, keys, args...))
+			recs = append(recs, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis error AddMany: %w", err)
+	}
+
+	out := make([]repo.Record, 0, len(records))
+	for i, cmd := range cmds {
+		res, err := cmd.Int()
+		if err != nil {
+			return nil, fmt.Errorf("redis error AddMany: %w", err)
+		}
+		if res == inserted {
+			out = append(out, recs[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *Repo) GetByUser(ctx context.Context, userID string) ([]repo.Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	members, err := r.rdb.SMembers(ctx, userPrefix+userID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis error GetByUser: %w", err)
+	}
+	keys := make([]string, 0, len(members))
+	for _, m := range members {
+		keys = append(keys, shortPrefix+m)
+	}
+	records, err := r.records(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("redis error GetByUser: %w", err)
+	}
+
+	now := time.Now()
+	out := make([]repo.Record, 0, len(records))
+	for _, rec := range records {
+		if !rec.Deleted && !rec.Expired(now) {
+			out = append(out, rec)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// DeleteByUser удаляет ссылку только в домене запроса и только у её владельца.
+func (r *Repo) DeleteByUser(ctx context.Context, reqs []repo.DeleteRequest) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	keys := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		keys = append(keys, shortKey(req.Domain, req.ShortURL))
+	}
+	vals, err := r.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return fmt.Errorf("redis error DeleteByUser: %w", err)
+	}
+
+	_, err = r.rdb.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, v := range vals {
+			old, ok := v.(string)
+			if !ok {
+				continue
+			}
+			var rec repo.Record
+			if err := json.Unmarshal([]byte(old), &rec); err != nil {
+				return fmt.Errorf("decode %s: %w", keys[i], err)
+			}
+			if rec.UserID != reqs[i].UserID || rec.Deleted {
+				continue
+			}
+			rec.Deleted = true
+			deleted, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			keys := []string{keys[i], urlKey(rec.Domain, rec.URL)}
+			deleteScript.Eval(ctx, pipe, keys, old, deleted, string(rec.ShortURL))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis error DeleteByUser: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired удаляет ссылки, истёкшие к now, по индексу сроков в expiresKey.
+func (r *Repo) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	members, err := r.rdb.ZRangeByScore(ctx, expiresKey, &goredis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis error PurgeExpired: %w", err)
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+	keys := make([]string, 0, len(members))
+	for _, m := range members {
+		keys = append(keys, shortPrefix+m)
+	}
+	records, err := r.records(ctx, keys)
+	if err != nil {
+		return 0, fmt.Errorf("redis error PurgeExpired: %w", err)
+	}
+
+	_, err = r.rdb.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, rec := range records {
+			m := member(rec.Domain, rec.ShortURL)
+			// код освобождается, и переходы уходят вместе со ссылкой
+			pipe.Del(ctx, shortPrefix+m, clicksPrefix+m, visitorsPrefix+m)
+			releaseScript.Eval(ctx, pipe, []string{urlKey(rec.Domain, rec.URL)}, string(rec.ShortURL))
+			if rec.UserID != "" {
+				pipe.SRem(ctx, userPrefix+rec.UserID, m)
+			}
+		}
+		// записи могло уже не быть, из индекса убираем всё найденное
+		pipe.ZRem(ctx, expiresKey, anySlice(members)...)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("redis error PurgeExpired: %w", err)
+	}
+	return int64(len(records)), nil
+}
+
+// CountURLs считает живые ссылки во всех доменах. Счётчиков в Redis нет, поэтому
+// записи обходятся SCAN-ом; статистику запрашивают редко.
+func (r *Repo) CountURLs(ctx context.Context) (int64, error) {
+	now := time.Now()
+	var n int64
+	err := r.scan(ctx, func(rec repo.Record) {
+		if !rec.Deleted && !rec.Expired(now) {
+			n++
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("redis error CountURLs: %w", err)
+	}
+	return n, nil
+}
+
+// CountUsers считает пользователей, у которых есть хотя бы одна живая ссылка.
+func (r *Repo) CountUsers(ctx context.Context) (int64, error) {
+	now := time.Now()
+	users := make(map[string]struct{})
+	err := r.scan(ctx, func(rec repo.Record) {
+		if rec.UserID != "" && !rec.Deleted && !rec.Expired(now) {
+			users[rec.UserID] = struct{}{}
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("redis error CountUsers: %w", err)
+	}
+	return int64(len(users)), nil
+}
+
+// NextVal счётчик для генераторов коротких id, общий для всех реплик.
+func (r *Repo) NextVal(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	v, err := r.rdb.Incr(ctx, seqKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis error NextVal: %w", err)
+	}
+	return v, nil
+}
+
+// records читает записи по ключам одним MGET; пропавшие ключи пропускаются.
+func (r *Repo) records(ctx context.Context, keys []string) ([]repo.Record, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	vals, err := r.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.Record, 0, len(vals))
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var rec repo.Record
+		if err := json.Unmarshal([]byte(s), &rec); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", keys[i], err)
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+func (r *Repo) scan(ctx context.Context, fn func(rec repo.Record)) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var cursor uint64
+	for {
+		keys, next, err := r.rdb.Scan(ctx, cursor, shortPrefix+"*", 1000).Result()
+		if err != nil {
+			return err
+		}
+		records, err := r.records(ctx, keys)
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			fn(rec)
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func anySlice(ss []string) []any {
+	out := make([]any, 0, len(ss))
+	for _, s := range ss {
+		out = append(out, s)
+	}
+	return out
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 type ShortURL string
@@ -12,9 +13,11 @@ var ErrNotFoundShortURL = errors.New("not found shortURL")
 var ErrNotFoundURL = errors.New("not found URL")
 var ErrAlreadyExists = errors.New("already exists URL")
 var ErrShortURLAlreadyExists = errors.New("already exist ShortURL")
+var ErrDeleted = errors.New("deleted shortURL")
+var ErrExpired = errors.New("expired shortURL")
 
 type Repository interface {
-	Add(ctx context.Context, key ShortURL, value URL) error
+	Add(ctx context.Context, rec Record) error
 	Get(ctx context.Context, key ShortURL) (URL, error)
 	Search(ctx context.Context, url URL) (ShortURL, error)
 }
@@ -25,6 +28,25 @@ type BatchRepo interface {
 	AddMany(ctx context.Context, records []ArgAddMany) ([]Record, error)
 }
 
+type UserRepo interface {
+	GetByUser(ctx context.Context, userID string) ([]Record, error)
+}
+
+type DeleteRepo interface {
+	DeleteByUser(ctx context.Context, reqs []DeleteRequest) error
+}
+
+type ExpiredPurger interface {
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// CountRepo счётчики для внутренней статистики: живые ссылки во всех доменах
+// и пользователи, создавшие хотя бы одну ссылку.
+type CountRepo interface {
+	CountURLs(ctx context.Context) (int64, error)
+	CountUsers(ctx context.Context) (int64, error)
+}
+
 type Seeder interface {
 	Seed([]Record)
 }
@@ -38,12 +60,44 @@ type Rollback interface {
 }
 
 type Record struct {
-	ID       int      `json:"id"`
-	URL      URL      `json:"url"`
-	ShortURL ShortURL `json:"short_url"`
+	ID        int        `json:"id"`
+	URL       URL        `json:"url"`
+	ShortURL  ShortURL   `json:"short_url"`
+	UserID    string     `json:"user_id,omitempty"`
+	Deleted   bool       `json:"is_deleted,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Domain дополнительный короткий домен; пустой — основной из BaseURL.
+	Domain string `json:"domain,omitempty"`
 }
 
 type ArgAddMany struct {
-	URL      URL      `json:"url"`
-	ShortURL ShortURL `json:"short_url"`
+	URL       URL        `json:"url"`
+	ShortURL  ShortURL   `json:"short_url"`
+	UserID    string     `json:"user_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// DeleteRequest удаляет код в одном домене и только у его владельца.
+type DeleteRequest struct {
+	Domain   string
+	UserID   string
+	ShortURL ShortURL
+}
+
+// DomainRepo хранилище, где короткий id и URL уникальны в пределах домена.
+// Методы самого хранилища работают с основным доменом, InDomain отдаёт такое же
+// хранилище, ограниченное domain: Get, Search и GetByURLs ищут только в нём,
+// Add и AddMany пишут в него.
+type DomainRepo interface {
+	InDomain(domain string) (Repository, error)
+}
+
+// Expired сообщает, истёк ли срок жизни записи к моменту now.
+func (r Record) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// Sequence источник монотонных номеров для счётчиковых генераторов коротких id.
+type Sequence interface {
+	NextVal(ctx context.Context) (int64, error)
 }
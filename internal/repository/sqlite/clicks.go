@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/IvanOplesnin/url-shortener/internal/repository/sqlite/query"
+)
+
+// AddClicks пишет пачку кликов одной транзакцией: по отдельности каждый клик был бы fsync.
+func (r *Repo) AddClicks(ctx context.Context, clicks []repository.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return r.withTx(ctx, func(q *query.Queries) error {
+		for _, c := range clicks {
+			params := query.AddClickParams{
+				Domain:    c.Domain,
+				ShortURL:  c.ShortURL,
+				ClickedAt: c.At.UTC(),
+				Referrer:  c.Referrer,
+				UserAgent: c.UserAgent,
+				Ip:        c.IP,
+			}
+			if err := q.AddClick(ctx, params); err != nil {
+				return fmt.Errorf("sqlite error AddClicks: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Repo) ClickStats(ctx context.Context, domain string, short repository.ShortURL) (repository.ClickStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	totals, err := r.queries.ClickTotals(ctx, query.ClickTotalsParams{Domain: domain, ShortURL: short})
+	if err != nil {
+		return repository.ClickStats{}, fmt.Errorf("sqlite error ClickTotals: %w", err)
+	}
+	rows, err := r.queries.ClickDaily(ctx, query.ClickDailyParams{Domain: domain, ShortURL: short})
+	if err != nil {
+		return repository.ClickStats{}, fmt.Errorf("sqlite error ClickDaily: %w", err)
+	}
+	stats := repository.ClickStats{
+		Total:  totals.Total,
+		Unique: totals.Uniq,
+		Daily:  make([]repository.DailyClicks, 0, len(rows)),
+	}
+	for _, row := range rows {
+		// время кликов хранится в UTC, так что первые десять символов — это день
+		day, err := time.Parse(time.DateOnly, row.Day)
+		if err != nil {
+			return repository.ClickStats{}, fmt.Errorf("sqlite error ClickDaily: parse day %q: %w", row.Day, err)
+		}
+		stats.Daily = append(stats.Daily, repository.DailyClicks{Day: day, Count: row.Clicks})
+	}
+	return stats, nil
+}
@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository/sqlite/query"
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
+)
+
+// DSNPrefix форма DATABASE_DSN для SQLite: sqlite://data.db или sqlite:///var/lib/shortener.db.
+const DSNPrefix = "sqlite://"
+
+// Вшиваем миграции в бинарь. Они повторяют migrations/schema, кроме пула ключей:
+// с SQLite он живёт в памяти.
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// PathFromDSN путь к файлу базы, если dsn в форме sqlite://path.
+func PathFromDSN(dsn string) (string, bool) {
+	path, ok := strings.CutPrefix(dsn, DSNPrefix)
+	if !ok || path == "" {
+		return "", false
+	}
+	return path, true
+}
+
+// Open открывает файл базы и накатывает миграции. WAL даёт читать во время записи,
+// busy_timeout заставляет писателей ждать друг друга вместо SQLITE_BUSY, а транзакции
+// сразу берут блокировку на запись, чтобы не падать при попытке её повысить.
+func Open(path string) (*sql.DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("sqlite mkdir: %w", err)
+		}
+	}
+	db, err := sql.Open("sqlite", fileDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("sqlite open: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("sqlite ping: %w", err)
+	}
+	if err := Migrate(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func fileDSN(path string) string {
+	return "file:" + path +
+		"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)" +
+		"&_time_format=sqlite&_txlock=immediate"
+}
+
+// seqSchema счётчик кодов в отдельном файле, той же формы, что short_code_seq основной базы.
+const seqSchema = `CREATE TABLE IF NOT EXISTS short_code_seq (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    value INTEGER NOT NULL
+)`
+
+// OpenSequence открывает файл счётчика кодов path.seq рядом с базой db. Писатель в SQLite
+// один на файл: пока InTx держит основную базу, номер из неё выдать можно только через эту
+// транзакцию, и её откат вернул бы номер другим. Из своего файла NextVal пишет своей
+// транзакцией и InTx не ждёт. Новый счётчик продолжает short_code_seq основной базы.
+func OpenSequence(db *sql.DB, path string) (*sql.DB, error) {
+	seq, err := sql.Open("sqlite", fileDSN(path+".seq"))
+	if err != nil {
+		return nil, fmt.Errorf("sqlite open sequence: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := initSequence(ctx, db, seq); err != nil {
+		_ = seq.Close()
+		return nil, fmt.Errorf("sqlite init sequence: %w", err)
+	}
+	return seq, nil
+}
+
+func initSequence(ctx context.Context, db, seq *sql.DB) error {
+	if _, err := seq.ExecContext(ctx, seqSchema); err != nil {
+		return err
+	}
+	last, err := query.New(db).CurrentCodeSeq(ctx)
+	if err != nil {
+		return err
+	}
+	return query.New(seq).InitCodeSeq(ctx, last)
+}
+
+// Migrate применяет все миграции SQLite вверх.
+func Migrate(db *sql.DB) error {
+	goose.SetDialect("sqlite3")
+	goose.SetBaseFS(migrationsFS)
+
+	if err := goose.Up(db, "migrations"); err != nil {
+		return fmt.Errorf("goose up: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE alias_url (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    "url" TEXT NOT NULL,
+    short_url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+
+    CONSTRAINT alias_url_url_uk UNIQUE ("url"),
+    CONSTRAINT alias_url_short_url_uk UNIQUE (short_url)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE alias_url;
+-- +goose StatementEnd
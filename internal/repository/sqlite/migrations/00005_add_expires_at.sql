@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE alias_url ADD COLUMN expires_at TIMESTAMP;
+CREATE INDEX alias_url_expires_at_idx ON alias_url (expires_at) WHERE expires_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX alias_url_expires_at_idx;
+ALTER TABLE alias_url DROP COLUMN expires_at;
+-- +goose StatementEnd
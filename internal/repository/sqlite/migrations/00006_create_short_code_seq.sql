@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- последовательностей в SQLite нет: одна строка со счётчиком
+CREATE TABLE short_code_seq (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    value INTEGER NOT NULL
+);
+INSERT INTO short_code_seq (id, value) VALUES (1, 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE short_code_seq;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+-- удалённый URL можно сократить заново, поэтому уникальность только среди живых ссылок;
+-- ограничение таблицы в SQLite не снять, поэтому таблица пересоздаётся
+CREATE TABLE alias_url_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    "url" TEXT NOT NULL,
+    short_url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL DEFAULT '',
+    is_deleted BOOLEAN NOT NULL DEFAULT false,
+    expires_at TIMESTAMP,
+    domain TEXT NOT NULL DEFAULT '',
+
+    CONSTRAINT alias_url_domain_short_url_uk UNIQUE (domain, short_url)
+);
+INSERT INTO alias_url_new (id, "url", short_url, created_at, user_id, is_deleted, expires_at, domain)
+SELECT id, "url", short_url, created_at, user_id, is_deleted, expires_at, domain FROM alias_url;
+DROP TABLE alias_url;
+ALTER TABLE alias_url_new RENAME TO alias_url;
+CREATE UNIQUE INDEX alias_url_domain_url_uk ON alias_url (domain, "url") WHERE NOT is_deleted;
+CREATE INDEX alias_url_user_id_idx ON alias_url (user_id);
+CREATE INDEX alias_url_expires_at_idx ON alias_url (expires_at) WHERE expires_at IS NOT NULL;
+-- удаление по коду идёт без домена
+CREATE INDEX alias_url_short_url_idx ON alias_url (short_url);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE alias_url_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    "url" TEXT NOT NULL,
+    short_url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL DEFAULT '',
+    is_deleted BOOLEAN NOT NULL DEFAULT false,
+    expires_at TIMESTAMP,
+    domain TEXT NOT NULL DEFAULT '',
+
+    CONSTRAINT alias_url_domain_url_uk UNIQUE (domain, "url"),
+    CONSTRAINT alias_url_domain_short_url_uk UNIQUE (domain, short_url)
+);
+-- из удалённых дублей остаётся живая ссылка или последняя
+INSERT INTO alias_url_old (id, "url", short_url, created_at, user_id, is_deleted, expires_at, domain)
+SELECT id, "url", short_url, created_at, user_id, is_deleted, expires_at, domain FROM alias_url AS a
+WHERE NOT EXISTS (
+    SELECT 1 FROM alias_url AS b
+    WHERE a.is_deleted AND b.domain = a.domain AND b."url" = a."url" AND (NOT b.is_deleted OR b.id > a.id)
+);
+DROP TABLE alias_url;
+ALTER TABLE alias_url_old RENAME TO alias_url;
+CREATE INDEX alias_url_user_id_idx ON alias_url (user_id);
+CREATE INDEX alias_url_expires_at_idx ON alias_url (expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX alias_url_short_url_idx ON alias_url (short_url);
+-- +goose StatementEnd
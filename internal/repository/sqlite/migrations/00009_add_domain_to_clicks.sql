@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE clicks ADD COLUMN domain TEXT NOT NULL DEFAULT '';
+DROP INDEX clicks_short_url_clicked_at_idx;
+CREATE INDEX clicks_domain_short_url_clicked_at_idx ON clicks (domain, short_url, clicked_at);
+-- удаление теперь идёт по домену и коду, его покрывает alias_url_domain_short_url_uk
+DROP INDEX alias_url_short_url_idx;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE INDEX alias_url_short_url_idx ON alias_url (short_url);
+DROP INDEX clicks_domain_short_url_clicked_at_idx;
+CREATE INDEX clicks_short_url_clicked_at_idx ON clicks (short_url, clicked_at);
+ALTER TABLE clicks DROP COLUMN domain;
+-- +goose StatementEnd
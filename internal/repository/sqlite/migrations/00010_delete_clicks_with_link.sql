@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- код освобождается вместе со ссылкой, и переходы старой ссылки не должны достаться новой
+CREATE TRIGGER alias_url_delete_clicks AFTER DELETE ON alias_url
+BEGIN
+    DELETE FROM clicks WHERE domain = OLD.domain AND short_url = OLD.short_url;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER alias_url_delete_clicks;
+-- +goose StatementEnd
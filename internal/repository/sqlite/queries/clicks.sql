@@ -0,0 +1,19 @@
+-- name: AddClick :exec
+INSERT INTO clicks (domain, short_url, clicked_at, referrer, user_agent, ip)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: ClickTotals :one
+SELECT
+  COUNT(*) AS total,
+  COUNT(DISTINCT ip || char(0) || user_agent) AS uniq
+FROM clicks
+WHERE domain = ? AND short_url = ?;
+
+-- name: ClickDaily :many
+SELECT
+  CAST(substr(clicked_at, 1, 10) AS TEXT) AS day,
+  COUNT(*) AS clicks
+FROM clicks
+WHERE domain = ? AND short_url = ?
+GROUP BY day
+ORDER BY day;
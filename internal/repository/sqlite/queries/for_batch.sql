@@ -0,0 +1,20 @@
+-- name: GetByURLs :many
+SELECT id, short_url, "url"
+FROM alias_url
+WHERE domain = sqlc.arg(domain) AND "url" IN (sqlc.slice(urls))
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > sqlc.arg(now));
+
+-- name: ReleaseExpiredURLs :execrows
+DELETE FROM alias_url
+WHERE domain = sqlc.arg(domain) AND "url" IN (sqlc.slice(urls))
+  AND expires_at IS NOT NULL AND expires_at <= sqlc.arg(now);
+
+-- name: AddIgnore :many
+INSERT INTO alias_url (
+    short_url, "url", created_at, user_id, expires_at, domain
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT DO NOTHING
+RETURNING id, short_url, "url", user_id, expires_at;
@@ -0,0 +1,49 @@
+-- name: Search :one
+SELECT short_url
+FROM alias_url
+WHERE domain = sqlc.arg(domain) AND "url" = sqlc.arg(url)
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > sqlc.arg(now))
+LIMIT 1;
+
+-- name: Get :one
+SELECT "url", is_deleted, expires_at
+FROM alias_url
+WHERE domain = ? AND short_url = ?;
+
+-- name: Add :exec
+INSERT INTO alias_url (
+    short_url, "url", created_at, user_id, expires_at, domain
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+);
+
+-- name: GetByUser :many
+SELECT id, short_url, "url", domain
+FROM alias_url
+WHERE user_id = sqlc.arg(user_id)
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > sqlc.arg(now))
+ORDER BY id;
+
+-- name: DeleteByUser :exec
+UPDATE alias_url
+SET is_deleted = true
+WHERE domain = ? AND user_id = ? AND short_url = ?;
+
+-- name: PurgeExpired :execrows
+DELETE FROM alias_url
+WHERE expires_at IS NOT NULL AND expires_at <= sqlc.arg(now);
+
+-- name: CountURLs :one
+SELECT count(*)
+FROM alias_url
+WHERE NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > sqlc.arg(now));
+
+-- name: CountUsers :one
+SELECT count(DISTINCT user_id)
+FROM alias_url
+WHERE user_id <> ''
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > sqlc.arg(now));
@@ -0,0 +1,12 @@
+-- name: NextCodeSeq :one
+UPDATE short_code_seq
+SET value = value + 1
+WHERE id = 1
+RETURNING value;
+
+-- name: CurrentCodeSeq :one
+SELECT value FROM short_code_seq WHERE id = 1;
+
+-- name: InitCodeSeq :exec
+INSERT INTO short_code_seq (id, value) VALUES (1, ?)
+ON CONFLICT (id) DO NOTHING;
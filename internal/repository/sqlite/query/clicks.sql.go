@@ -0,0 +1,107 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: clicks.sql
+
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+const addClick = `-- name: AddClick :exec
+INSERT INTO clicks (domain, short_url, clicked_at, referrer, user_agent, ip)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type AddClickParams struct {
+	Domain    string
+	ShortURL  repository.ShortURL
+	ClickedAt time.Time
+	Referrer  string
+	UserAgent string
+	Ip        string
+}
+
+func (q *Queries) AddClick(ctx context.Context, arg AddClickParams) error {
+	_, err := q.db.ExecContext(ctx, addClick,
+		arg.Domain,
+		arg.ShortURL,
+		arg.ClickedAt,
+		arg.Referrer,
+		arg.UserAgent,
+		arg.Ip,
+	)
+	return err
+}
+
+const clickDaily = `-- name: ClickDaily :many
+SELECT
+  CAST(substr(clicked_at, 1, 10) AS TEXT) AS day,
+  COUNT(*) AS clicks
+FROM clicks
+WHERE domain = ? AND short_url = ?
+GROUP BY day
+ORDER BY day
+`
+
+type ClickDailyParams struct {
+	Domain   string
+	ShortURL repository.ShortURL
+}
+
+type ClickDailyRow struct {
+	Day    string
+	Clicks int64
+}
+
+func (q *Queries) ClickDaily(ctx context.Context, arg ClickDailyParams) ([]ClickDailyRow, error) {
+	rows, err := q.db.QueryContext(ctx, clickDaily, arg.Domain, arg.ShortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClickDailyRow
+	for rows.Next() {
+		var i ClickDailyRow
+		if err := rows.Scan(&i.Day, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clickTotals = `-- name: ClickTotals :one
+SELECT
+  COUNT(*) AS total,
+  COUNT(DISTINCT ip || char(0) || user_agent) AS uniq
+FROM clicks
+WHERE domain = ? AND short_url = ?
+`
+
+type ClickTotalsParams struct {
+	Domain   string
+	ShortURL repository.ShortURL
+}
+
+type ClickTotalsRow struct {
+	Total int64
+	Uniq  int64
+}
+
+func (q *Queries) ClickTotals(ctx context.Context, arg ClickTotalsParams) (ClickTotalsRow, error) {
+	row := q.db.QueryRowContext(ctx, clickTotals, arg.Domain, arg.ShortURL)
+	var i ClickTotalsRow
+	err := row.Scan(&i.Total, &i.Uniq)
+	return i, err
+}
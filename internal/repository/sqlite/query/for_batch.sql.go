@@ -0,0 +1,164 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: for_batch.sql
+
+package query
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+const addIgnore = `-- name: AddIgnore :many
+INSERT INTO alias_url (
+    short_url, "url", created_at, user_id, expires_at, domain
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT DO NOTHING
+RETURNING id, short_url, "url", user_id, expires_at
+`
+
+type AddIgnoreParams struct {
+	ShortURL  repository.ShortURL
+	URL       repository.URL
+	CreatedAt time.Time
+	UserID    string
+	ExpiresAt *time.Time
+	Domain    string
+}
+
+type AddIgnoreRow struct {
+	ID        int64
+	ShortURL  repository.ShortURL
+	URL       repository.URL
+	UserID    string
+	ExpiresAt *time.Time
+}
+
+func (q *Queries) AddIgnore(ctx context.Context, arg AddIgnoreParams) ([]AddIgnoreRow, error) {
+	rows, err := q.db.QueryContext(ctx, addIgnore,
+		arg.ShortURL,
+		arg.URL,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.Domain,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AddIgnoreRow
+	for rows.Next() {
+		var i AddIgnoreRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortURL,
+			&i.URL,
+			&i.UserID,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getByURLs = `-- name: GetByURLs :many
+SELECT id, short_url, "url"
+FROM alias_url
+WHERE domain = ?1 AND "url" IN (/*SLICE:urls*/?)
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > ?3)
+`
+
+type GetByURLsParams struct {
+	Domain string
+	Urls   []repository.URL
+	Now    *time.Time
+}
+
+type GetByURLsRow struct {
+	ID       int64
+	ShortURL repository.ShortURL
+	URL      repository.URL
+}
+
+func (q *Queries) GetByURLs(ctx context.Context, arg GetByURLsParams) ([]GetByURLsRow, error) {
+	query := getByURLs
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.Domain)
+	if len(arg.Urls) > 0 {
+		for _, v := range arg.Urls {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:urls*/?", strings.Repeat(",?", len(arg.Urls))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:urls*/?", "NULL", 1)
+	}
+	queryParams = append(queryParams, arg.Now)
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetByURLsRow
+	for rows.Next() {
+		var i GetByURLsRow
+		if err := rows.Scan(&i.ID, &i.ShortURL, &i.URL); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const releaseExpiredURLs = `-- name: ReleaseExpiredURLs :execrows
+DELETE FROM alias_url
+WHERE domain = ?1 AND "url" IN (/*SLICE:urls*/?)
+  AND expires_at IS NOT NULL AND expires_at <= ?3
+`
+
+type ReleaseExpiredURLsParams struct {
+	Domain string
+	Urls   []repository.URL
+	Now    *time.Time
+}
+
+func (q *Queries) ReleaseExpiredURLs(ctx context.Context, arg ReleaseExpiredURLsParams) (int64, error) {
+	query := releaseExpiredURLs
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.Domain)
+	if len(arg.Urls) > 0 {
+		for _, v := range arg.Urls {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:urls*/?", strings.Repeat(",?", len(arg.Urls))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:urls*/?", "NULL", 1)
+	}
+	queryParams = append(queryParams, arg.Now)
+	result, err := q.db.ExecContext(ctx, query, queryParams...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
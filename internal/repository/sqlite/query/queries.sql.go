@@ -0,0 +1,196 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: queries.sql
+
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+const add = `-- name: Add :exec
+INSERT INTO alias_url (
+    short_url, "url", created_at, user_id, expires_at, domain
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+)
+`
+
+type AddParams struct {
+	ShortURL  repository.ShortURL
+	URL       repository.URL
+	CreatedAt time.Time
+	UserID    string
+	ExpiresAt *time.Time
+	Domain    string
+}
+
+func (q *Queries) Add(ctx context.Context, arg AddParams) error {
+	_, err := q.db.ExecContext(ctx, add,
+		arg.ShortURL,
+		arg.URL,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.Domain,
+	)
+	return err
+}
+
+const countURLs = `-- name: CountURLs :one
+SELECT count(*)
+FROM alias_url
+WHERE NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > ?1)
+`
+
+func (q *Queries) CountURLs(ctx context.Context, now *time.Time) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countURLs, now)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUsers = `-- name: CountUsers :one
+SELECT count(DISTINCT user_id)
+FROM alias_url
+WHERE user_id <> ''
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > ?1)
+`
+
+func (q *Queries) CountUsers(ctx context.Context, now *time.Time) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsers, now)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteByUser = `-- name: DeleteByUser :exec
+UPDATE alias_url
+SET is_deleted = true
+WHERE domain = ? AND user_id = ? AND short_url = ?
+`
+
+type DeleteByUserParams struct {
+	Domain   string
+	UserID   string
+	ShortURL repository.ShortURL
+}
+
+func (q *Queries) DeleteByUser(ctx context.Context, arg DeleteByUserParams) error {
+	_, err := q.db.ExecContext(ctx, deleteByUser, arg.Domain, arg.UserID, arg.ShortURL)
+	return err
+}
+
+const get = `-- name: Get :one
+SELECT "url", is_deleted, expires_at
+FROM alias_url
+WHERE domain = ? AND short_url = ?
+`
+
+type GetParams struct {
+	Domain   string
+	ShortURL repository.ShortURL
+}
+
+type GetRow struct {
+	URL       repository.URL
+	IsDeleted bool
+	ExpiresAt *time.Time
+}
+
+func (q *Queries) Get(ctx context.Context, arg GetParams) (GetRow, error) {
+	row := q.db.QueryRowContext(ctx, get, arg.Domain, arg.ShortURL)
+	var i GetRow
+	err := row.Scan(&i.URL, &i.IsDeleted, &i.ExpiresAt)
+	return i, err
+}
+
+const getByUser = `-- name: GetByUser :many
+SELECT id, short_url, "url", domain
+FROM alias_url
+WHERE user_id = ?1
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > ?2)
+ORDER BY id
+`
+
+type GetByUserParams struct {
+	UserID string
+	Now    *time.Time
+}
+
+type GetByUserRow struct {
+	ID       int64
+	ShortURL repository.ShortURL
+	URL      repository.URL
+	Domain   string
+}
+
+func (q *Queries) GetByUser(ctx context.Context, arg GetByUserParams) ([]GetByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getByUser, arg.UserID, arg.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetByUserRow
+	for rows.Next() {
+		var i GetByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortURL,
+			&i.URL,
+			&i.Domain,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeExpired = `-- name: PurgeExpired :execrows
+DELETE FROM alias_url
+WHERE expires_at IS NOT NULL AND expires_at <= ?1
+`
+
+func (q *Queries) PurgeExpired(ctx context.Context, now *time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeExpired, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const search = `-- name: Search :one
+SELECT short_url
+FROM alias_url
+WHERE domain = ?1 AND "url" = ?2
+  AND NOT is_deleted
+  AND (expires_at IS NULL OR expires_at > ?3)
+LIMIT 1
+`
+
+type SearchParams struct {
+	Domain string
+	URL    repository.URL
+	Now    *time.Time
+}
+
+func (q *Queries) Search(ctx context.Context, arg SearchParams) (repository.ShortURL, error) {
+	row := q.db.QueryRowContext(ctx, search, arg.Domain, arg.URL, arg.Now)
+	var short_url repository.ShortURL
+	err := row.Scan(&short_url)
+	return short_url, err
+}
@@ -0,0 +1,45 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: sequence.sql
+
+package query
+
+import (
+	"context"
+)
+
+const currentCodeSeq = `-- name: CurrentCodeSeq :one
+SELECT value FROM short_code_seq WHERE id = 1
+`
+
+func (q *Queries) CurrentCodeSeq(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, currentCodeSeq)
+	var value int64
+	err := row.Scan(&value)
+	return value, err
+}
+
+const initCodeSeq = `-- name: InitCodeSeq :exec
+INSERT INTO short_code_seq (id, value) VALUES (1, ?)
+ON CONFLICT (id) DO NOTHING
+`
+
+func (q *Queries) InitCodeSeq(ctx context.Context, value int64) error {
+	_, err := q.db.ExecContext(ctx, initCodeSeq, value)
+	return err
+}
+
+const nextCodeSeq = `-- name: NextCodeSeq :one
+UPDATE short_code_seq
+SET value = value + 1
+WHERE id = 1
+RETURNING value
+`
+
+func (q *Queries) NextCodeSeq(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, nextCodeSeq)
+	var value int64
+	err := row.Scan(&value)
+	return value, err
+}
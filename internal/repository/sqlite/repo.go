@@ -0,0 +1,364 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/IvanOplesnin/url-shortener/internal/repository/sqlite/query"
+	msqlite "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Колонки уникальных ограничений alias_url: в отличие от Postgres, SQLite в ошибке
+// называет не ограничение, а его колонки.
+const (
+	domainShortURLUK = "alias_url.domain, alias_url.short_url"
+	domainURLUK      = "alias_url.domain, alias_url.url"
+)
+
+type Repo struct {
+	db      *sql.DB
+	queries *query.Queries
+	// seq база из OpenSequence, только для NextVal
+	seq *query.Queries
+	// tx есть у копии внутри InTx
+	tx *sql.Tx
+	// domain в котором ищут и создают ссылки Get, Search, Add и пакетные методы
+	domain string
+	// run пускает в InTx по одному: писатель в SQLite всё равно один
+	run *sync.Mutex
+}
+
+func NewRepo(db, seq *sql.DB) *Repo {
+	return &Repo{
+		db:      db,
+		queries: query.New(db),
+		seq:     query.New(seq),
+		run:     &sync.Mutex{},
+	}
+}
+
+func (r *Repo) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *Repo) Get(ctx context.Context, shortURL repository.ShortURL) (repository.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	row, err := r.queries.Get(ctx, query.GetParams{Domain: r.domain, ShortURL: shortURL})
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.URL(""), repository.ErrNotFoundShortURL
+	}
+	if err != nil {
+		return repository.URL(""), fmt.Errorf("sqlite error Get: %w", err)
+	}
+	if row.IsDeleted {
+		return repository.URL(""), repository.ErrDeleted
+	}
+	if row.ExpiresAt != nil && !time.Now().Before(*row.ExpiresAt) {
+		return repository.URL(""), repository.ErrExpired
+	}
+	return row.URL, nil
+}
+
+func (r *Repo) Search(ctx context.Context, url repository.URL) (repository.ShortURL, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	shortURL, err := r.queries.Search(ctx, query.SearchParams{Domain: r.domain, URL: url, Now: &now})
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ShortURL(""), repository.ErrNotFoundURL
+	}
+	if err != nil {
+		return repository.ShortURL(""), fmt.Errorf("sqlite error Search: %w", err)
+	}
+	return shortURL, nil
+}
+
+func (r *Repo) Add(ctx context.Context, rec repository.Record) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	shortURL, url := rec.ShortURL, rec.URL
+	params := query.AddParams{
+		ShortURL:  shortURL,
+		URL:       url,
+		CreatedAt: time.Now().UTC(),
+		UserID:    rec.UserID,
+		ExpiresAt: utc(rec.ExpiresAt),
+		Domain:    r.domain,
+	}
+	err := r.queries.Add(ctx, params)
+	if cols, ok := uniqueViolation(err); ok && cols == domainURLUK {
+		// URL может держать просроченная ссылка: её место занимает новая
+		n, relErr := r.queries.ReleaseExpiredURLs(ctx, query.ReleaseExpiredURLsParams{Domain: r.domain, Urls: []repository.URL{url}, Now: &params.CreatedAt})
+		if relErr != nil {
+			return fmt.Errorf("sqlite error Add: %w", relErr)
+		}
+		if n > 0 {
+			err = r.queries.Add(ctx, params)
+		}
+	}
+	if err != nil {
+		if cols, ok := uniqueViolation(err); ok {
+			switch cols {
+			case domainShortURLUK:
+				return fmt.Errorf("%w: %v", repository.ErrShortURLAlreadyExists, shortURL)
+			case domainURLUK:
+				return fmt.Errorf("%w: %v", repository.ErrAlreadyExists, url)
+			default:
+				return fmt.Errorf("%w", repository.ErrAlreadyExists)
+			}
+		}
+
+		return fmt.Errorf("sqlite error Add: %w", err)
+	}
+	return nil
+}
+
+// uniqueViolation колонки нарушенного UNIQUE-ограничения.
+func uniqueViolation(err error) (string, bool) {
+	var se *msqlite.Error
+	if !errors.As(err, &se) || se.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return "", false
+	}
+	_, cols, _ := strings.Cut(se.Error(), "UNIQUE constraint failed: ")
+	if i := strings.LastIndex(cols, " ("); i >= 0 {
+		cols = cols[:i]
+	}
+	return cols, true
+}
+
+func (r *Repo) GetByURLs(ctx context.Context, urls []string) ([]repository.Record, error) {
+	if len(urls) == 0 {
+		return []repository.Record{}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	arg := make([]repository.URL, 0, len(urls))
+	for _, u := range urls {
+		arg = append(arg, repository.URL(u))
+	}
+	now := time.Now().UTC()
+	rows, err := r.queries.GetByURLs(ctx, query.GetByURLsParams{Domain: r.domain, Urls: arg, Now: &now})
+	if err != nil {
+		return nil, fmt.Errorf("sqlite error GetByURLs: %w", err)
+	}
+	records := make([]repository.Record, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, repository.Record{
+			ID:       int(row.ID),
+			URL:      row.URL,
+			ShortURL: row.ShortURL,
+			Domain:   r.domain,
+		})
+	}
+	return records, nil
+}
+
+// AddMany вставляет пакет по строке в одной транзакции; строки с занятым кодом или URL
+// пропускаются, как ON CONFLICT DO NOTHING у psql.
+func (r *Repo) AddMany(ctx context.Context, records []repository.ArgAddMany) ([]repository.Record, error) {
+	if len(records) == 0 {
+		return []repository.Record{}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var res []repository.Record
+	insert := func(q *query.Queries) error {
+		res = make([]repository.Record, 0, len(records))
+		now := time.Now().UTC()
+		urls := make([]repository.URL, 0, len(records))
+		for _, rec := range records {
+			urls = append(urls, rec.URL)
+		}
+		// просроченные ссылки уступают URL новым, иначе ON CONFLICT их молча пропустит
+		_, err := q.ReleaseExpiredURLs(ctx, query.ReleaseExpiredURLsParams{Domain: r.domain, Urls: urls, Now: &now})
+		if err != nil {
+			return fmt.Errorf("sqlite error AddMany: %w", err)
+		}
+		for _, rec := range records {
+			rows, err := q.AddIgnore(ctx, query.AddIgnoreParams{
+				ShortURL:  rec.ShortURL,
+				URL:       rec.URL,
+				CreatedAt: now,
+				UserID:    rec.UserID,
+				ExpiresAt: utc(rec.ExpiresAt),
+				Domain:    r.domain,
+			})
+			if err != nil {
+				return fmt.Errorf("sqlite error AddMany: %w", err)
+			}
+			for _, row := range rows {
+				res = append(res, repository.Record{
+					ID:        int(row.ID),
+					URL:       row.URL,
+					ShortURL:  row.ShortURL,
+					UserID:    row.UserID,
+					ExpiresAt: row.ExpiresAt,
+					Domain:    r.domain,
+				})
+			}
+		}
+		return nil
+	}
+
+	if r.tx != nil {
+		if err := insert(r.queries); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	if err := r.withTx(ctx, insert); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *Repo) GetByUser(ctx context.Context, userID string) ([]repository.Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	rows, err := r.queries.GetByUser(ctx, query.GetByUserParams{UserID: userID, Now: &now})
+	if err != nil {
+		return nil, fmt.Errorf("sqlite error GetByUser: %w", err)
+	}
+	records := make([]repository.Record, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, repository.Record{
+			ID:       int(row.ID),
+			URL:      row.URL,
+			ShortURL: row.ShortURL,
+			UserID:   userID,
+			Domain:   row.Domain,
+		})
+	}
+	return records, nil
+}
+
+// DeleteByUser помечает ссылки удалёнными в одной транзакции; чужие ссылки не затрагиваются.
+func (r *Repo) DeleteByUser(ctx context.Context, reqs []repository.DeleteRequest) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return r.withTx(ctx, func(q *query.Queries) error {
+		for _, req := range reqs {
+			params := query.DeleteByUserParams{Domain: req.Domain, UserID: req.UserID, ShortURL: req.ShortURL}
+			if err := q.DeleteByUser(ctx, params); err != nil {
+				return fmt.Errorf("sqlite error DeleteByUser: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Repo) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	n, err := r.queries.PurgeExpired(ctx, utc(&now))
+	if err != nil {
+		return 0, fmt.Errorf("sqlite error PurgeExpired: %w", err)
+	}
+	return n, nil
+}
+
+func (r *Repo) CountURLs(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	n, err := r.queries.CountURLs(ctx, &now)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite error CountURLs: %w", err)
+	}
+	return n, nil
+}
+
+func (r *Repo) CountUsers(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	n, err := r.queries.CountUsers(ctx, &now)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite error CountUsers: %w", err)
+	}
+	return n, nil
+}
+
+// InDomain то же хранилище, но ссылки ищутся и создаются в domain.
+func (r *Repo) InDomain(domain string) (repository.Repository, error) {
+	scoped := *r
+	scoped.domain = domain
+	return &scoped, nil
+}
+
+func (r *Repo) InTx(ctx context.Context, fn func(r repository.Repository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	r.run.Lock()
+	defer r.run.Unlock()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	txRepo := &Repo{db: r.db, queries: r.queries.WithTx(tx), seq: r.seq, tx: tx, domain: r.domain, run: r.run}
+	if err := fn(txRepo); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// NextVal берёт номер из файла счётчика своей транзакцией, в том числе изнутри InTx:
+// откат пакета выданный номер не возвращает.
+func (r *Repo) NextVal(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	v, err := r.seq.NextCodeSeq(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite error NextVal: %w", err)
+	}
+	return v, nil
+}
+
+// withTx выполняет fn в транзакции копии или, если её нет, в новой.
+func (r *Repo) withTx(ctx context.Context, fn func(q *query.Queries) error) error {
+	if r.tx != nil {
+		return fn(r.queries)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := fn(r.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// utc время в базе хранится текстом в UTC, чтобы сравнение строк совпадало со сравнением моментов.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
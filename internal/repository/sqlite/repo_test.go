@@ -0,0 +1,330 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	"github.com/IvanOplesnin/url-shortener/internal/repository/sqlite/query"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepo(t *testing.T) *Repo {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	seq, err := OpenSequence(db, path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = seq.Close() })
+	return NewRepo(db, seq)
+}
+
+func TestPathFromDSN(t *testing.T) {
+	path, ok := PathFromDSN("sqlite://data/short.db")
+	require.True(t, ok)
+	require.Equal(t, "data/short.db", path)
+
+	path, ok = PathFromDSN("sqlite:///var/lib/short.db")
+	require.True(t, ok)
+	require.Equal(t, "/var/lib/short.db", path)
+
+	_, ok = PathFromDSN("postgresql://localhost/db")
+	require.False(t, ok)
+}
+
+func TestRepoAddGet(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "a", UserID: "u1"}))
+	got, err := r.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, repository.URL("https://a.ru"), got)
+
+	short, err := r.Search(ctx, "https://a.ru")
+	require.NoError(t, err)
+	require.Equal(t, repository.ShortURL("a"), short)
+
+	err = r.Add(ctx, repository.Record{URL: "https://b.ru", ShortURL: "a"})
+	require.ErrorIs(t, err, repository.ErrShortURLAlreadyExists)
+	err = r.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "b"})
+	require.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+	// в другом домене тот же код свободен
+	other, err := r.InDomain("x.example")
+	require.NoError(t, err)
+	require.NoError(t, other.Add(ctx, repository.Record{URL: "https://b.ru", ShortURL: "a"}))
+	got, err = other.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, repository.URL("https://b.ru"), got)
+
+	_, err = r.Get(ctx, "missing")
+	require.ErrorIs(t, err, repository.ErrNotFoundShortURL)
+	_, err = r.Search(ctx, "https://missing.ru")
+	require.ErrorIs(t, err, repository.ErrNotFoundURL)
+}
+
+func TestRepoExpireDeletePurge(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "a", UserID: "u1"}))
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://b.ru", ShortURL: "b", UserID: "u1", ExpiresAt: &future}))
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://old.ru", ShortURL: "old", UserID: "u1", ExpiresAt: &past}))
+
+	_, err := r.Get(ctx, "old")
+	require.ErrorIs(t, err, repository.ErrExpired)
+	n, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	// чужую ссылку удалить нельзя
+	require.NoError(t, r.DeleteByUser(ctx, []repository.DeleteRequest{
+		{UserID: "u1", ShortURL: "a"},
+		{UserID: "u2", ShortURL: "b"},
+	}))
+	_, err = r.Get(ctx, "a")
+	require.ErrorIs(t, err, repository.ErrDeleted)
+	_, err = r.Get(ctx, "b")
+	require.NoError(t, err)
+
+	purged, err := r.PurgeExpired(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+	_, err = r.Get(ctx, "old")
+	require.ErrorIs(t, err, repository.ErrNotFoundShortURL)
+
+	users, err := r.CountUsers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), users)
+}
+
+func TestRepoBatchInTx(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "a"}))
+
+	err := r.InTx(ctx, func(tx repository.Repository) error {
+		// генератор кодов зовут изнутри транзакции
+		v, err := r.NextVal(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), v)
+
+		batch := tx.(repository.BatchRepo)
+		found, err := batch.GetByURLs(ctx, []string{"https://a.ru", "https://b.ru"})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+
+		added, err := batch.AddMany(ctx, []repository.ArgAddMany{
+			{URL: "https://b.ru", ShortURL: "b", UserID: "u1"},
+			// занятый код пропускается
+			{URL: "https://c.ru", ShortURL: "a", UserID: "u1"},
+		})
+		require.NoError(t, err)
+		require.Len(t, added, 1)
+		require.Equal(t, repository.ShortURL("b"), added[0].ShortURL)
+		return nil
+	})
+	require.NoError(t, err)
+
+	v, err := r.NextVal(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), v)
+
+	records, err := r.GetByUser(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	// ошибка внутри InTx откатывает вставку, но не выданный номер
+	err = r.InTx(ctx, func(tx repository.Repository) error {
+		v, err := r.NextVal(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), v)
+		_, err = tx.(repository.BatchRepo).AddMany(ctx, []repository.ArgAddMany{{URL: "https://d.ru", ShortURL: "d"}})
+		require.NoError(t, err)
+		return repository.ErrNotFoundURL
+	})
+	require.ErrorIs(t, err, repository.ErrNotFoundURL)
+	_, err = r.Get(ctx, "d")
+	require.ErrorIs(t, err, repository.ErrNotFoundShortURL)
+	v, err = r.NextVal(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), v)
+}
+
+func TestNextValDuringInTx(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	started := make(chan struct{})
+	done := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.InTx(ctx, func(tx repository.Repository) error {
+			err := tx.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "a"})
+			close(started)
+			<-done
+			if err != nil {
+				return err
+			}
+			return repository.ErrNotFoundURL
+		})
+	}()
+	<-started
+
+	// чужая открытая транзакция номер не держит и не откатывает
+	v, err := r.NextVal(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), v)
+	close(done)
+	require.ErrorIs(t, <-errc, repository.ErrNotFoundURL)
+
+	v, err = r.NextVal(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), v)
+}
+
+func TestOpenSequenceContinues(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	// номера, выданные до переезда счётчика в свой файл
+	for range 3 {
+		_, err := query.New(db).NextCodeSeq(ctx)
+		require.NoError(t, err)
+	}
+
+	seq, err := OpenSequence(db, path)
+	require.NoError(t, err)
+	v, err := NewRepo(db, seq).NextVal(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), v)
+	require.NoError(t, seq.Close())
+
+	// повторное открытие счётчик не сбрасывает
+	seq, err = OpenSequence(db, path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = seq.Close() })
+	v, err = NewRepo(db, seq).NextVal(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), v)
+}
+
+func TestRepoClicks(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	day := time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)
+
+	require.NoError(t, r.AddClicks(ctx, []repository.Click{
+		{ShortURL: "a", At: day, IP: "1.1.1.1", UserAgent: "x"},
+		{ShortURL: "a", At: day.Add(time.Minute), IP: "1.1.1.1", UserAgent: "x"},
+		{ShortURL: "a", At: day.Add(time.Hour), IP: "2.2.2.2", UserAgent: "x"},
+		{ShortURL: "b", At: day, IP: "1.1.1.1", UserAgent: "x"},
+		{Domain: "x.example", ShortURL: "a", At: day, IP: "3.3.3.3", UserAgent: "x"},
+	}))
+
+	stats, err := r.ClickStats(ctx, "", "a")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), stats.Total)
+	require.Equal(t, int64(2), stats.Unique)
+	require.Equal(t, []repository.DailyClicks{
+		{Day: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Count: 2},
+		{Day: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Count: 1},
+	}, stats.Daily)
+
+	// тот же код в другом домене считается отдельно
+	stats, err = r.ClickStats(ctx, "x.example", "a")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stats.Total)
+}
+
+func TestRepoPurgeDropsClicks(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://old.ru", ShortURL: "a", ExpiresAt: &past}))
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://gone.ru", ShortURL: "b", ExpiresAt: &past}))
+	require.NoError(t, r.AddClicks(ctx, []repository.Click{
+		{ShortURL: "a", At: past, IP: "1.1.1.1", UserAgent: "x"},
+		{ShortURL: "b", At: past, IP: "1.1.1.1", UserAgent: "x"},
+		{Domain: "x.example", ShortURL: "a", At: past, IP: "1.1.1.1", UserAgent: "x"},
+	}))
+
+	// URL просроченной ссылки занимает новая: старая удаляется вместе с переходами
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://gone.ru", ShortURL: "c"}))
+	_, err := r.PurgeExpired(ctx, time.Now())
+	require.NoError(t, err)
+
+	// код снова свободен, и новой ссылке чужие переходы не достаются
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://new.ru", ShortURL: "a"}))
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://other.ru", ShortURL: "b"}))
+	for _, short := range []repository.ShortURL{"a", "b"} {
+		stats, err := r.ClickStats(ctx, "", short)
+		require.NoError(t, err)
+		require.Zero(t, stats.Total, short)
+	}
+	stats, err := r.ClickStats(ctx, "x.example", "a")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stats.Total)
+}
+
+func TestRepoReshortenDeleted(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "a", UserID: "u1"}))
+	require.NoError(t, r.DeleteByUser(ctx, []repository.DeleteRequest{{UserID: "u1", ShortURL: "a"}}))
+
+	// удалённая ссылка не находится по URL
+	_, err := r.Search(ctx, "https://a.ru")
+	require.ErrorIs(t, err, repository.ErrNotFoundURL)
+	found, err := r.GetByURLs(ctx, []string{"https://a.ru"})
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	// и URL можно сократить заново
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "b", UserID: "u1"}))
+	short, err := r.Search(ctx, "https://a.ru")
+	require.NoError(t, err)
+	require.Equal(t, repository.ShortURL("b"), short)
+	// живая ссылка на URL снова единственная
+	require.ErrorIs(t, r.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "c"}), repository.ErrAlreadyExists)
+	_, err = r.Get(ctx, "a")
+	require.ErrorIs(t, err, repository.ErrDeleted)
+}
+
+func TestRepoReshortenExpired(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "a", ExpiresAt: &past}))
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://c.ru", ShortURL: "c", ExpiresAt: &past}))
+
+	// просроченная ссылка не находится по URL
+	_, err := r.Search(ctx, "https://a.ru")
+	require.ErrorIs(t, err, repository.ErrNotFoundURL)
+	found, err := r.GetByURLs(ctx, []string{"https://a.ru", "https://c.ru"})
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	// и уступает URL новой, по одной и пачкой
+	require.NoError(t, r.Add(ctx, repository.Record{URL: "https://a.ru", ShortURL: "b"}))
+	short, err := r.Search(ctx, "https://a.ru")
+	require.NoError(t, err)
+	require.Equal(t, repository.ShortURL("b"), short)
+	added, err := r.AddMany(ctx, []repository.ArgAddMany{{URL: "https://c.ru", ShortURL: "d"}})
+	require.NoError(t, err)
+	require.Len(t, added, 1)
+	found, err = r.GetByURLs(ctx, []string{"https://c.ru"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, repository.ShortURL("d"), found[0].ShortURL)
+}
@@ -0,0 +1,33 @@
+package shortener
+
+import "time"
+
+// runBatches читает in до закрытия канала и отдаёт элементы в flush пачками
+// не больше size либо раз в interval. Остаток сбрасывается при закрытии in.
+func runBatches[T any](in <-chan T, size int, interval time.Duration, flush func([]T)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	buf := make([]T, 0, size)
+	for {
+		select {
+		case item, ok := <-in:
+			if !ok {
+				if len(buf) > 0 {
+					flush(buf)
+				}
+				return
+			}
+			buf = append(buf, item)
+			if len(buf) >= size {
+				flush(buf)
+				buf = buf[:0]
+			}
+		case <-ticker.C:
+			if len(buf) > 0 {
+				flush(buf)
+				buf = buf[:0]
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package shortener
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+var ErrBlockedURL = errors.New("url domain is blocked")
+
+// domainSet запрещённые домены; поддомен запрещён вместе с родителем.
+type domainSet map[string]struct{}
+
+func newDomainSet(domains []string) domainSet {
+	set := make(domainSet, len(domains))
+	for _, d := range domains {
+		d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d != "" {
+			set[d] = struct{}{}
+		}
+	}
+	return set
+}
+
+func (d domainSet) blocks(u repository.URL) bool {
+	if len(d) == 0 {
+		return false
+	}
+	parsed, err := url.Parse(string(u))
+	if err != nil {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	for host != "" {
+		if _, ok := d[host]; ok {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+	return false
+}
+
+// WithBlockedDomains запрещает сокращать ссылки на домены из списка.
+func WithBlockedDomains(domains []string) Option {
+	return func(s *Service) { s.SetBlockedDomains(domains) }
+}
+
+// SetBlockedDomains атомарно заменяет список запрещённых доменов; безопасно вызывать
+// параллельно с обработкой запросов.
+func (s *Service) SetBlockedDomains(domains []string) {
+	set := newDomainSet(domains)
+	s.blocked.Store(&set)
+}
+
+func (s *Service) isBlocked(u repository.URL) bool {
+	set := s.blocked.Load()
+	return set != nil && set.blocks(u)
+}
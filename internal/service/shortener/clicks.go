@@ -0,0 +1,84 @@
+package shortener
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+// ClickRecorder копит клики в буфере и пишет их в репозиторий пачками.
+// Если буфер переполнен, клик отбрасывается: редирект не должен ждать записи.
+type ClickRecorder struct {
+	r        repository.ClickRepo
+	in       chan repository.Click
+	size     int
+	interval time.Duration
+	dropped  atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+func NewClickRecorder(r repository.ClickRepo, bufferSize, batchSize int, interval time.Duration) *ClickRecorder {
+	return &ClickRecorder{
+		r:        r,
+		in:       make(chan repository.Click, bufferSize),
+		size:     batchSize,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+func (c *ClickRecorder) Record(click repository.Click) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.in <- click:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// Dropped возвращает число кликов, отброшенных из-за переполнения буфера.
+func (c *ClickRecorder) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+func (c *ClickRecorder) Run() {
+	defer close(c.done)
+	runBatches(c.in, c.size, c.interval, c.flush)
+}
+
+func (c *ClickRecorder) flush(buf []repository.Click) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.r.AddClicks(ctx, buf); err != nil {
+		logger.Log.Errorf("click recorder flush %d items: %s", len(buf), err)
+	}
+}
+
+func (c *ClickRecorder) Close(ctx context.Context) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.in)
+	c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,98 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+var ErrDeleterClosed = errors.New("deleter closed")
+
+// Deleter собирает запросы на удаление от всех хендлеров в один канал (fan-in)
+// и сбрасывает их в репозиторий пачками: по размеру пачки или по таймеру.
+type Deleter struct {
+	r        repository.DeleteRepo
+	in       chan repository.DeleteRequest
+	size     int
+	interval time.Duration
+
+	mu        sync.Mutex
+	closed    bool
+	producers sync.WaitGroup
+	done      chan struct{}
+}
+
+func NewDeleter(r repository.DeleteRepo, batchSize int, interval time.Duration) *Deleter {
+	return &Deleter{
+		r:        r,
+		in:       make(chan repository.DeleteRequest, batchSize),
+		size:     batchSize,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Delete ставит удаление в очередь и возвращает управление, как только все коды в ней.
+// Если очередь полна, ждёт места, пока жив ctx: под нагрузкой запросы тормозят,
+// а не копят горутины.
+func (d *Deleter) Delete(ctx context.Context, userID, domain string, shorts []repository.ShortURL) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrDeleterClosed
+	}
+	d.producers.Add(1)
+	d.mu.Unlock()
+	defer d.producers.Done()
+
+	for _, short := range shorts {
+		select {
+		case d.in <- repository.DeleteRequest{Domain: domain, UserID: userID, ShortURL: short}:
+		case <-ctx.Done():
+			return fmt.Errorf("deleter enqueue: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
+// Run читает очередь до Close; должен быть запущен ровно один раз.
+func (d *Deleter) Run() {
+	defer close(d.done)
+	runBatches(d.in, d.size, d.interval, d.flush)
+}
+
+func (d *Deleter) flush(buf []repository.DeleteRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.r.DeleteByUser(ctx, buf); err != nil {
+		logger.Log.Errorf("deleter flush %d items: %s", len(buf), err)
+	}
+}
+
+// Close перестаёт принимать запросы, дожидается записи всей очереди и завершения Run.
+func (d *Deleter) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	go func() {
+		d.producers.Wait()
+		close(d.in)
+	}()
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,87 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+)
+
+var ErrUnknownDomain = errors.New("unknown domain")
+
+// WithDomains добавляет короткие домены помимо основного из baseURL. Ссылки в каждом
+// домене независимы: один и тот же id в разных доменах ведёт на разные адреса.
+func WithDomains(domains []string) Option {
+	return func(s *Service) {
+		s.domains = make(map[string]struct{}, len(domains))
+		for _, d := range domains {
+			if d = normalizeHost(d); d != "" {
+				s.domains[d] = struct{}{}
+			}
+		}
+	}
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// domain приводит имя из запроса к ключу хранилища: пусто или основной хост — "",
+// дополнительный домен — он сам, остальное — ErrUnknownDomain.
+func (s *Service) domain(name string) (string, error) {
+	name = normalizeHost(name)
+	if name == "" || name == s.defaultHost() {
+		return "", nil
+	}
+	if _, ok := s.domains[name]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownDomain, name)
+}
+
+// hostDomain домен, в котором искать ссылку по Host запроса; чужой Host — основной домен.
+func (s *Service) hostDomain(host string) string {
+	host = normalizeHost(host)
+	if _, ok := s.domains[host]; ok && host != s.defaultHost() {
+		return host
+	}
+	return ""
+}
+
+func (s *Service) defaultHost() string {
+	base, err := url.Parse(s.baseURL)
+	if err != nil {
+		return ""
+	}
+	return normalizeHost(base.Host)
+}
+
+// repoFor хранилище, ограниченное доменом; для основного — само s.r.
+func (s *Service) repoFor(domain string) (repository.Repository, error) {
+	if domain == "" {
+		return s.r, nil
+	}
+	dr, ok := s.r.(repository.DomainRepo)
+	if !ok {
+		return nil, fmt.Errorf("repo doesn't support domains")
+	}
+	return dr.InDomain(domain)
+}
+
+// linkBase базовый URL ссылок домена: схема и путь основного, хост — домена.
+func (s *Service) linkBase(ctx context.Context, domain string) string {
+	base := usvc.Base(ctx, s.baseURL)
+	if domain == "" {
+		return base
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	u.Host = domain
+	return u.String()
+}
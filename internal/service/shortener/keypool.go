@@ -0,0 +1,120 @@
+package shortener
+
+import (
+	"context"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+)
+
+// KeyPoolFiller в фоне держит запас ключей в пуле: когда их меньше low, догенерирует до high.
+type KeyPoolFiller struct {
+	pool     repository.KeyPool
+	gen      usvc.CodeGenerator
+	low      int
+	high     int
+	interval time.Duration
+	kick     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewKeyPoolFiller(pool repository.KeyPool, gen usvc.CodeGenerator, size int, interval time.Duration) *KeyPoolFiller {
+	return &KeyPoolFiller{
+		pool:     pool,
+		gen:      gen,
+		low:      size / 2,
+		high:     size,
+		interval: interval,
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (f *KeyPoolFiller) Run() {
+	defer close(f.done)
+
+	f.fill()
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.fill()
+		case <-f.kick:
+			f.fill()
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// Kick просит пополнить пул, не дожидаясь тикера.
+func (f *KeyPoolFiller) Kick() {
+	select {
+	case f.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (f *KeyPoolFiller) fill() {
+	const chunk = 500
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	size, err := f.pool.PoolSize(ctx)
+	if err != nil {
+		logger.Log.Errorf("key pool size: %s", err)
+		return
+	}
+	if size > int64(f.low) {
+		return
+	}
+
+	tracker, _ := f.gen.(usvc.CollisionTracker)
+	need := int64(f.high) - size
+	// ограничиваем число раундов, чтобы заполненное пространство ключей не зациклило нас
+	for round := 0; need > 0 && round < f.high/chunk+3; round++ {
+		keys := make([]repository.ShortURL, 0, min(need, chunk))
+		for int64(len(keys)) < min(need, chunk) {
+			k, err := f.gen.Next(ctx, "", 0)
+			if err != nil {
+				logger.Log.Errorf("key pool generate: %s", err)
+				return
+			}
+			keys = append(keys, k)
+		}
+		added, err := f.pool.AddKeys(ctx, keys)
+		if err != nil {
+			logger.Log.Errorf("key pool add keys: %s", err)
+			return
+		}
+		if tracker != nil {
+			for i := range keys {
+				tracker.Observe(int64(i) >= added, 0)
+			}
+		}
+		need -= added
+	}
+	if need > 0 {
+		logger.Log.Warnf("key pool: %d keys short of target %d", need, f.high)
+	}
+}
+
+func (f *KeyPoolFiller) Close(ctx context.Context) error {
+	select {
+	case <-f.stop:
+	default:
+		close(f.stop)
+	}
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
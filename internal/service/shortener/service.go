@@ -4,15 +4,107 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
 
+	"github.com/IvanOplesnin/url-shortener/internal/audit"
+	"github.com/IvanOplesnin/url-shortener/internal/auth"
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
 	"github.com/IvanOplesnin/url-shortener/internal/model"
 	"github.com/IvanOplesnin/url-shortener/internal/repository"
 	usvc "github.com/IvanOplesnin/url-shortener/internal/service/url"
+	"github.com/IvanOplesnin/url-shortener/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/IvanOplesnin/url-shortener/internal/service/shortener")
+
+// lookupMisses ответы на переход по ссылке, которые не считаются сбоем в трассировке.
+var lookupMisses = []error{repository.ErrNotFoundShortURL, repository.ErrDeleted, repository.ErrExpired}
+
 type Service struct {
 	r       repository.Repository
 	baseURL string
+	deleter *Deleter
+	clicks  repository.ClickRepo
+	rec     *ClickRecorder
+	aliases *usvc.AliasPolicy
+	gen     usvc.CodeGenerator
+	pool    repository.KeyPool
+	filler  *KeyPoolFiller
+	blocked atomic.Pointer[domainSet]
+	domains map[string]struct{}
+	audit   audit.Observer
+}
+
+type Option func(*Service)
+
+// WithDeleter включает асинхронное удаление ссылок через d.
+func WithDeleter(d *Deleter) Option {
+	return func(s *Service) { s.deleter = d }
+}
+
+// WithClicks включает учёт переходов: запись идёт через rec, статистика читается из r.
+func WithClicks(r repository.ClickRepo, rec *ClickRecorder) Option {
+	return func(s *Service) {
+		s.clicks = r
+		s.rec = rec
+	}
+}
+
+// WithAudit сообщает obs о каждой созданной ссылке.
+func WithAudit(obs audit.Observer) Option {
+	return func(s *Service) { s.audit = obs }
+}
+
+// ShortenParams необязательные параметры создания ссылки.
+// ExpiresAt и TTL взаимоисключающие; без них ссылка бессрочная.
+// CustomAlias задаёт короткий id вместо случайного.
+// Domain один из WithDomains; пустой — основной домен.
+type ShortenParams struct {
+	ExpiresAt   *time.Time
+	TTL         time.Duration
+	CustomAlias repository.ShortURL
+	Domain      string
+}
+
+var ErrInvalidExpiry = errors.New("invalid expiry")
+
+// maxTTLSeconds больше этого ttl_seconds не помещается в time.Duration.
+const maxTTLSeconds = math.MaxInt64 / int64(time.Second)
+
+// TTLFromSeconds переводит ttl_seconds из запроса в TTL, не давая умножению переполниться
+// в отрицательный или уже прошедший срок.
+func TTLFromSeconds(seconds int64) (time.Duration, error) {
+	if seconds > maxTTLSeconds {
+		return 0, fmt.Errorf("%w: ttl must be at most %d seconds", ErrInvalidExpiry, maxTTLSeconds)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// ErrAliasMismatch URL уже сокращён, но под другим id, чем просили в CustomAlias.
+var ErrAliasMismatch = errors.New("url is already shortened with another alias")
+
+// WithGenerator задаёт стратегию генерации коротких id.
+func WithGenerator(g usvc.CodeGenerator) Option {
+	return func(s *Service) { s.gen = g }
+}
+
+// WithKeyPool берёт короткие id из пула заранее сгенерированных ключей;
+// filler (может быть nil) пинается, когда пул опустел.
+func WithKeyPool(p repository.KeyPool, filler *KeyPoolFiller) Option {
+	return func(s *Service) {
+		s.pool = p
+		s.filler = filler
+	}
+}
+
+// WithAliasPolicy задаёт правила для пользовательских коротких id.
+func WithAliasPolicy(p *usvc.AliasPolicy) Option {
+	return func(s *Service) { s.aliases = p }
 }
 
 type Result struct {
@@ -21,18 +113,49 @@ type Result struct {
 	Exists bool
 }
 
-func New(r repository.Repository, baseURL string) *Service {
-	return &Service{r: r, baseURL: baseURL}
+func New(r repository.Repository, baseURL string, opts ...Option) *Service {
+	s := &Service{r: r, baseURL: baseURL, aliases: usvc.DefaultAliasPolicy(), gen: usvc.NewRandomGenerator(6)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *Service) Shorten(ctx context.Context, u repository.URL) (Result, error) {
+func (s *Service) Shorten(ctx context.Context, u repository.URL, params ShortenParams) (_ Result, err error) {
+	ctx, span := tracer.Start(ctx, "Service.Shorten", trace.WithAttributes(attribute.String("shortener.domain", params.Domain), attribute.Bool("shortener.custom_alias", params.CustomAlias != "")))
+	defer func() { tracing.End(span, err) }()
+
 	if _, err := usvc.ParseURL(string(u)); err != nil {
 		return Result{}, fmt.Errorf("invalid url: %w", err)
 	}
+	if s.isBlocked(u) {
+		return Result{}, fmt.Errorf("%w: %s", ErrBlockedURL, u)
+	}
+	expiresAt, err := params.expiresAt(time.Now())
+	if err != nil {
+		return Result{}, err
+	}
+	if params.CustomAlias != "" {
+		if err := s.aliases.Validate(params.CustomAlias); err != nil {
+			return Result{}, err
+		}
+	}
+	domain, err := s.domain(params.Domain)
+	if err != nil {
+		return Result{}, err
+	}
+	r, err := s.repoFor(domain)
+	if err != nil {
+		return Result{}, err
+	}
 
-	short, err := s.r.Search(ctx, u)
+	short, err := r.Search(ctx, u)
 	if err == nil {
-		link, err := usvc.CreateURL(s.baseURL, short)
+		// второй alias на тот же URL не заводим, а молча отдать старый — обмануть клиента
+		if params.CustomAlias != "" && short != params.CustomAlias {
+			return Result{}, fmt.Errorf("%w: %s", ErrAliasMismatch, short)
+		}
+		link, err := usvc.CreateURL(s.linkBase(ctx, domain), short)
 		if err != nil {
 			return Result{}, err
 		}
@@ -43,12 +166,24 @@ func (s *Service) Shorten(ctx context.Context, u repository.URL) (Result, error)
 		return Result{}, err
 	}
 
-	short, err = usvc.AddRandomString(ctx, s.r, u)
-	if err != nil {
-		return Result{}, err
+	rec := repository.Record{URL: u, UserID: auth.UserID(ctx), ExpiresAt: expiresAt, Domain: domain}
+	if params.CustomAlias != "" {
+		// занятый alias — ошибка, случайный id вместо него не подставляем
+		rec.ShortURL = params.CustomAlias
+		if err := r.Add(ctx, rec); err != nil {
+			return Result{}, err
+		}
+		short = params.CustomAlias
+	} else {
+		short, err = s.addGenerated(ctx, r, rec)
+		if err != nil {
+			return Result{}, err
+		}
 	}
 
-	link, err := usvc.CreateURL(s.baseURL, short)
+	s.notifyCreated(ctx, u)
+
+	link, err := usvc.CreateURL(s.linkBase(ctx, domain), short)
 
 	if err != nil {
 		return Result{}, err
@@ -60,62 +195,138 @@ func (s *Service) Resolve(ctx context.Context, short repository.ShortURL) (repos
 	return s.r.Get(ctx, short)
 }
 
-func (s *Service) AddRandomString(ctx context.Context, u repository.URL) (repository.ShortURL, error) {
-	const retry = 6
+// ResolveHost ищет short в домене, на который пришёл запрос с заголовком Host.
+func (s *Service) ResolveHost(ctx context.Context, host string, short repository.ShortURL) (_ repository.URL, err error) {
+	ctx, span := tracer.Start(ctx, "Service.ResolveHost", trace.WithAttributes(attribute.String("shortener.host", host), attribute.String("shortener.short_id", string(short))))
+	defer func() { tracing.EndExpected(span, err, lookupMisses...) }()
 
-	for i := 0; i < retry; i++ {
-		short := usvc.GenerateShort(6)
+	r, err := s.repoFor(s.hostDomain(host))
+	if err != nil {
+		return "", err
+	}
+	return r.Get(ctx, short)
+}
 
-		err := s.r.Add(ctx, short, u)
+// addGenerated сохраняет rec под ключом из пула, а если пул пуст — под сгенерированным.
+func (s *Service) addGenerated(ctx context.Context, r repository.Repository, rec repository.Record) (repository.ShortURL, error) {
+	if keys := s.claimKeys(ctx, 1); len(keys) == 1 {
+		rec.ShortURL = keys[0]
+		err := r.Add(ctx, rec)
 		if err == nil {
-			return short, nil
+			return keys[0], nil
 		}
-
-		if errors.Is(err, repository.ErrShortURLAlreadyExists) || errors.Is(err, repository.ErrAlreadyExists) {
-			continue
+		// ключ мог успеть занять пользовательский alias — тогда генерируем как обычно
+		if !errors.Is(err, repository.ErrShortURLAlreadyExists) {
+			return "", err
 		}
+	}
+	return usvc.AddRandomString(ctx, r, s.gen, rec)
+}
 
-		return "", err
+// claimKeys забирает до n ключей из пула; ошибки пула не фатальны — вызывающий догенерирует сам.
+func (s *Service) claimKeys(ctx context.Context, n int) []repository.ShortURL {
+	if s.pool == nil || n <= 0 {
+		return nil
+	}
+	keys, err := s.pool.ClaimKeys(ctx, n)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("claim keys from pool: %s", err)
+	}
+	if len(keys) < n && s.filler != nil {
+		s.filler.Kick()
 	}
+	return keys
+}
+
+// GeneratorStats текущая длина кода и счётчики коллизий, если генератор их ведёт.
+func (s *Service) GeneratorStats() (usvc.GeneratorStats, bool) {
+	r, ok := s.gen.(usvc.StatsReporter)
+	if !ok {
+		return usvc.GeneratorStats{}, false
+	}
+	return r.Stats(), true
+}
 
-	return "", fmt.Errorf("can't generate unique short url after %d retries", retry)
+func (s *Service) AddRandomString(ctx context.Context, rec repository.Record) (repository.ShortURL, error) {
+	return usvc.AddRandomString(ctx, s.r, s.gen, rec)
 }
 
-func (s *Service) Batch(ctx context.Context, batch []model.RequestBatchBody) ([]model.ResponseBatchBody, bool, error) {
+// Batch сокращает пачку ссылок в одном домене; domain как в ShortenParams.
+func (s *Service) Batch(ctx context.Context, domain string, batch []model.RequestBatchBody) (_ []model.ResponseBatchBody, _ bool, err error) {
+	ctx, span := tracer.Start(ctx, "Service.Batch", trace.WithAttributes(attribute.String("shortener.domain", domain), attribute.Int("shortener.batch_size", len(batch))))
+	defer func() { tracing.End(span, err) }()
+
 	hadExisting := false
 	wrap := func(err error) error { return fmt.Errorf("service batch: %w", err) }
 
+	domain, err = s.domain(domain)
+	if err != nil {
+		return nil, hadExisting, wrap(err)
+	}
+	r, err := s.repoFor(domain)
+	if err != nil {
+		return nil, hadExisting, wrap(err)
+	}
+
 	// валидируем вход и соберём порядок URL
 	order := make([]string, 0, len(batch))
 	seen := make(map[repository.URL]struct{}, len(batch))
 	corr := make(map[repository.URL]string, len(batch))
+	expires := make(map[repository.URL]*time.Time, len(batch))
+	aliases := make(map[repository.URL]repository.ShortURL)
+	aliasSeen := make(map[repository.ShortURL]struct{})
+	now := time.Now()
 
 	for _, b := range batch {
 		if _, err := usvc.ParseURL(string(b.OriginalURL)); err != nil {
 			return nil, hadExisting, wrap(err)
 		}
+		if s.isBlocked(b.OriginalURL) {
+			return nil, hadExisting, wrap(fmt.Errorf("%w: %s", ErrBlockedURL, b.OriginalURL))
+		}
+		ttl, err := TTLFromSeconds(b.TTLSeconds)
+		if err != nil {
+			return nil, hadExisting, wrap(err)
+		}
+		params := ShortenParams{ExpiresAt: b.ExpiresAt, TTL: ttl}
+		expiresAt, err := params.expiresAt(now)
+		if err != nil {
+			return nil, hadExisting, wrap(err)
+		}
 		if _, ok := seen[b.OriginalURL]; ok {
 			return nil, hadExisting, wrap(fmt.Errorf("double url in data %s", b.OriginalURL))
 		}
 		seen[b.OriginalURL] = struct{}{}
 		order = append(order, string(b.OriginalURL))
+		if b.CustomAlias != "" {
+			if err := s.aliases.Validate(b.CustomAlias); err != nil {
+				return nil, hadExisting, wrap(err)
+			}
+			if _, ok := aliasSeen[b.CustomAlias]; ok {
+				return nil, hadExisting, wrap(fmt.Errorf("%w: double alias in data %s", usvc.ErrInvalidAlias, b.CustomAlias))
+			}
+			aliasSeen[b.CustomAlias] = struct{}{}
+			aliases[b.OriginalURL] = b.CustomAlias
+		}
 		corr[b.OriginalURL] = b.CorrelationID
+		expires[b.OriginalURL] = expiresAt
 	}
 
 	result := make(map[repository.URL]repository.ShortURL, len(batch))
+	created := make(map[repository.URL]struct{}, len(batch))
 
 	// Транзакционный путь
-	tx, ok := s.r.(repository.TxRunner)
+	tx, ok := r.(repository.TxRunner)
 	if !ok {
 		// без транзакции
-		err := createBatchFunc(ctx, order, result, &hadExisting)(s.r)
+		err := createBatchFunc(ctx, s.gen, s.claimKeys, order, expires, aliases, result, created, &hadExisting)(r)
 		if err != nil {
 			return nil, hadExisting, err
 		}
 	}
 	if ok {
 		// с тразакцией
-		err := tx.InTx(ctx, createBatchFunc(ctx, order, result, &hadExisting))
+		err := tx.InTx(ctx, createBatchFunc(ctx, s.gen, s.claimKeys, order, expires, aliases, result, created, &hadExisting))
 		if err != nil {
 			return nil, hadExisting, err
 		}
@@ -123,7 +334,10 @@ func (s *Service) Batch(ctx context.Context, batch []model.RequestBatchBody) ([]
 	// Формируем ответ
 	out := make([]model.ResponseBatchBody, 0, len(batch))
 	for _, u := range order {
-		link, err := usvc.CreateURL(s.baseURL, result[repository.URL(u)])
+		if _, ok := created[repository.URL(u)]; ok {
+			s.notifyCreated(ctx, repository.URL(u))
+		}
+		link, err := usvc.CreateURL(s.linkBase(ctx, domain), result[repository.URL(u)])
 		if err != nil {
 			return nil, hadExisting, wrap(err)
 		}
@@ -135,11 +349,155 @@ func (s *Service) Batch(ctx context.Context, batch []model.RequestBatchBody) ([]
 	return out, hadExisting, nil
 }
 
+func (s *Service) notifyCreated(ctx context.Context, u repository.URL) {
+	if s.audit != nil {
+		s.audit.Notify(audit.NewEvent(audit.ActionShorten, auth.UserID(ctx), string(u)))
+	}
+}
+
+func (s *Service) UserURLs(ctx context.Context, userID string) (_ []model.ResponseUserURL, err error) {
+	ctx, span := tracer.Start(ctx, "Service.UserURLs")
+	defer func() { tracing.End(span, err) }()
+
+	ur, ok := s.r.(repository.UserRepo)
+	if !ok {
+		return nil, fmt.Errorf("service user urls: repo doesn't support user methods")
+	}
+	records, err := ur.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service user urls: %w", err)
+	}
+	out := make([]model.ResponseUserURL, 0, len(records))
+	for _, rec := range records {
+		link, err := usvc.CreateURL(s.linkBase(ctx, rec.Domain), rec.ShortURL)
+		if err != nil {
+			return nil, fmt.Errorf("service user urls: %w", err)
+		}
+		out = append(out, model.ResponseUserURL{ShortURL: link, OriginalURL: rec.URL})
+	}
+	return out, nil
+}
+
+// DeleteUserURLs удаляет ссылки пользователя в домене domain. С Deleter удаление идёт в фоне,
+// без него — синхронно. Ошибка с ErrDeleterClosed или ctx.Err() значит, что очередь
+// не приняла запрос и его стоит повторить.
+func (s *Service) DeleteUserURLs(ctx context.Context, userID, domain string, shorts []repository.ShortURL) (err error) {
+	ctx, span := tracer.Start(ctx, "Service.DeleteUserURLs", trace.WithAttributes(attribute.Int("shortener.batch_size", len(shorts))))
+	defer func() { tracing.End(span, err) }()
+
+	domain, err = s.domain(domain)
+	if err != nil {
+		return err
+	}
+	if len(shorts) == 0 {
+		return nil
+	}
+	if s.deleter != nil {
+		return s.deleter.Delete(ctx, userID, domain, shorts)
+	}
+	dr, ok := s.r.(repository.DeleteRepo)
+	if !ok {
+		return fmt.Errorf("service delete: repo doesn't support delete methods")
+	}
+	reqs := make([]repository.DeleteRequest, 0, len(shorts))
+	for _, short := range shorts {
+		reqs = append(reqs, repository.DeleteRequest{Domain: domain, UserID: userID, ShortURL: short})
+	}
+	return dr.DeleteByUser(ctx, reqs)
+}
+
+// RecordClick ставит событие перехода в очередь записи; домен берётся по host, как в ResolveHost.
+// Без WithClicks ничего не делает.
+func (s *Service) RecordClick(host string, click repository.Click) {
+	if s.rec != nil {
+		click.Domain = s.hostDomain(host)
+		s.rec.Record(click)
+	}
+}
+
+// InternalStats число живых ссылок и пользователей во всём хранилище.
+func (s *Service) InternalStats(ctx context.Context) (_ model.ResponseInternalStats, err error) {
+	ctx, span := tracer.Start(ctx, "Service.InternalStats")
+	defer func() { tracing.End(span, err) }()
+
+	cr, ok := s.r.(repository.CountRepo)
+	if !ok {
+		return model.ResponseInternalStats{}, fmt.Errorf("service internal stats: repo doesn't support count methods")
+	}
+	urls, err := cr.CountURLs(ctx)
+	if err != nil {
+		return model.ResponseInternalStats{}, fmt.Errorf("service internal stats: %w", err)
+	}
+	users, err := cr.CountUsers(ctx)
+	if err != nil {
+		return model.ResponseInternalStats{}, fmt.Errorf("service internal stats: %w", err)
+	}
+	return model.ResponseInternalStats{URLs: urls, Users: users}, nil
+}
+
+// Stats статистика переходов по short в домене domain; пустой домен — основной.
+func (s *Service) Stats(ctx context.Context, domain string, short repository.ShortURL) (_ model.ResponseStats, err error) {
+	ctx, span := tracer.Start(ctx, "Service.Stats", trace.WithAttributes(attribute.String("shortener.short_id", string(short))))
+	defer func() { tracing.EndExpected(span, err, repository.ErrNotFoundShortURL, ErrUnknownDomain) }()
+
+	wrap := func(err error) error { return fmt.Errorf("service stats: %w", err) }
+	if s.clicks == nil {
+		return model.ResponseStats{}, wrap(fmt.Errorf("click tracking is disabled"))
+	}
+	domain, err = s.domain(domain)
+	if err != nil {
+		return model.ResponseStats{}, wrap(err)
+	}
+	r, err := s.repoFor(domain)
+	if err != nil {
+		return model.ResponseStats{}, wrap(err)
+	}
+	_, err = r.Get(ctx, short)
+	if err != nil && !errors.Is(err, repository.ErrDeleted) && !errors.Is(err, repository.ErrExpired) {
+		return model.ResponseStats{}, wrap(err)
+	}
+
+	stats, err := s.clicks.ClickStats(ctx, domain, short)
+	if err != nil {
+		return model.ResponseStats{}, wrap(err)
+	}
+	link, err := usvc.CreateURL(s.linkBase(ctx, domain), short)
+	if err != nil {
+		return model.ResponseStats{}, wrap(err)
+	}
+	out := model.ResponseStats{
+		ShortURL:       link,
+		TotalClicks:    stats.Total,
+		UniqueVisitors: stats.Unique,
+		Daily:          make([]model.ResponseDailyClicks, 0, len(stats.Daily)),
+	}
+	for _, d := range stats.Daily {
+		out.Daily = append(out.Daily, model.ResponseDailyClicks{
+			Date:   d.Day.Format(time.DateOnly),
+			Clicks: d.Count,
+		})
+	}
+	return out, nil
+}
+
 // Batch func
-func createBatchFunc(ctx context.Context, order []string, result map[repository.URL]repository.ShortURL, hadExisting *bool) func(r repository.Repository) error {
+func createBatchFunc(ctx context.Context, gen usvc.CodeGenerator, claim func(ctx context.Context, n int) []repository.ShortURL, order []string, expires map[repository.URL]*time.Time, aliases map[repository.URL]repository.ShortURL, result map[repository.URL]repository.ShortURL, created map[repository.URL]struct{}, hadExisting *bool) func(r repository.Repository) error {
 	const retry = 6
 	wrap := func(err error) error { return fmt.Errorf("service batch: %w", err) }
 
+	userID := auth.UserID(ctx)
+
+	// found отдаёт уже сокращённые URL; alias, отличный от старого кода, — конфликт, как в Shorten
+	found := func(recs []repository.Record) error {
+		for _, rec := range recs {
+			if alias, ok := aliases[rec.URL]; ok && alias != rec.ShortURL {
+				return wrap(fmt.Errorf("%w: %s", ErrAliasMismatch, rec.ShortURL))
+			}
+			result[rec.URL] = rec.ShortURL
+		}
+		return nil
+	}
+
 	batch := func(r repository.Repository) error {
 		br, ok := r.(repository.BatchRepo)
 		if !ok {
@@ -156,22 +514,41 @@ func createBatchFunc(ctx context.Context, order []string, result map[repository.
 		if len(existing) > 0 {
 			*hadExisting = true
 		}
-		for _, rec := range existing {
-			result[rec.URL] = rec.ShortURL
+		if err := found(existing); err != nil {
+			return err
 		}
 		remaining = urlsDiff(remaining, existing) // осталось только то, чего нет в БД
 		if len(remaining) == 0 {
 			return nil
 		}
 
+		tracker, _ := gen.(usvc.CollisionTracker)
+
+		// ключи из пула заведомо свободны, берём их на первую попытку
+		var keys []repository.ShortURL
+		if claim != nil {
+			keys = claim(ctx, len(remaining)-countAliased(remaining, aliases))
+		}
+
 		// Пытаемся вставить оставшиеся, перегенерируя short только для оставшихся
 		for attempt := 0; attempt < retry && len(remaining) > 0; attempt++ {
 			args := make([]repository.ArgAddMany, 0, len(remaining))
 			for _, u := range remaining {
-				short := usvc.GenerateShort(6)
+				short, ok := aliases[repository.URL(u)]
+				if !ok && len(keys) > 0 {
+					short, keys, ok = keys[0], keys[1:], true
+				}
+				if !ok {
+					short, err = gen.Next(ctx, repository.URL(u), attempt)
+					if err != nil {
+						return wrap(err)
+					}
+				}
 				args = append(args, repository.ArgAddMany{
-					URL:      repository.URL(u),
-					ShortURL: short,
+					URL:       repository.URL(u),
+					ShortURL:  short,
+					UserID:    userID,
+					ExpiresAt: expires[repository.URL(u)],
 				})
 			}
 
@@ -182,6 +559,10 @@ func createBatchFunc(ctx context.Context, order []string, result map[repository.
 
 			for _, rec := range inserted {
 				result[rec.URL] = rec.ShortURL
+				created[rec.URL] = struct{}{}
+				if _, ok := aliases[rec.URL]; !ok && tracker != nil {
+					tracker.Observe(false, attempt)
+				}
 			}
 
 			remaining = urlsDiff(remaining, inserted)
@@ -195,10 +576,25 @@ func createBatchFunc(ctx context.Context, order []string, result map[repository.
 			if err != nil {
 				return wrap(err)
 			}
-			for _, rec := range nowExist {
-				result[rec.URL] = rec.ShortURL
+			if err := found(nowExist); err != nil {
+				return err
 			}
 			remaining = urlsDiff(remaining, nowExist)
+
+			if tracker != nil {
+				for _, u := range remaining {
+					if _, ok := aliases[repository.URL(u)]; !ok {
+						tracker.Observe(true, attempt)
+					}
+				}
+			}
+
+			// alias не вставился и URL не появился — значит alias занят, повтор не поможет
+			for _, u := range remaining {
+				if alias, ok := aliases[repository.URL(u)]; ok {
+					return wrap(fmt.Errorf("%w: %v", repository.ErrShortURLAlreadyExists, alias))
+				}
+			}
 		}
 
 		if len(remaining) > 0 {
@@ -209,6 +605,16 @@ func createBatchFunc(ctx context.Context, order []string, result map[repository.
 	return batch
 }
 
+func countAliased(urls []string, aliases map[repository.URL]repository.ShortURL) int {
+	n := 0
+	for _, u := range urls {
+		if _, ok := aliases[repository.URL(u)]; ok {
+			n++
+		}
+	}
+	return n
+}
+
 // urlsDiff возвращает urls, которых нет среди records (по URL), сохраняя порядок.
 func urlsDiff(urls []string, records []repository.Record) []string {
 	existSet := make(map[string]struct{}, len(records))
@@ -223,3 +629,24 @@ func urlsDiff(urls []string, records []repository.Record) []string {
 	}
 	return out
 }
+
+// expiresAt вычисляет момент истечения ссылки относительно now; nil — бессрочная.
+func (p ShortenParams) expiresAt(now time.Time) (*time.Time, error) {
+	switch {
+	case p.ExpiresAt != nil && p.TTL != 0:
+		return nil, fmt.Errorf("%w: expires_at and ttl_seconds are mutually exclusive", ErrInvalidExpiry)
+	case p.TTL < 0:
+		return nil, fmt.Errorf("%w: ttl must be positive", ErrInvalidExpiry)
+	case p.TTL > 0:
+		at := now.Add(p.TTL).UTC()
+		return &at, nil
+	case p.ExpiresAt != nil:
+		if !p.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("%w: expires_at is in the past", ErrInvalidExpiry)
+		}
+		at := p.ExpiresAt.UTC()
+		return &at, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,68 @@
+package shortener
+
+import (
+	"context"
+	"time"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+// Sweeper периодически удаляет истёкшие ссылки, освобождая их URL для повторного сокращения.
+type Sweeper struct {
+	r        repository.ExpiredPurger
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewSweeper(r repository.ExpiredPurger, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		r:        r,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *Sweeper) Run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.sweep()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Sweeper) sweep() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+	defer cancel()
+	n, err := s.r.PurgeExpired(ctx, time.Now())
+	if err != nil {
+		logger.Log.Errorf("sweeper purge expired: %s", err)
+		return
+	}
+	if n > 0 {
+		logger.Log.Infof("sweeper purged %d expired links", n)
+	}
+}
+
+func (s *Sweeper) Close(ctx context.Context) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
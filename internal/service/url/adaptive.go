@@ -0,0 +1,142 @@
+package url
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/IvanOplesnin/url-shortener/internal/logger"
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+// CollisionTracker получает исход каждой попытки вставить сгенерированный код.
+// attempt — номер попытки в рамках одной вставки, начиная с 0.
+type CollisionTracker interface {
+	Observe(collided bool, attempt int)
+}
+
+// StatsReporter генератор, который ведёт счётчики.
+type StatsReporter interface {
+	Stats() GeneratorStats
+}
+
+type GeneratorStats struct {
+	Length     int
+	Generated  uint64
+	Collisions uint64
+	Grows      uint64
+}
+
+// AdaptiveConfig правила роста длины кода.
+type AdaptiveConfig struct {
+	MinLen int
+	MaxLen int
+	// Window число попыток, по которым считается доля коллизий.
+	Window int
+	// MaxRate доля коллизий в окне, после которой длина растёт.
+	MaxRate float64
+	// MaxRetries столько неудачных попыток подряд в одной вставке — растём сразу, не дожидаясь окна.
+	MaxRetries int
+}
+
+func DefaultAdaptiveConfig(minLen, maxLen int) AdaptiveConfig {
+	return AdaptiveConfig{
+		MinLen:     minLen,
+		MaxLen:     maxLen,
+		Window:     1000,
+		MaxRate:    0.05,
+		MaxRetries: 3,
+	}
+}
+
+// AdaptiveGenerator оборачивает генератор фиксированной длины и удлиняет код,
+// когда пространство ключей заполняется и коллизии учащаются.
+// Длина только растёт; после рестарта снова стартует с MinLen и подстраивается заново.
+type AdaptiveGenerator struct {
+	factory func(n int) (CodeGenerator, error)
+	cfg     AdaptiveConfig
+
+	mu               sync.Mutex
+	gen              CodeGenerator
+	stats            GeneratorStats
+	windowAttempts   int
+	windowCollisions int
+}
+
+func NewAdaptiveGenerator(factory func(n int) (CodeGenerator, error), cfg AdaptiveConfig) (*AdaptiveGenerator, error) {
+	if cfg.MinLen <= 0 || cfg.MaxLen < cfg.MinLen {
+		return nil, fmt.Errorf("adaptive generator: bad length bounds %d..%d", cfg.MinLen, cfg.MaxLen)
+	}
+	if cfg.Window <= 0 || cfg.MaxRetries <= 0 {
+		return nil, fmt.Errorf("adaptive generator: window and max retries must be positive")
+	}
+	gen, err := factory(cfg.MinLen)
+	if err != nil {
+		return nil, err
+	}
+	// фабрика должна уметь построить генератор любой допустимой длины
+	if _, err := factory(cfg.MaxLen); err != nil {
+		return nil, err
+	}
+	return &AdaptiveGenerator{
+		factory: factory,
+		cfg:     cfg,
+		gen:     gen,
+		stats:   GeneratorStats{Length: cfg.MinLen},
+	}, nil
+}
+
+func (g *AdaptiveGenerator) Next(ctx context.Context, u repository.URL, attempt int) (repository.ShortURL, error) {
+	g.mu.Lock()
+	gen := g.gen
+	g.stats.Generated++
+	g.mu.Unlock()
+
+	return gen.Next(ctx, u, attempt)
+}
+
+func (g *AdaptiveGenerator) Observe(collided bool, attempt int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.windowAttempts++
+	if collided {
+		g.windowCollisions++
+		g.stats.Collisions++
+		if attempt+1 >= g.cfg.MaxRetries {
+			g.grow()
+			return
+		}
+	}
+	if g.windowAttempts >= g.cfg.Window {
+		if float64(g.windowCollisions)/float64(g.windowAttempts) > g.cfg.MaxRate {
+			g.grow()
+			return
+		}
+		g.windowAttempts, g.windowCollisions = 0, 0
+	}
+}
+
+// grow вызывается под g.mu.
+func (g *AdaptiveGenerator) grow() {
+	g.windowAttempts, g.windowCollisions = 0, 0
+	if g.stats.Length >= g.cfg.MaxLen {
+		return
+	}
+	gen, err := g.factory(g.stats.Length + 1)
+	if err != nil {
+		logger.Log.Errorf("adaptive generator: grow to %d: %s", g.stats.Length+1, err)
+		return
+	}
+	g.gen = gen
+	g.stats.Length++
+	g.stats.Grows++
+	logger.Log.Infof("adaptive generator: code length grown to %d (collisions %d of %d)",
+		g.stats.Length, g.stats.Collisions, g.stats.Generated)
+}
+
+func (g *AdaptiveGenerator) Stats() GeneratorStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
@@ -0,0 +1,71 @@
+package url
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+var (
+	ErrInvalidAlias  = errors.New("invalid custom alias")
+	ErrReservedAlias = errors.New("reserved custom alias")
+)
+
+const DefaultAliasPattern = `^[A-Za-z0-9_-]+$`
+
+// AliasPolicy описывает, какие пользовательские короткие id допустимы.
+type AliasPolicy struct {
+	MinLen   int
+	MaxLen   int
+	pattern  *regexp.Regexp
+	reserved map[string]struct{}
+}
+
+func NewAliasPolicy(minLen, maxLen int, pattern string, reserved []string) (*AliasPolicy, error) {
+	if minLen <= 0 || maxLen < minLen {
+		return nil, fmt.Errorf("alias policy: bad length bounds %d..%d", minLen, maxLen)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("alias policy: bad pattern %q: %w", pattern, err)
+	}
+	p := &AliasPolicy{
+		MinLen:   minLen,
+		MaxLen:   maxLen,
+		pattern:  re,
+		reserved: make(map[string]struct{}, len(reserved)),
+	}
+	for _, w := range reserved {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			p.reserved[w] = struct{}{}
+		}
+	}
+	return p, nil
+}
+
+// DefaultReservedAliases служебные пути, которые нельзя занять alias-ом и без настройки.
+func DefaultReservedAliases() []string {
+	return []string{"api", "ping", "debug", "metrics"}
+}
+
+func DefaultAliasPolicy() *AliasPolicy {
+	p, _ := NewAliasPolicy(3, 32, DefaultAliasPattern, DefaultReservedAliases())
+	return p
+}
+
+func (p *AliasPolicy) Validate(alias repository.ShortURL) error {
+	a := string(alias)
+	if len(a) < p.MinLen || len(a) > p.MaxLen {
+		return fmt.Errorf("%w %q: length must be %d..%d", ErrInvalidAlias, a, p.MinLen, p.MaxLen)
+	}
+	if !p.pattern.MatchString(a) {
+		return fmt.Errorf("%w %q: must match %s", ErrInvalidAlias, a, p.pattern)
+	}
+	if _, ok := p.reserved[strings.ToLower(a)]; ok {
+		return fmt.Errorf("%w %q", ErrReservedAlias, a)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package url
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	mrand "math/rand/v2"
+
+	"github.com/IvanOplesnin/url-shortener/internal/repository"
+)
+
+const (
+	GeneratorRandom   = "random"
+	GeneratorSequence = "sequence"
+	GeneratorSqids    = "sqids"
+	GeneratorHash     = "hash"
+)
+
+const base62 = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+var ErrUnknownGenerator = errors.New("unknown code generator")
+
+// CodeGenerator выдаёт кандидата в короткий id для u.
+// attempt — номер попытки после коллизии: детерминированные генераторы
+// должны на разных attempt давать разные коды.
+type CodeGenerator interface {
+	Next(ctx context.Context, u repository.URL, attempt int) (repository.ShortURL, error)
+}
+
+// RandomGenerator случайный base62 код из crypto/rand.
+type RandomGenerator struct {
+	n int
+}
+
+func NewRandomGenerator(n int) *RandomGenerator {
+	return &RandomGenerator{n: n}
+}
+
+func (g *RandomGenerator) Next(_ context.Context, _ repository.URL, _ int) (repository.ShortURL, error) {
+	b := make([]byte, g.n)
+	buf := make([]byte, g.n*2)
+	for i := 0; i < len(b); {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("random generator: %w", err)
+		}
+		for _, c := range buf {
+			// 248 = 4*62: отбрасываем хвост, чтобы не было перекоса по модулю
+			if c >= 248 {
+				continue
+			}
+			b[i] = base62[c%62]
+			i++
+			if i == len(b) {
+				break
+			}
+		}
+	}
+	return repository.ShortURL(b), nil
+}
+
+// SequenceGenerator кодирует очередной номер последовательности в base62,
+// дополняя до n символов. Коды не повторяются, но легко перебираются подряд.
+type SequenceGenerator struct {
+	seq repository.Sequence
+	n   int
+}
+
+func NewSequenceGenerator(seq repository.Sequence, n int) *SequenceGenerator {
+	return &SequenceGenerator{seq: seq, n: n}
+}
+
+func (g *SequenceGenerator) Next(ctx context.Context, _ repository.URL, _ int) (repository.ShortURL, error) {
+	v, err := g.seq.NextVal(ctx)
+	if err != nil {
+		return "", fmt.Errorf("sequence generator: %w", err)
+	}
+	return repository.ShortURL(encodeBase62(uint64(v), base62, g.n)), nil
+}
+
+// SqidsGenerator обфусцирует номер последовательности (в духе Sqids/Hashids):
+// номер переставляется биекцией по модулю 62^n и кодируется перемешанным по salt алфавитом.
+// Пока номера не превысили 62^n, коды уникальны и не выглядят последовательными.
+type SqidsGenerator struct {
+	seq      repository.Sequence
+	n        int
+	alphabet string
+	space    uint64
+	mult     uint64
+	offset   uint64
+}
+
+func NewSqidsGenerator(seq repository.Sequence, n int, salt string) (*SqidsGenerator, error) {
+	if n <= 0 || n > 10 {
+		return nil, fmt.Errorf("sqids generator: length %d out of range 1..10", n)
+	}
+	space := uint64(1)
+	for i := 0; i < n; i++ {
+		space *= 62
+	}
+
+	sum := sha256.Sum256([]byte(salt))
+	seed1 := binary.BigEndian.Uint64(sum[0:8])
+	seed2 := binary.BigEndian.Uint64(sum[8:16])
+
+	alphabet := []byte(base62)
+	r := mrand.New(mrand.NewPCG(seed1, seed2))
+	r.Shuffle(len(alphabet), func(i, j int) { alphabet[i], alphabet[j] = alphabet[j], alphabet[i] })
+
+	// множитель взаимно прост с 62, поэтому v*mult mod 62^n — перестановка
+	mult := binary.BigEndian.Uint64(sum[16:24])%space | 1
+	for mult%31 == 0 {
+		mult += 2
+	}
+
+	return &SqidsGenerator{
+		seq:      seq,
+		n:        n,
+		alphabet: string(alphabet),
+		space:    space,
+		mult:     mult,
+		offset:   binary.BigEndian.Uint64(sum[24:32]) % space,
+	}, nil
+}
+
+func (g *SqidsGenerator) Next(ctx context.Context, _ repository.URL, _ int) (repository.ShortURL, error) {
+	v, err := g.seq.NextVal(ctx)
+	if err != nil {
+		return "", fmt.Errorf("sqids generator: %w", err)
+	}
+	return repository.ShortURL(g.Encode(uint64(v))), nil
+}
+
+func (g *SqidsGenerator) Encode(v uint64) string {
+	// 62^10 < 2^60, произведение может не влезть в uint64 — считаем через big.Int
+	x := new(big.Int).SetUint64(v % g.space)
+	x.Mul(x, new(big.Int).SetUint64(g.mult))
+	x.Add(x, new(big.Int).SetUint64(g.offset))
+	x.Mod(x, new(big.Int).SetUint64(g.space))
+	return encodeBase62(x.Uint64(), g.alphabet, g.n)
+}
+
+// HashGenerator детерминированный код: base62 от sha256(url).
+// Одинаковые URL дают одинаковый код; при коллизии в хэш подмешивается attempt.
+type HashGenerator struct {
+	n int
+}
+
+func NewHashGenerator(n int) *HashGenerator {
+	return &HashGenerator{n: n}
+}
+
+func (g *HashGenerator) Next(_ context.Context, u repository.URL, attempt int) (repository.ShortURL, error) {
+	h := sha256.New()
+	h.Write([]byte(u))
+	if attempt > 0 {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(attempt))
+		h.Write(b[:])
+	}
+	x := new(big.Int).SetBytes(h.Sum(nil))
+	d, m := big.NewInt(62), new(big.Int)
+	b := make([]byte, g.n)
+	for i := range b {
+		x.DivMod(x, d, m)
+		b[i] = base62[m.Int64()]
+	}
+	return repository.ShortURL(b), nil
+}
+
+// encodeBase62 записывает v в алфавите alphabet, дополняя слева до minLen.
+func encodeBase62(v uint64, alphabet string, minLen int) string {
+	var buf [16]byte
+	i := len(buf)
+	for v > 0 || i == len(buf) {
+		i--
+		buf[i] = alphabet[v%62]
+		v /= 62
+	}
+	for len(buf)-i < minLen {
+		i--
+		buf[i] = alphabet[0]
+	}
+	return string(buf[i:])
+}
+
+// NewGenerator собирает генератор по имени из конфига; seq нужен только счётчиковым режимам.
+func NewGenerator(kind string, n int, salt string, seq repository.Sequence) (CodeGenerator, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("code length must be positive, got %d", n)
+	}
+	switch kind {
+	case "", GeneratorRandom:
+		return NewRandomGenerator(n), nil
+	case GeneratorHash:
+		return NewHashGenerator(n), nil
+	case GeneratorSequence:
+		if seq == nil {
+			return nil, fmt.Errorf("%s generator requires a sequence", kind)
+		}
+		return NewSequenceGenerator(seq, n), nil
+	case GeneratorSqids:
+		if seq == nil {
+			return nil, fmt.Errorf("%s generator requires a sequence", kind)
+		}
+		return NewSqidsGenerator(seq, n, salt)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownGenerator, kind)
+	}
+}
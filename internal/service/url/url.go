@@ -5,9 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/url"
-	"time"
 
 	"github.com/IvanOplesnin/url-shortener/internal/repository"
 )
@@ -20,6 +18,22 @@ func CreateURL(base string, id repository.ShortURL) (string, error) {
 	return url, nil
 }
 
+type baseKey struct{}
+
+// WithBase кладёт в ctx базовый URL, вычисленный для конкретного запроса
+// (например, по заголовкам доверенного прокси).
+func WithBase(ctx context.Context, base string) context.Context {
+	return context.WithValue(ctx, baseKey{}, base)
+}
+
+// Base базовый URL из ctx, если его туда положили, иначе fallback.
+func Base(ctx context.Context, fallback string) string {
+	if base, ok := ctx.Value(baseKey{}).(string); ok && base != "" {
+		return base
+	}
+	return fallback
+}
+
 func ParseURL(urlRaw string) (repository.URL, error) {
 	if urlRaw == "" {
 		return "", fmt.Errorf("empty body")
@@ -42,36 +56,30 @@ func BasePath(baseURL string) string {
 	return basePath
 }
 
-func AddRandomString(ctx context.Context, repositoryorage repository.Repository, url repository.URL) (repository.ShortURL, error) {
+// AddRandomString сохраняет rec под коротким id от gen; поле rec.ShortURL перезаписывается.
+func AddRandomString(ctx context.Context, storage repository.Repository, gen CodeGenerator, rec repository.Record) (repository.ShortURL, error) {
 	const retry = 6
 
-	lettrs := "abcdefghijklmnopqrrepositoryuvwxyzABCDEFGHIJKLMNOPQRrepositoryUVWXYZ0123456789"
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	b := make([]byte, 6)
-	for count := 0; count < retry; count++ {
-		for i := range b {
-			b[i] = lettrs[r.Intn(len(lettrs))]
+	tracker, _ := gen.(CollisionTracker)
+	for attempt := 0; attempt < retry; attempt++ {
+		short, err := gen.Next(ctx, rec.URL, attempt)
+		if err != nil {
+			return "", fmt.Errorf("error addRandomString: %w", err)
+		}
+		rec.ShortURL = short
+		err = storage.Add(ctx, rec)
+		collided := errors.Is(err, repository.ErrShortURLAlreadyExists)
+		if tracker != nil && (err == nil || collided) {
+			tracker.Observe(collided, attempt)
 		}
-		err := repositoryorage.Add(ctx, repository.ShortURL(b), url)
 		if err == nil {
-			return repository.ShortURL(string(b)), nil
+			return short, nil
 		}
-		if errors.Is(err, repository.ErrShortURLAlreadyExists) || errors.Is(err, repository.ErrAlreadyExists) {
+		if collided || errors.Is(err, repository.ErrAlreadyExists) {
 			continue
 		}
-		return "", fmt.Errorf("error addRandomrepositoryring: %w", err)
+		return "", fmt.Errorf("error addRandomString: %w", err)
 	}
 
-	return "", fmt.Errorf("error addRandomrepositoryring: Can't generate random repositoryring")
-}
-
-const lettrs = "abcdefghijklmnopqrrepositoryuvwxyzABCDEFGHIJKLMNOPQRrepositoryUVWXYZ0123456789"
-
-func GenerateShort(n int) repository.ShortURL {
-	b := make([]byte, 6)
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	for i := range b {
-		b[i] = lettrs[r.Intn(len(lettrs))]
-	}
-	return repository.ShortURL(string(b))
+	return "", fmt.Errorf("error addRandomString: can't generate unique short url")
 }
@@ -0,0 +1,120 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const validFor = 365 * 24 * time.Hour
+
+// Ensure проверяет, что по путям certFile и keyFile лежит пара сертификат/ключ.
+// Если обоих файлов нет, генерирует самоподписанный сертификат на hosts и сохраняет его
+// туда же, чтобы при рестарте не выпускать новый. Возвращает true, если сертификат создан.
+func Ensure(certFile, keyFile string, hosts []string) (bool, error) {
+	certExists, err := exists(certFile)
+	if err != nil {
+		return false, err
+	}
+	keyExists, err := exists(keyFile)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case certExists && keyExists:
+		return false, nil
+	case certExists != keyExists:
+		return false, fmt.Errorf("tlscert: only one of %s and %s exists", certFile, keyFile)
+	}
+
+	certPEM, keyPEM, err := Generate(hosts, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if err := write(keyFile, keyPEM, 0o600); err != nil {
+		return false, err
+	}
+	if err := write(certFile, certPEM, 0o644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Generate выпускает самоподписанный ECDSA P-256 сертификат для hosts (DNS-имена и IP).
+func Generate(hosts []string, now time.Time) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tlscert: generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("tlscert: serial: %w", err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"url-shortener"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if h != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	if len(tmpl.DNSNames) > 0 {
+		tmpl.Subject.CommonName = tmpl.DNSNames[0]
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tlscert: create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tlscert: marshal key: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return false, fmt.Errorf("tlscert: stat %s: %w", path, err)
+}
+
+func write(path string, data []byte, perm os.FileMode) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("tlscert: mkdir: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return fmt.Errorf("tlscert: write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsure(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls", "cert.pem")
+	keyFile := filepath.Join(dir, "tls", "key.pem")
+
+	created, err := Ensure(certFile, keyFile, []string{"localhost", "127.0.0.1"})
+	require.NoError(t, err)
+	require.True(t, created)
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	require.NoError(t, cert.VerifyHostname("localhost"))
+	require.NoError(t, cert.VerifyHostname("127.0.0.1"))
+
+	// второй запуск берёт закэшированную пару
+	before, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	created, err = Ensure(certFile, keyFile, []string{"localhost"})
+	require.NoError(t, err)
+	require.False(t, created)
+	after, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	// одинокий ключ без сертификата — ошибка, а не тихая перезапись
+	require.NoError(t, os.Remove(certFile))
+	_, err = Ensure(certFile, keyFile, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE alias_url ADD COLUMN user_id VARCHAR NOT NULL DEFAULT '';
+CREATE INDEX alias_url_user_id_idx ON alias_url (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX alias_url_user_id_idx;
+ALTER TABLE alias_url DROP COLUMN user_id;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE clicks (
+    id BIGSERIAL PRIMARY KEY,
+    short_url VARCHAR NOT NULL,
+    clicked_at TIMESTAMPTZ NOT NULL,
+    referrer VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    ip VARCHAR NOT NULL DEFAULT ''
+);
+CREATE INDEX clicks_short_url_clicked_at_idx ON clicks (short_url, clicked_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE clicks;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE SEQUENCE short_code_seq AS BIGINT START WITH 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP SEQUENCE short_code_seq;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE key_pool (
+    short_url TEXT PRIMARY KEY
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE key_pool;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE alias_url ADD COLUMN domain VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE alias_url DROP CONSTRAINT alias_url_url_uk;
+ALTER TABLE alias_url DROP CONSTRAINT alias_url_short_url_uk;
+ALTER TABLE alias_url ADD CONSTRAINT alias_url_domain_url_uk UNIQUE (domain, "url");
+ALTER TABLE alias_url ADD CONSTRAINT alias_url_domain_short_url_uk UNIQUE (domain, short_url);
+-- удаление по коду идёт без домена
+CREATE INDEX alias_url_short_url_idx ON alias_url (short_url);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM alias_url WHERE domain <> '';
+DROP INDEX alias_url_short_url_idx;
+ALTER TABLE alias_url DROP CONSTRAINT alias_url_domain_short_url_uk;
+ALTER TABLE alias_url DROP CONSTRAINT alias_url_domain_url_uk;
+ALTER TABLE alias_url ADD CONSTRAINT alias_url_short_url_uk UNIQUE (short_url);
+ALTER TABLE alias_url ADD CONSTRAINT alias_url_url_uk UNIQUE ("url");
+ALTER TABLE alias_url DROP COLUMN domain;
+-- +goose StatementEnd
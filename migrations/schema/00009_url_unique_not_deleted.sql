@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- удалённый URL можно сократить заново, поэтому уникальность только среди живых ссылок
+ALTER TABLE alias_url DROP CONSTRAINT alias_url_domain_url_uk;
+CREATE UNIQUE INDEX alias_url_domain_url_uk ON alias_url (domain, "url") WHERE NOT is_deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM alias_url AS a
+WHERE a.is_deleted
+  AND EXISTS (
+    SELECT 1 FROM alias_url AS b
+    WHERE b.domain = a.domain AND b."url" = a."url" AND (NOT b.is_deleted OR b.id > a.id)
+  );
+DROP INDEX alias_url_domain_url_uk;
+ALTER TABLE alias_url ADD CONSTRAINT alias_url_domain_url_uk UNIQUE (domain, "url");
+-- +goose StatementEnd
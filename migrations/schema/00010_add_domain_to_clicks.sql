@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE clicks ADD COLUMN domain VARCHAR NOT NULL DEFAULT '';
+DROP INDEX clicks_short_url_clicked_at_idx;
+CREATE INDEX clicks_domain_short_url_clicked_at_idx ON clicks (domain, short_url, clicked_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX clicks_domain_short_url_clicked_at_idx;
+CREATE INDEX clicks_short_url_clicked_at_idx ON clicks (short_url, clicked_at);
+ALTER TABLE clicks DROP COLUMN domain;
+-- +goose StatementEnd
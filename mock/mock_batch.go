@@ -42,17 +42,17 @@ func (m *MockBatchRepo) EXPECT() *MockBatchRepoMockRecorder {
 }
 
 // Add mocks base method.
-func (m *MockBatchRepo) Add(ctx context.Context, key repository.ShortURL, value repository.URL) error {
+func (m *MockBatchRepo) Add(ctx context.Context, rec repository.Record) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Add", ctx, key, value)
+	ret := m.ctrl.Call(m, "Add", ctx, rec)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Add indicates an expected call of Add.
-func (mr *MockBatchRepoMockRecorder) Add(ctx, key, value any) *gomock.Call {
+func (mr *MockBatchRepoMockRecorder) Add(ctx, rec any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockBatchRepo)(nil).Add), ctx, key, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockBatchRepo)(nil).Add), ctx, rec)
 }
 
 // AddMany mocks base method.
@@ -3,7 +3,7 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=internal/repository/repo.go -destination=mock/mock_repo.go -package=mock_repo
+//	mockgen -source=internal/repository/repo.go -destination=mock/mock_repo.go -package=mock_repo -exclude_interfaces=BatchRepo
 //
 
 // Package mock_repo is a generated GoMock package.
@@ -12,6 +12,7 @@ package mock_repo
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	repository "github.com/IvanOplesnin/url-shortener/internal/repository"
 	gomock "go.uber.org/mock/gomock"
@@ -42,17 +43,17 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 }
 
 // Add mocks base method.
-func (m *MockRepository) Add(ctx context.Context, key repository.ShortURL, value repository.URL) error {
+func (m *MockRepository) Add(ctx context.Context, rec repository.Record) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Add", ctx, key, value)
+	ret := m.ctrl.Call(m, "Add", ctx, rec)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Add indicates an expected call of Add.
-func (mr *MockRepositoryMockRecorder) Add(ctx, key, value any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Add(ctx, rec any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockRepository)(nil).Add), ctx, key, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockRepository)(nil).Add), ctx, rec)
 }
 
 // Get mocks base method.
@@ -85,6 +86,122 @@ func (mr *MockRepositoryMockRecorder) Search(ctx, url any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockRepository)(nil).Search), ctx, url)
 }
 
+// MockUserRepo is a mock of UserRepo interface.
+type MockUserRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockUserRepoMockRecorder is the mock recorder for MockUserRepo.
+type MockUserRepoMockRecorder struct {
+	mock *MockUserRepo
+}
+
+// NewMockUserRepo creates a new mock instance.
+func NewMockUserRepo(ctrl *gomock.Controller) *MockUserRepo {
+	mock := &MockUserRepo{ctrl: ctrl}
+	mock.recorder = &MockUserRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserRepo) EXPECT() *MockUserRepoMockRecorder {
+	return m.recorder
+}
+
+// GetByUser mocks base method.
+func (m *MockUserRepo) GetByUser(ctx context.Context, userID string) ([]repository.Record, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUser", ctx, userID)
+	ret0, _ := ret[0].([]repository.Record)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUser indicates an expected call of GetByUser.
+func (mr *MockUserRepoMockRecorder) GetByUser(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUser", reflect.TypeOf((*MockUserRepo)(nil).GetByUser), ctx, userID)
+}
+
+// MockDeleteRepo is a mock of DeleteRepo interface.
+type MockDeleteRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeleteRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockDeleteRepoMockRecorder is the mock recorder for MockDeleteRepo.
+type MockDeleteRepoMockRecorder struct {
+	mock *MockDeleteRepo
+}
+
+// NewMockDeleteRepo creates a new mock instance.
+func NewMockDeleteRepo(ctrl *gomock.Controller) *MockDeleteRepo {
+	mock := &MockDeleteRepo{ctrl: ctrl}
+	mock.recorder = &MockDeleteRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeleteRepo) EXPECT() *MockDeleteRepoMockRecorder {
+	return m.recorder
+}
+
+// DeleteByUser mocks base method.
+func (m *MockDeleteRepo) DeleteByUser(ctx context.Context, reqs []repository.DeleteRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByUser", ctx, reqs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByUser indicates an expected call of DeleteByUser.
+func (mr *MockDeleteRepoMockRecorder) DeleteByUser(ctx, reqs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByUser", reflect.TypeOf((*MockDeleteRepo)(nil).DeleteByUser), ctx, reqs)
+}
+
+// MockExpiredPurger is a mock of ExpiredPurger interface.
+type MockExpiredPurger struct {
+	ctrl     *gomock.Controller
+	recorder *MockExpiredPurgerMockRecorder
+	isgomock struct{}
+}
+
+// MockExpiredPurgerMockRecorder is the mock recorder for MockExpiredPurger.
+type MockExpiredPurgerMockRecorder struct {
+	mock *MockExpiredPurger
+}
+
+// NewMockExpiredPurger creates a new mock instance.
+func NewMockExpiredPurger(ctrl *gomock.Controller) *MockExpiredPurger {
+	mock := &MockExpiredPurger{ctrl: ctrl}
+	mock.recorder = &MockExpiredPurgerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExpiredPurger) EXPECT() *MockExpiredPurgerMockRecorder {
+	return m.recorder
+}
+
+// PurgeExpired mocks base method.
+func (m *MockExpiredPurger) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpired", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpired indicates an expected call of PurgeExpired.
+func (mr *MockExpiredPurgerMockRecorder) PurgeExpired(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpired", reflect.TypeOf((*MockExpiredPurger)(nil).PurgeExpired), ctx, now)
+}
+
 // MockSeeder is a mock of Seeder interface.
 type MockSeeder struct {
 	ctrl     *gomock.Controller
@@ -194,3 +311,81 @@ func (mr *MockRollbackMockRecorder) Remove(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockRollback)(nil).Remove), arg0, arg1)
 }
+
+// MockDomainRepo is a mock of DomainRepo interface.
+type MockDomainRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockDomainRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockDomainRepoMockRecorder is the mock recorder for MockDomainRepo.
+type MockDomainRepoMockRecorder struct {
+	mock *MockDomainRepo
+}
+
+// NewMockDomainRepo creates a new mock instance.
+func NewMockDomainRepo(ctrl *gomock.Controller) *MockDomainRepo {
+	mock := &MockDomainRepo{ctrl: ctrl}
+	mock.recorder = &MockDomainRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDomainRepo) EXPECT() *MockDomainRepoMockRecorder {
+	return m.recorder
+}
+
+// InDomain mocks base method.
+func (m *MockDomainRepo) InDomain(domain string) (repository.Repository, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InDomain", domain)
+	ret0, _ := ret[0].(repository.Repository)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InDomain indicates an expected call of InDomain.
+func (mr *MockDomainRepoMockRecorder) InDomain(domain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InDomain", reflect.TypeOf((*MockDomainRepo)(nil).InDomain), domain)
+}
+
+// MockSequence is a mock of Sequence interface.
+type MockSequence struct {
+	ctrl     *gomock.Controller
+	recorder *MockSequenceMockRecorder
+	isgomock struct{}
+}
+
+// MockSequenceMockRecorder is the mock recorder for MockSequence.
+type MockSequenceMockRecorder struct {
+	mock *MockSequence
+}
+
+// NewMockSequence creates a new mock instance.
+func NewMockSequence(ctrl *gomock.Controller) *MockSequence {
+	mock := &MockSequence{ctrl: ctrl}
+	mock.recorder = &MockSequenceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSequence) EXPECT() *MockSequenceMockRecorder {
+	return m.recorder
+}
+
+// NextVal mocks base method.
+func (m *MockSequence) NextVal(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NextVal", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NextVal indicates an expected call of NextVal.
+func (mr *MockSequenceMockRecorder) NextVal(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextVal", reflect.TypeOf((*MockSequence)(nil).NextVal), ctx)
+}